@@ -1,12 +1,84 @@
 # Service Seed Configuration File
 # This file defines the core configuration for the service-seed application
 
+# Variables can be overridden per environment with SS_VAR_<name> (e.g., SS_VAR_env=prod)
+# variable "env" {
+#   default = "local"
+# }
+#
+# Locals are computed once and referenced as local.<name>
+# locals {
+#   service_name = "service-seed-${var.env}"
+# }
+
 # Directory where log files will be stored
 log_dir = "logs"
 
+# Log level: debug, info, warn, error (default: info)
+log_level = "info"
+
 # Directory where application data will be stored
 data_dir = "data"
 
+# Filesystem settings for log_dir and data_dir (optional)
+# Directories are created at startup with dir_mode; existing directories must be
+# writable and, when owner/group are set, owned by them.
+# filesystem {
+#   dir_mode = "0755"
+#   owner    = "service-seed"   # user name or uid
+#   group    = "service-seed"   # group name or gid
+# }
+
+# Embedded key-value storage (optional)
+# The bolt engine keeps data in a single file under data_dir; memory loses data on shutdown.
+# storage {
+#   engine          = "bolt"       # bolt or memory
+#   path            = "store.db"   # relative to data_dir unless absolute
+#   timeout_seconds = 5            # wait for the database file lock
+# }
+
+# Snapshots of data_dir (optional)
+# Archives are written by service-seed backup, POST /v1/admin/snapshot and the schedule below.
+# snapshot {
+#   dir              = "snapshots"  # relative to data_dir unless absolute
+#   interval_seconds = 3600         # scheduled snapshots; 0 disables them (or use a schedule block)
+#   retain           = 7            # scheduled snapshots to keep; 0 keeps all
+# }
+
+# Background worker pool (optional)
+# Handlers offload slow work with workers.Submit or workers.Do.
+# workers {
+#   max_workers           = 10    # tasks run concurrently
+#   queue_size            = 100   # waiting tasks; submissions beyond are rejected
+#   drain_timeout_seconds = 30    # shutdown waits this long before cancelling tasks
+# }
+
+# Persistent background jobs (optional)
+# Jobs are stored with the storage engine and survive restarts; failed runs are
+# retried with exponential backoff, then moved to the dead-letter list.
+# jobs {
+#   concurrency           = 4     # jobs run at once
+#   max_attempts          = 5     # runs before a job is dead-lettered
+#   backoff_seconds       = 1     # first retry delay, doubled per attempt
+#   max_backoff_seconds   = 300   # retry delay cap
+#   poll_interval_seconds = 1     # check for delayed and retried jobs
+#   retention_hours       = 168   # keep succeeded jobs queryable this long
+# }
+
+# Periodic tasks (optional, repeatable)
+# The label names a task registered in code (overriding its schedule) or, with
+# job set, a task that enqueues a job. Set cron or interval_seconds.
+# schedule "snapshot" {
+#   cron           = "0 3 * * *"  # minute hour day-of-month month day-of-week, local time
+#   jitter_seconds = 60           # random delay added to each run
+#   allow_overlap  = false        # skip a run while the previous one is still going
+# }
+# schedule "nightly_report" {
+#   interval_seconds = 86400
+#   job              = "report"   # enqueue a job of this type on every run
+#   payload          = jsonencode({ days = 1 })
+# }
+
 # HTTP Server configuration block
 server {
   # Port on which the HTTP server will listen
@@ -14,8 +86,94 @@ server {
 
   # Address to bind the server (0.0.0.0 for all interfaces, 127.0.0.1 for localhost only)
   address = "0.0.0.0"
+
+  # CORS for browser front-ends on other origins (optional)
+  # cors {
+  #   allowed_origins   = ["https://app.example.com", "https://*.example.com"]  # or ["*"]
+  #   allowed_methods   = ["GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"]
+  #   allowed_headers   = ["Authorization", "Content-Type"]                     # "*" for any
+  #   exposed_headers   = ["Location", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"]
+  #   allow_credentials = false
+  #   max_age_seconds   = 600
+  # }
+
+  # Security headers on every response (optional); set a string to "" to omit it
+  # security_headers {
+  #   hsts_max_age_seconds    = 0    # enable only behind HTTPS, e.g. 31536000
+  #   hsts_include_subdomains = false
+  #   hsts_preload            = false
+  #   content_type_nosniff    = true
+  #   content_security_policy = "default-src 'none'; frame-ancestors 'none'"
+  #   frame_options           = "DENY"          # DENY or SAMEORIGIN
+  #   referrer_policy         = "no-referrer"
+  # }
 }
 
+# API authentication (optional)
+# Without this block every endpoint is open. With it, each route group (system,
+# api, admin) requires credentials unless its route_group block sets anonymous.
+# auth {
+#   # Static bearer tokens; the label is the principal name
+#   token "deploy" {
+#     file  = "/run/secrets/deploy_token"
+#     roles = ["admin"]
+#   }
+#
+#   # HMAC-SHA256 signed requests; the label is the key ID clients send
+#   hmac "billing" {
+#     secret_file = "/run/secrets/billing_hmac_key"
+#   }
+#   hmac_max_skew_seconds = 300
+#
+#   # JWT bearer tokens verified against a JWKS (jwks_file or jwks_url)
+#   jwt {
+#     jwks_url             = "https://idp.example.com/.well-known/jwks.json"
+#     # jwks_file          = "/etc/service-seed/jwks.json"  # instead of jwks_url
+#     jwks_refresh_seconds = 300
+#     issuer               = "https://idp.example.com/"
+#     audience             = ["service-seed"]
+#     leeway_seconds       = 60      # clock drift allowed in exp and nbf
+#     principal_claim      = "sub"
+#     roles_claim          = "roles"   # list or space-separated string
+#   }
+#
+#   route_group "system" {
+#     anonymous = true               # health checks and scrapes without credentials
+#   }
+#   route_group "admin" {
+#     methods = ["jwt"]              # default: every configured method
+#   }
+#
+#   # Role policies, at least one; requests no policy allows are denied.
+#   # Tokens and hmac keys take roles = [...]; JWTs use jwt.roles_claim.
+#   # "authenticated" matches any valid credentials, "*" anonymous requests too.
+#   policy "public" {
+#     roles   = ["*"]                # every request, anonymous included
+#     routes  = ["/v1/health"]       # exact paths or prefixes ending in /*
+#     methods = ["GET"]              # default: every method
+#   }
+#   policy "admins" {
+#     roles  = ["admin"]
+#     routes = ["/v1/admin/*", "/v1/system/*"]
+#   }
+#   audit_log = "audit.log"          # decisions as JSON lines, under log_dir (default)
+# }
+
+# Rate limiting (optional); over-limit requests get 429 with Retry-After
+# rate_limit {
+#   max_in_flight   = 200            # requests served at once, all groups (0 = no cap)
+#   trusted_proxies = ["10.0.0.0/8"] # read the client IP from X-Forwarded-For
+#   api_key_header  = "X-API-Key"    # read by key = "api_key"
+#   api_keys        = [file("/run/secrets/partner_key")]  # other keys fall back to the IP
+#
+#   # Token bucket per client; groups without a block are not limited
+#   route_group "api" {
+#     key                 = "principal"  # ip (default), principal or api_key
+#     requests_per_second = 10
+#     burst               = 20           # default: requests_per_second
+#   }
+# }
+
 # Telemetry configuration (optional)
 # Uncomment to enable OpenTelemetry export via OTLP gRPC
 # telemetry {
@@ -30,8 +188,11 @@ server {
 #   # }
 #
 #   # Optional: Shared headers (e.g., for authentication)
+#   # Secrets can be referenced instead of written in plaintext:
+#   #   file("/run/secrets/otlp_token"), env("OTLP_TOKEN"), secret("<provider>", "<ref>")
+#   # Resolved secrets are redacted in logs and the /v1/system/status endpoint.
 #   # headers = {
-#   #   "Authorization" = "Bearer token"
+#   #   "Authorization" = "Bearer ${file("/run/secrets/otlp_token")}"
 #   # }
 #
 #   # Metrics export configuration
@@ -55,3 +216,32 @@ server {
 #   #   # endpoint = "localhost:4317"  # Optional: Override shared endpoint
 #   # }
 # }
+
+# Client settings used by the status, health and metrics commands (optional)
+# client {
+#   # Agent base URL (default: derived from the server block, wildcard -> 127.0.0.1)
+#   address = "http://127.0.0.1:8080"
+#
+#   # Bearer token sent to the agent API
+#   # token = file("/run/secrets/agent_token")
+#
+#   timeout_seconds = 5
+#
+#   # tls {
+#   #   insecure  = false
+#   #   ca_file   = "/path/to/ca.crt"
+#   #   cert_file = "/path/to/client.crt"
+#   #   key_file  = "/path/to/client.key"
+#   # }
+# }
+
+# Profiles overlay environment-specific settings on the base configuration above.
+# Select one with --profile <name> or SS_PROFILE=<name>; unset fields are inherited.
+# profile "prod" {
+#   log_dir  = "/var/log/service-seed"
+#   data_dir = "/var/lib/service-seed"
+#
+#   server {
+#     port = "9595"
+#   }
+# }
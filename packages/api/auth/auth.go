@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cloudputation/service-seed/packages/config"
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/stats"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Name identifies the caller: the token name, the HMAC key ID or the JWT
+	// principal claim
+	Name string
+
+	// Method is how the caller authenticated: token, hmac or jwt
+	Method string
+
+	// Roles are granted by the token or HMAC key block, or read from the JWT
+	// roles claim
+	Roles []string
+
+	// Claims holds the verified JWT claims; nil for other methods
+	Claims map[string]any
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying p
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the request's principal; false for anonymous requests
+// and when auth is not configured
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// tracker receives the principal authenticated further down the handler chain
+type tracker struct {
+	principal *Principal
+}
+
+type trackerKey struct{}
+
+// Track returns a copy of ctx in which Middleware records the principal it
+// authenticates, and a function returning that principal (nil for anonymous
+// and rejected requests). Handlers wrapping Middleware, such as the access
+// log, use it to read the principal once the request is served.
+func Track(ctx context.Context) (context.Context, func() *Principal) {
+	t := &tracker{}
+	return context.WithValue(ctx, trackerKey{}, t), func() *Principal { return t.principal }
+}
+
+// errNoCredentials is returned when a request carries no Authorization header
+var errNoCredentials = errors.New("no credentials")
+
+type staticToken struct {
+	name  string
+	value []byte
+	roles []string
+}
+
+type hmacKey struct {
+	secret []byte
+	roles  []string
+}
+
+type groupPolicy struct {
+	methods   []string
+	anonymous bool
+}
+
+// Authenticator verifies request credentials according to the auth block.
+// A nil Authenticator lets every request through.
+type Authenticator struct {
+	tokens      []staticToken
+	hmacKeys    map[string]*hmacKey
+	hmacMaxSkew time.Duration
+	jwt         *jwtVerifier
+	groups      map[string]*groupPolicy
+	policies    []*policy
+}
+
+// New builds an Authenticator for the given route groups and route patterns
+// from cfg, reading token and key files and a local JWKS. A remote JWKS is
+// fetched by Start. Returns nil when cfg is nil.
+func New(cfg *config.Auth, groups, patterns []string) (*Authenticator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	a := &Authenticator{
+		hmacKeys:    map[string]*hmacKey{},
+		hmacMaxSkew: time.Duration(cfg.HMACMaxSkewSeconds) * time.Second,
+		groups:      map[string]*groupPolicy{},
+	}
+
+	for _, t := range cfg.Tokens {
+		value, err := readSecretFile(t.File)
+		if err != nil {
+			return nil, fmt.Errorf("token %q: %v", t.Name, err)
+		}
+		a.tokens = append(a.tokens, staticToken{name: t.Name, value: value, roles: t.Roles})
+	}
+
+	for _, k := range cfg.HMACKeys {
+		secret, err := readSecretFile(k.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("hmac key %q: %v", k.ID, err)
+		}
+		a.hmacKeys[k.ID] = &hmacKey{secret: secret, roles: k.Roles}
+	}
+
+	if cfg.JWT != nil {
+		verifier, err := newJWTVerifier(cfg.JWT)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: %v", err)
+		}
+		a.jwt = verifier
+	}
+
+	configured := cfg.Methods()
+	for _, name := range groups {
+		a.groups[name] = &groupPolicy{methods: configured}
+	}
+	for _, g := range cfg.RouteGroups {
+		policy, ok := a.groups[g.Name]
+		if !ok {
+			return nil, fmt.Errorf("route_group %q: unknown route group (want one of %s)", g.Name, strings.Join(groups, ", "))
+		}
+		if len(g.Methods) > 0 {
+			policy.methods = g.Methods
+		}
+		policy.anonymous = g.Anonymous
+	}
+
+	policies, err := newPolicies(cfg, patterns)
+	if err != nil {
+		return nil, err
+	}
+	a.policies = policies
+
+	return a, nil
+}
+
+// readSecretFile reads a token or key, ignoring surrounding whitespace
+func readSecretFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(config.ResolvePath(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret file: %v", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return nil, fmt.Errorf("secret file %s is empty", path)
+	}
+	return []byte(secret), nil
+}
+
+// Start fetches a remote JWKS and starts reloading the key set every
+// jwt.jwks_refresh_seconds. A failed first fetch is logged and retried when a
+// token arrives, so an unreachable identity provider does not block startup.
+func (a *Authenticator) Start() {
+	if a == nil || a.jwt == nil {
+		return
+	}
+	a.jwt.keys.start()
+}
+
+// Stop stops reloading the key set
+func (a *Authenticator) Stop() {
+	if a == nil || a.jwt == nil {
+		return
+	}
+	a.jwt.keys.stop()
+}
+
+// Middleware authenticates requests to a route group. Authenticated requests
+// carry their Principal in the context and as enduser.id and auth.method
+// attributes of the request span; others get 401 unless the group allows
+// anonymous requests. Requests no policy allows get 403 (401 when anonymous).
+func (a *Authenticator) Middleware(group string, next http.HandlerFunc) http.HandlerFunc {
+	if a == nil {
+		return next
+	}
+	policy := a.groups[group]
+	if policy == nil {
+		panic(fmt.Sprintf("auth: unknown route group %q", group))
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		principal, method, err := a.authenticate(r, policy.methods)
+
+		switch {
+		case err == nil:
+			stats.RecordAuth(ctx, group, principal.Method, "ok")
+			trace.SpanFromContext(ctx).SetAttributes(
+				attribute.String("enduser.id", principal.Name),
+				attribute.String("auth.method", principal.Method),
+			)
+			r = r.WithContext(NewContext(ctx, principal))
+			if t, ok := ctx.Value(trackerKey{}).(*tracker); ok {
+				t.principal = principal
+			}
+
+		case errors.Is(err, errNoCredentials) && policy.anonymous:
+			stats.RecordAuth(ctx, group, "none", "anonymous")
+
+		default:
+			stats.RecordAuth(ctx, group, method, "denied")
+			log.Warn(fmt.Sprintf("Authentication failed for %s %s (group: %s): %v", r.Method, r.URL.Path, group, err))
+			unauthorized(w, policy.methods)
+			return
+		}
+
+		if _, allowed := a.authorize(r, group, principal); !allowed {
+			if principal == nil {
+				unauthorized(w, policy.methods)
+			} else {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+			}
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// authenticate verifies the request's Authorization header with the first
+// accepted method that applies. It returns the method tried, or "none".
+func (a *Authenticator) authenticate(r *http.Request, methods []string) (*Principal, string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return nil, "none", errNoCredentials
+	}
+
+	scheme, credentials, _ := strings.Cut(header, " ")
+	credentials = strings.TrimSpace(credentials)
+
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		tokenAccepted := contains(methods, config.AuthMethodToken)
+		jwtAccepted := contains(methods, config.AuthMethodJWT) && strings.Count(credentials, ".") == 2
+
+		if tokenAccepted {
+			if t := a.matchToken(credentials); t != nil {
+				return &Principal{Name: t.name, Method: config.AuthMethodToken, Roles: t.roles}, config.AuthMethodToken, nil
+			}
+		}
+		if jwtAccepted {
+			p, err := a.jwt.verify(credentials)
+			return p, config.AuthMethodJWT, err
+		}
+		if tokenAccepted {
+			return nil, config.AuthMethodToken, errors.New("unknown bearer token")
+		}
+		return nil, "none", errors.New("bearer credentials are not accepted for this route")
+
+	case strings.EqualFold(scheme, HMACScheme):
+		if !contains(methods, config.AuthMethodHMAC) {
+			return nil, "none", errors.New("signed requests are not accepted for this route")
+		}
+		p, err := a.verifyHMAC(r, credentials)
+		return p, config.AuthMethodHMAC, err
+
+	default:
+		return nil, "none", fmt.Errorf("unsupported authorization scheme %q", scheme)
+	}
+}
+
+// matchToken compares the token against every static token in constant time
+func (a *Authenticator) matchToken(token string) *staticToken {
+	var match *staticToken
+	for i := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), a.tokens[i].value) == 1 {
+			match = &a.tokens[i]
+		}
+	}
+	return match
+}
+
+// unauthorized answers 401 with a WWW-Authenticate challenge for each
+// accepted scheme
+func unauthorized(w http.ResponseWriter, methods []string) {
+	if contains(methods, config.AuthMethodToken) || contains(methods, config.AuthMethodJWT) {
+		w.Header().Add("WWW-Authenticate", "Bearer")
+	}
+	if contains(methods, config.AuthMethodHMAC) {
+		w.Header().Add("WWW-Authenticate", HMACScheme)
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
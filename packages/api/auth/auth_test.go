@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestTrack checks handlers wrapping Middleware read the principal it
+// authenticated, and nil for rejected and anonymous requests
+func TestTrack(t *testing.T) {
+	a := &Authenticator{
+		tokens: []staticToken{{name: "ci", value: []byte("ci-token")}},
+		groups: map[string]*groupPolicy{
+			"api":    {methods: []string{"token"}},
+			"system": {methods: []string{"token"}, anonymous: true},
+		},
+		policies: []*policy{{name: "all", roles: []string{AnyRole}, routes: []string{"/v1/*"}}},
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	tests := []struct {
+		name       string
+		group      string
+		token      string
+		wantStatus int
+		wantName   string
+	}{
+		{"authenticated", "api", "ci-token", http.StatusOK, "ci"},
+		{"rejected", "api", "wrong", http.StatusUnauthorized, ""},
+		{"no credentials", "api", "", http.StatusUnauthorized, ""},
+		{"anonymous", "system", "", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/jobs", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			ctx, principalOf := Track(r.Context())
+			w := httptest.NewRecorder()
+
+			a.Middleware(tt.group, ok)(w, r.WithContext(ctx))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			name := ""
+			if p := principalOf(); p != nil {
+				name = p.Name
+			}
+			if name != tt.wantName {
+				t.Errorf("tracked principal = %q, want %q", name, tt.wantName)
+			}
+		})
+	}
+}
+
+// TestMiddlewarePolicies checks requests are denied unless a policy allows
+// them, including when no policy is configured
+func TestMiddlewarePolicies(t *testing.T) {
+	readers := []*policy{{name: "readers", roles: []string{"reader"}, routes: []string{"/v1/jobs"}, methods: []string{http.MethodGet}}}
+	anyone := []*policy{{name: "health", roles: []string{AnyRole}, routes: []string{"/v1/health"}}}
+	authenticated := []*policy{{name: "authenticated", roles: []string{AuthenticatedRole}, routes: []string{"/v1/*"}}}
+
+	tests := []struct {
+		name       string
+		policies   []*policy
+		method     string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{"no policies, authenticated", nil, http.MethodGet, "/v1/jobs", "reader-token", http.StatusForbidden},
+		{"no policies, anonymous", nil, http.MethodGet, "/v1/health", "", http.StatusUnauthorized},
+		{"role allowed", readers, http.MethodGet, "/v1/jobs", "reader-token", http.StatusOK},
+		{"method not allowed", readers, http.MethodPost, "/v1/jobs", "reader-token", http.StatusForbidden},
+		{"route not allowed", readers, http.MethodGet, "/v1/admin/snapshot", "reader-token", http.StatusForbidden},
+		{"role missing", readers, http.MethodGet, "/v1/jobs", "other-token", http.StatusForbidden},
+		{"any role, anonymous", anyone, http.MethodGet, "/v1/health", "", http.StatusOK},
+		{"authenticated role, no roles granted", authenticated, http.MethodPost, "/v1/jobs", "other-token", http.StatusOK},
+		{"authenticated role, anonymous", authenticated, http.MethodGet, "/v1/health", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Authenticator{
+				tokens: []staticToken{
+					{name: "reader", value: []byte("reader-token"), roles: []string{"reader"}},
+					{name: "other", value: []byte("other-token")},
+				},
+				groups:   map[string]*groupPolicy{"api": {methods: []string{"token"}, anonymous: true}},
+				policies: tt.policies,
+			}
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			a.Middleware("api", func(w http.ResponseWriter, r *http.Request) {})(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
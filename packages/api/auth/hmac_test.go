@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestHMACAuthenticator accepts requests signed with the "billing" key
+func newTestHMACAuthenticator() *Authenticator {
+	return &Authenticator{
+		hmacKeys: map[string]*hmacKey{
+			"billing": {secret: []byte("billing-secret"), roles: []string{"writer"}},
+		},
+		hmacMaxSkew: 5 * time.Minute,
+	}
+}
+
+// signedHeader builds an Authorization header for a request signed at timestamp
+func signedHeader(keyID string, secret []byte, method, uri string, timestamp int64, body string) string {
+	signature := sign(secret, method, uri, timestamp, []byte(body))
+	return fmt.Sprintf("%s key=%s, timestamp=%d, signature=%s", HMACScheme, keyID, timestamp, hex.EncodeToString(signature))
+}
+
+// TestSignRequest checks a request signed by SignRequest verifies and keeps
+// its body for the handler
+func TestSignRequest(t *testing.T) {
+	a := newTestHMACAuthenticator()
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/jobs?priority=high", strings.NewReader(`{"type":"report"}`))
+	if err := SignRequest(r, "billing", []byte("billing-secret")); err != nil {
+		t.Fatalf("SignRequest() failed: %v", err)
+	}
+
+	principal, method, err := a.authenticate(r, []string{"hmac"})
+	if err != nil {
+		t.Fatalf("authenticate() failed: %v", err)
+	}
+	if method != "hmac" || principal.Name != "billing" || principal.Method != "hmac" {
+		t.Errorf("authenticate() = %s:%s via %s, want hmac:billing", principal.Method, principal.Name, method)
+	}
+	if strings.Join(principal.Roles, ",") != "writer" {
+		t.Errorf("authenticate() roles = %v, want [writer]", principal.Roles)
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if string(body) != `{"type":"report"}` {
+		t.Errorf("body after verification = %q, want the original body", body)
+	}
+}
+
+// TestVerifyHMAC checks signatures, keys and the timestamp skew
+func TestVerifyHMAC(t *testing.T) {
+	a := newTestHMACAuthenticator()
+	secret := []byte("billing-secret")
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name    string
+		method  string
+		uri     string
+		body    string
+		header  string
+		wantErr string
+	}{
+		{
+			name: "valid", method: "POST", uri: "/v1/jobs", body: `{"a":1}`,
+			header: signedHeader("billing", secret, "POST", "/v1/jobs", now, `{"a":1}`),
+		},
+		{
+			name: "valid without body", method: "GET", uri: "/v1/jobs?status=dead",
+			header: signedHeader("billing", secret, "GET", "/v1/jobs?status=dead", now, ""),
+		},
+		{
+			name: "past skew within limit", method: "GET", uri: "/v1/jobs",
+			header: signedHeader("billing", secret, "GET", "/v1/jobs", now-290, ""),
+		},
+		{
+			name: "future skew within limit", method: "GET", uri: "/v1/jobs",
+			header: signedHeader("billing", secret, "GET", "/v1/jobs", now+290, ""),
+		},
+		{
+			name: "too old", method: "GET", uri: "/v1/jobs",
+			header:  signedHeader("billing", secret, "GET", "/v1/jobs", now-310, ""),
+			wantErr: "away from the server clock",
+		},
+		{
+			name: "too far in the future", method: "GET", uri: "/v1/jobs",
+			header:  signedHeader("billing", secret, "GET", "/v1/jobs", now+310, ""),
+			wantErr: "away from the server clock",
+		},
+		{
+			name: "body changed", method: "POST", uri: "/v1/jobs", body: `{"a":2}`,
+			header:  signedHeader("billing", secret, "POST", "/v1/jobs", now, `{"a":1}`),
+			wantErr: "signature mismatch",
+		},
+		{
+			name: "method changed", method: "DELETE", uri: "/v1/jobs",
+			header:  signedHeader("billing", secret, "GET", "/v1/jobs", now, ""),
+			wantErr: "signature mismatch",
+		},
+		{
+			name: "query changed", method: "GET", uri: "/v1/jobs?status=dead",
+			header:  signedHeader("billing", secret, "GET", "/v1/jobs", now, ""),
+			wantErr: "signature mismatch",
+		},
+		{
+			name: "wrong secret", method: "GET", uri: "/v1/jobs",
+			header:  signedHeader("billing", []byte("guessed"), "GET", "/v1/jobs", now, ""),
+			wantErr: "signature mismatch",
+		},
+		{
+			name: "unknown key", method: "GET", uri: "/v1/jobs",
+			header:  signedHeader("payroll", secret, "GET", "/v1/jobs", now, ""),
+			wantErr: "unknown hmac key",
+		},
+		{
+			name: "missing timestamp", method: "GET", uri: "/v1/jobs",
+			header:  HMACScheme + " key=billing, signature=00",
+			wantErr: "timestamp",
+		},
+		{
+			name: "missing signature", method: "GET", uri: "/v1/jobs",
+			header:  fmt.Sprintf("%s key=billing, timestamp=%d", HMACScheme, now),
+			wantErr: "missing or invalid signature",
+		},
+		{
+			name: "malformed credentials", method: "GET", uri: "/v1/jobs",
+			header:  HMACScheme + " billing",
+			wantErr: "malformed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.uri, strings.NewReader(tt.body))
+			r.Header.Set("Authorization", tt.header)
+
+			principal, _, err := a.authenticate(r, []string{"hmac"})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("authenticate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("authenticate() failed: %v", err)
+			}
+			if principal.Name != "billing" {
+				t.Errorf("authenticate() principal = %q, want billing", principal.Name)
+			}
+		})
+	}
+}
+
+// TestVerifyHMACNotAccepted checks signed requests are refused on routes that
+// do not accept the hmac method
+func TestVerifyHMACNotAccepted(t *testing.T) {
+	a := newTestHMACAuthenticator()
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/jobs", nil)
+	r.Header.Set("Authorization", signedHeader("billing", []byte("billing-secret"), "GET", "/v1/jobs", time.Now().Unix(), ""))
+
+	if _, _, err := a.authenticate(r, []string{"token"}); err == nil {
+		t.Error("authenticate() accepted a signed request on a token-only route")
+	}
+}
@@ -0,0 +1,319 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/config"
+	log "github.com/cloudputation/service-seed/packages/logger"
+)
+
+const (
+	// jwksFetchTimeout bounds a JWKS download
+	jwksFetchTimeout = 10 * time.Second
+
+	// jwksMaxBytes bounds the size of a key set
+	jwksMaxBytes = 1 << 20
+
+	// jwksMinReload is the shortest interval between reloads triggered by
+	// tokens signed with an unknown key
+	jwksMinReload = 30 * time.Second
+
+	// minRSABits rejects RSA keys too short to trust
+	minRSABits = 2048
+)
+
+// jwk is a JSON Web Key as found in a key set
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey is a signature verification key from the key set
+type publicKey struct {
+	kid string
+	alg string // empty when the key does not restrict its algorithm
+	key crypto.PublicKey
+}
+
+// keySet caches the keys of a JWKS file or URL
+type keySet struct {
+	file    string
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu       sync.RWMutex
+	keys     []*publicKey
+	loadedAt time.Time
+
+	// loadMu serializes reloads
+	loadMu sync.Mutex
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newKeySet(cfg *config.AuthJWT) *keySet {
+	return &keySet{
+		file:    cfg.JWKSFile,
+		url:     cfg.JWKSURL,
+		refresh: time.Duration(cfg.JWKSRefreshSeconds) * time.Second,
+		client:  &http.Client{Timeout: jwksFetchTimeout},
+	}
+}
+
+// source describes where the key set comes from, for log lines
+func (s *keySet) source() string {
+	if s.url != "" {
+		return s.url
+	}
+	return s.file
+}
+
+// load reads and parses the key set, replacing the cached keys on success
+func (s *keySet) load() error {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	s.mu.Lock()
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	data, err := s.read()
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *keySet) read() ([]byte, error) {
+	if s.file != "" {
+		data, err := os.ReadFile(config.ResolvePath(s.file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %v", err)
+		}
+		return data, nil
+	}
+
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s returned %s", s.url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, jwksMaxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	return data, nil
+}
+
+// lookup returns the keys that may have signed a token. An unknown key ID
+// reloads the set, at most once per jwksMinReload, to pick up rotated keys.
+func (s *keySet) lookup(kid, alg string) []*publicKey {
+	keys, stale := s.match(kid, alg)
+	if len(keys) == 0 && stale {
+		if err := s.load(); err != nil {
+			log.Warn(fmt.Sprintf("Failed to reload JWKS from %s: %v", s.source(), err))
+		}
+		keys, _ = s.match(kid, alg)
+	}
+	return keys
+}
+
+func (s *keySet) match(kid, alg string) ([]*publicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []*publicKey
+	for _, k := range s.keys {
+		if kid != "" && k.kid != kid {
+			continue
+		}
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, time.Since(s.loadedAt) >= jwksMinReload
+}
+
+// start loads the key set and reloads it every refresh interval until stop
+func (s *keySet) start() {
+	if err := s.load(); err != nil {
+		log.Warn(fmt.Sprintf("Failed to load JWKS from %s, retrying when tokens arrive: %v", s.source(), err))
+	} else {
+		log.Info(fmt.Sprintf("Loaded JWKS from %s (%d keys)", s.source(), s.count()))
+	}
+
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				if err := s.load(); err != nil {
+					log.Warn(fmt.Sprintf("Failed to reload JWKS from %s, keeping current keys: %v", s.source(), err))
+				}
+			}
+		}
+	}()
+}
+
+// stop ends the reload loop started by start
+func (s *keySet) stop() {
+	if s.done == nil {
+		return
+	}
+	close(s.done)
+	s.wg.Wait()
+	s.done = nil
+}
+
+func (s *keySet) count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// parseJWKS decodes the signature keys of a key set. Encryption keys, key
+// types other than RSA, EC and OKP (Ed25519) and RSA keys under 2048 bits are
+// skipped; a set with no usable key is an error naming the last one skipped.
+func parseJWKS(data []byte) ([]*publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	var keys []*publicKey
+	var skipped error
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			skipped = fmt.Errorf("key %q: %v", k.Kid, err)
+			continue
+		}
+		keys = append(keys, &publicKey{kid: k.Kid, alg: k.Alg, key: key})
+	}
+
+	if len(keys) == 0 {
+		if skipped != nil {
+			return nil, fmt.Errorf("JWKS has no usable signature keys (%v)", skipped)
+		}
+		return nil, errors.New("JWKS has no usable signature keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key material
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %v", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid exponent")
+		}
+		if n.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key is %d bits, need at least %d", n.BitLen(), minRSABits)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		var check ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, check = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, check = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, check = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		size := (curve.Params().BitSize + 7) / 8
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("invalid curve point")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := check.NewPublicKey(point); err != nil {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// jwtVerifier checks JWT signatures against a key set and the issuer,
+// audience and validity claims against the jwt block
+type jwtVerifier struct {
+	keys           *keySet
+	issuer         string
+	audience       []string
+	leeway         time.Duration
+	principalClaim string
+	rolesClaim     string
+}
+
+// newJWTVerifier builds a verifier; a JWKS file is loaded here so a missing or
+// invalid file fails startup and config validate
+func newJWTVerifier(cfg *config.AuthJWT) (*jwtVerifier, error) {
+	v := &jwtVerifier{
+		keys:           newKeySet(cfg),
+		issuer:         cfg.Issuer,
+		audience:       cfg.Audience,
+		leeway:         time.Duration(cfg.LeewaySeconds) * time.Second,
+		principalClaim: cfg.PrincipalClaim,
+		rolesClaim:     cfg.RolesClaim,
+	}
+
+	if cfg.JWKSFile != "" {
+		if err := v.keys.load(); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// verify checks a compact JWS token and returns its principal
+func (v *jwtVerifier) verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed JWT")
+	}
+
+	var header struct {
+		Alg  string   `json:"alg"`
+		Kid  string   `json:"kid"`
+		Crit []string `json:"crit"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed JWT header: %v", err)
+	}
+	if len(header.Crit) > 0 {
+		return nil, fmt.Errorf("unsupported critical JWT header parameters %v", header.Crit)
+	}
+	if _, ok := jwtHashes[header.Alg]; !ok {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed JWT signature")
+	}
+
+	keys := v.keys.lookup(header.Kid, header.Alg)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no JWKS key matches kid %q and alg %s", header.Kid, header.Alg)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, k := range keys {
+		if verifySignature(header.Alg, k.key, signed, signature) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("invalid JWT signature")
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed JWT claims: %v", err)
+	}
+	if err := v.checkClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	name, _ := claims[v.principalClaim].(string)
+	if name == "" {
+		return nil, fmt.Errorf("JWT has no %s claim", v.principalClaim)
+	}
+	return &Principal{Name: name, Method: config.AuthMethodJWT, Roles: claimRoles(claims[v.rolesClaim]), Claims: claims}, nil
+}
+
+// claimRoles reads roles from a list of strings or a space-separated string,
+// the form of OAuth scope claims
+func claimRoles(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		var roles []string
+		for _, role := range v {
+			if s, ok := role.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	}
+	return nil
+}
+
+// checkClaims enforces iss, aud, exp and nbf; exp is required
+func (v *jwtVerifier) checkClaims(claims map[string]any, now time.Time) error {
+	if iss, _ := claims["iss"].(string); iss != v.issuer {
+		return fmt.Errorf("JWT issuer %q is not accepted", iss)
+	}
+
+	var audiences []string
+	switch aud := claims["aud"].(type) {
+	case string:
+		audiences = []string{aud}
+	case []any:
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+	}
+	accepted := false
+	for _, a := range audiences {
+		if contains(v.audience, a) {
+			accepted = true
+			break
+		}
+	}
+	if !accepted {
+		return fmt.Errorf("JWT audience %v is not accepted", audiences)
+	}
+
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return errors.New("JWT has no exp claim")
+	}
+	if now.After(exp.Add(v.leeway)) {
+		return fmt.Errorf("JWT expired at %s", exp.UTC().Format(time.RFC3339))
+	}
+
+	if _, present := claims["nbf"]; present {
+		nbf, ok := numericDate(claims["nbf"])
+		if !ok {
+			return errors.New("JWT has an invalid nbf claim")
+		}
+		if now.Add(v.leeway).Before(nbf) {
+			return fmt.Errorf("JWT is not valid before %s", nbf.UTC().Format(time.RFC3339))
+		}
+	}
+
+	return nil
+}
+
+// numericDate converts a NumericDate claim (seconds since the epoch)
+func numericDate(value any) (time.Time, bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, 0).Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+// decodeSegment decodes a base64url JSON segment, keeping numbers exact
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// jwtHashes maps the supported JWS algorithms to their digest
+var jwtHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+	"EdDSA": 0,
+}
+
+// ecdsaCurveBits maps the ECDSA algorithms to the size of their curve
+var ecdsaCurveBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+// verifySignature checks a JWS signature with a key of the matching type
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	if alg == "EdDSA" {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, signed, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+
+	hash := jwtHashes[alg]
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key is not an RSA key")
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+
+	case "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key is not an RSA key")
+		}
+		return rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("key is not an EC key")
+		}
+		if pub.Curve.Params().BitSize != ecdsaCurveBits[alg] {
+			return errors.New("key curve does not match the algorithm")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testJWTKeys are the signing keys of the JWT tests: an ES256 key restricted
+// to its algorithm and an Ed25519 key that does not name one
+type testJWTKeys struct {
+	ec       *ecdsa.PrivateKey
+	ed       ed25519.PrivateKey
+	verifier *jwtVerifier
+}
+
+func newTestJWTKeys(t *testing.T) *testJWTKeys {
+	t.Helper()
+
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	edPub, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	keys := &keySet{
+		keys: []*publicKey{
+			{kid: "ec", alg: "ES256", key: &ec.PublicKey},
+			{kid: "ed", key: edPub},
+		},
+		// Keeps lookup from reloading the set, which has no file or URL
+		loadedAt: time.Now().Add(time.Hour),
+	}
+
+	return &testJWTKeys{
+		ec: ec,
+		ed: ed,
+		verifier: &jwtVerifier{
+			keys:           keys,
+			issuer:         "https://idp.test/",
+			audience:       []string{"seed", "seed-admin"},
+			leeway:         time.Minute,
+			principalClaim: "sub",
+			rolesClaim:     "roles",
+		},
+	}
+}
+
+// signES256 returns a compact JWS signed with the EC key, whatever alg the
+// header claims
+func (k *testJWTKeys) signES256(t *testing.T, header, claims map[string]any) string {
+	t.Helper()
+
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, k.ec, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// signEdDSA returns a compact JWS signed with the Ed25519 key
+func (k *testJWTKeys) signEdDSA(t *testing.T, header, claims map[string]any) string {
+	t.Helper()
+
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	signature := ed25519.Sign(k.ed, []byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to encode segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// TestJWTVerify checks signature, algorithm and claim validation
+func TestJWTVerify(t *testing.T) {
+	keys := newTestJWTKeys(t)
+	now := time.Now().Unix()
+
+	// claims returns valid claims with the given changes; a nil value removes
+	// the claim
+	claims := func(changes map[string]any) map[string]any {
+		c := map[string]any{
+			"iss":   "https://idp.test/",
+			"aud":   "seed",
+			"sub":   "alice",
+			"exp":   now + 600,
+			"roles": []string{"reader", "writer"},
+		}
+		for name, value := range changes {
+			if value == nil {
+				delete(c, name)
+				continue
+			}
+			c[name] = value
+		}
+		return c
+	}
+	es256 := map[string]any{"alg": "ES256", "kid": "ec", "typ": "JWT"}
+	eddsa := map[string]any{"alg": "EdDSA", "kid": "ed"}
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr string
+	}{
+		{"valid ES256", func() string { return keys.signES256(t, es256, claims(nil)) }, ""},
+		{"valid EdDSA", func() string { return keys.signEdDSA(t, eddsa, claims(nil)) }, ""},
+		{"audience list", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"aud": []string{"other", "seed-admin"}}))
+		}, ""},
+		{"expired within leeway", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"exp": now - 30}))
+		}, ""},
+		{"expired", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"exp": now - 120}))
+		}, "JWT expired"},
+		{"no exp", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"exp": nil}))
+		}, "no exp claim"},
+		{"nbf within leeway", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"nbf": now + 30}))
+		}, ""},
+		{"not yet valid", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"nbf": now + 120}))
+		}, "not valid before"},
+		{"invalid nbf", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"nbf": "soon"}))
+		}, "invalid nbf"},
+		{"wrong issuer", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"iss": "https://evil.test/"}))
+		}, "issuer"},
+		{"wrong audience", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"aud": "other"}))
+		}, "audience"},
+		{"no audience", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"aud": nil}))
+		}, "audience"},
+		{"no principal claim", func() string {
+			return keys.signES256(t, es256, claims(map[string]any{"sub": nil}))
+		}, "no sub claim"},
+		{"alg none", func() string {
+			return encodeSegment(t, map[string]any{"alg": "none"}) + "." + encodeSegment(t, claims(nil)) + "."
+		}, "unsupported JWT algorithm"},
+		{"alg HS256", func() string {
+			return keys.signES256(t, map[string]any{"alg": "HS256", "kid": "ec"}, claims(nil))
+		}, "unsupported JWT algorithm"},
+		{"alg other than the key's", func() string {
+			return keys.signES256(t, map[string]any{"alg": "RS256", "kid": "ec"}, claims(nil))
+		}, "no JWKS key matches"},
+		{"alg of another key type", func() string {
+			return keys.signES256(t, map[string]any{"alg": "ES256", "kid": "ed"}, claims(nil))
+		}, "invalid JWT signature"},
+		{"alg of another curve", func() string {
+			return keys.signEdDSA(t, map[string]any{"alg": "ES384", "kid": "ed"}, claims(nil))
+		}, "invalid JWT signature"},
+		{"unknown kid", func() string {
+			return keys.signES256(t, map[string]any{"alg": "ES256", "kid": "gone"}, claims(nil))
+		}, "no JWKS key matches"},
+		{"tampered claims", func() string {
+			token := keys.signES256(t, es256, claims(nil))
+			parts := strings.Split(token, ".")
+			parts[1] = encodeSegment(t, claims(map[string]any{"sub": "mallory"}))
+			return strings.Join(parts, ".")
+		}, "invalid JWT signature"},
+		{"critical header", func() string {
+			return keys.signES256(t, map[string]any{"alg": "ES256", "kid": "ec", "crit": []string{"exp"}}, claims(nil))
+		}, "critical"},
+		{"malformed", func() string { return "not.a-token" }, "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := keys.verifier.verify(tt.token())
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("verify() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify() failed: %v", err)
+			}
+			if principal.Name != "alice" || principal.Method != "jwt" {
+				t.Errorf("verify() principal = %s:%s, want jwt:alice", principal.Method, principal.Name)
+			}
+			if strings.Join(principal.Roles, ",") != "reader,writer" {
+				t.Errorf("verify() roles = %v, want [reader writer]", principal.Roles)
+			}
+		})
+	}
+}
+
+// TestClaimRoles checks the forms of the roles claim
+func TestClaimRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"list", []any{"reader", "writer"}, "reader,writer"},
+		{"scope string", "reader  writer", "reader,writer"},
+		{"list with other types", []any{"reader", 1.0, true}, "reader"},
+		{"missing", nil, ""},
+		{"number", 1.0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(claimRoles(tt.value), ","); got != tt.want {
+				t.Errorf("claimRoles(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cloudputation/service-seed/packages/audit"
+	"github.com/cloudputation/service-seed/packages/config"
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/stats"
+)
+
+// Policy roles matched by the request rather than granted to credentials
+const (
+	// AnyRole matches every request, anonymous ones included
+	AnyRole = config.AuthRoleAny
+
+	// AuthenticatedRole matches every request with verified credentials
+	AuthenticatedRole = config.AuthRoleAuthenticated
+)
+
+// policy is a resolved policy block
+type policy struct {
+	name    string
+	roles   []string
+	routes  []string
+	methods []string
+}
+
+// newPolicies resolves policy blocks, checking that every route matches at
+// least one served route pattern
+func newPolicies(cfg *config.Auth, patterns []string) ([]*policy, error) {
+	var policies []*policy
+	for _, p := range cfg.Policies {
+		for _, route := range p.Routes {
+			if !routeKnown(route, patterns) {
+				return nil, fmt.Errorf("policy %q: route %q matches no API route", p.Name, route)
+			}
+		}
+		policies = append(policies, &policy{name: p.Name, roles: p.Roles, routes: p.Routes, methods: p.Methods})
+	}
+	return policies, nil
+}
+
+// allows reports whether the policy covers the request
+func (p *policy) allows(principal *Principal, method, path string) bool {
+	return p.matchRole(principal) && p.matchMethod(method) && p.matchRoute(path)
+}
+
+func (p *policy) matchRole(principal *Principal) bool {
+	for _, role := range p.roles {
+		if role == AnyRole || (role == AuthenticatedRole && principal != nil) {
+			return true
+		}
+		if principal != nil && contains(principal.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *policy) matchMethod(method string) bool {
+	return len(p.methods) == 0 || contains(p.methods, "*") || contains(p.methods, method)
+}
+
+func (p *policy) matchRoute(path string) bool {
+	for _, route := range p.routes {
+		if routeMatches(route, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeMatches matches a request path against an exact route or a "/*" prefix.
+// "/v1/admin/*" matches /v1/admin and everything under it.
+func routeMatches(route, path string) bool {
+	prefix, ok := strings.CutSuffix(route, "*")
+	if !ok {
+		return path == route
+	}
+	return strings.HasPrefix(path, prefix) || path == strings.TrimSuffix(prefix, "/")
+}
+
+// routeKnown reports whether a policy route can match a request served by one
+// of the patterns. Patterns ending in "/" serve every path under them.
+func routeKnown(route string, patterns []string) bool {
+	prefix, isPrefix := strings.CutSuffix(route, "*")
+	for _, pattern := range patterns {
+		subtree := strings.HasSuffix(pattern, "/")
+		if !isPrefix {
+			if pattern == route || (subtree && strings.HasPrefix(route, pattern)) {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(pattern, prefix) || pattern == strings.TrimSuffix(prefix, "/") ||
+			(subtree && strings.HasPrefix(prefix, pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize evaluates the policies in order and records the decision. It
+// returns the allowing policy, or false when none allows the request.
+func (a *Authenticator) authorize(r *http.Request, group string, principal *Principal) (string, bool) {
+	ctx := r.Context()
+
+	event := audit.Event{
+		Type:       "authz",
+		Principal:  "anonymous",
+		Action:     r.Method,
+		Resource:   r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+	}
+	if principal != nil {
+		event.Principal = principal.Method + ":" + principal.Name
+		event.Roles = principal.Roles
+	}
+	span := trace.SpanFromContext(ctx)
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		event.TraceID = sc.TraceID().String()
+	}
+
+	for _, p := range a.policies {
+		if p.allows(principal, r.Method, r.URL.Path) {
+			event.Decision = audit.Allow
+			event.Policy = p.name
+			audit.Record(event)
+			stats.RecordAuthz(ctx, group, audit.Allow, p.name)
+			span.SetAttributes(attribute.String("auth.policy", p.name))
+			return p.name, true
+		}
+	}
+
+	event.Decision = audit.Deny
+	event.Reason = "no policy allows this request"
+	audit.Record(event)
+	stats.RecordAuthz(ctx, group, audit.Deny, "none")
+	log.Warn(fmt.Sprintf("Authorization denied for %s %s (principal: %s, group: %s)", r.Method, r.URL.Path, event.Principal, group))
+	return "", false
+}
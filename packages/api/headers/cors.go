@@ -0,0 +1,210 @@
+package headers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// CORS answers preflight requests and adds CORS headers to responses for
+// allowed origins, as configured by the server.cors block. A nil CORS sends
+// no CORS headers.
+type CORS struct {
+	anyOrigin   bool
+	origins     map[string]bool
+	suffixes    []originSuffix
+	methods     []string
+	headers     map[string]bool
+	anyHeader   bool
+	exposed     string
+	credentials bool
+	maxAge      string
+}
+
+// originSuffix matches "https://*.example.com": the scheme, then a host
+// ending in the domain (".example.com", with the pattern's port if any)
+type originSuffix struct {
+	scheme string
+	domain string
+}
+
+// NewCORS builds a CORS handler from cfg. Returns nil when cfg is nil.
+func NewCORS(cfg *config.CORS) *CORS {
+	if cfg == nil {
+		return nil
+	}
+
+	c := &CORS{
+		origins:     map[string]bool{},
+		methods:     cfg.AllowedMethods,
+		headers:     map[string]bool{},
+		exposed:     strings.Join(cfg.ExposedHeaders, ", "),
+		credentials: cfg.AllowCredentials,
+		maxAge:      strconv.Itoa(cfg.MaxAgeSeconds),
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		switch {
+		case origin == "*":
+			c.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			scheme, domain, _ := strings.Cut(origin, "://*")
+			c.suffixes = append(c.suffixes, originSuffix{scheme: scheme + "://", domain: domain})
+		default:
+			c.origins[origin] = true
+		}
+	}
+
+	for _, header := range cfg.AllowedHeaders {
+		if header == "*" {
+			c.anyHeader = true
+			continue
+		}
+		c.headers[strings.ToLower(header)] = true
+	}
+
+	return c
+}
+
+// Handler wraps next. Preflight requests are answered here, before
+// authentication, since browsers send them without credentials: 204 with the
+// allowed methods and headers, or 403 when the origin, method or headers are
+// not allowed.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		header := w.Header()
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Add("Vary", "Origin")
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			c.preflight(w, r, origin)
+			return
+		}
+
+		if !c.anyOrigin || c.credentials {
+			header.Add("Vary", "Origin")
+		}
+		if origin != "" && c.allowOrigin(origin) {
+			c.setOrigin(header, origin)
+			if c.exposed != "" {
+				header.Set("Access-Control-Expose-Headers", c.exposed)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight answers an OPTIONS request carrying Access-Control-Request-Method
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	method := r.Header.Get("Access-Control-Request-Method")
+	requested := requestedHeaders(r)
+
+	if origin == "" || !c.allowOrigin(origin) || !c.allowMethod(method) || !c.allowHeaders(requested) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	header := w.Header()
+	c.setOrigin(header, origin)
+	header.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+	if len(requested) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+	}
+	header.Set("Access-Control-Max-Age", c.maxAge)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOrigin allows the origin, echoing it unless any origin is allowed
+// without credentials
+func (c *CORS) setOrigin(header http.Header, origin string) {
+	if c.anyOrigin && !c.credentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+	if c.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *CORS) allowOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if c.origins[origin] {
+		return true
+	}
+	for _, s := range c.suffixes {
+		// The domain keeps the pattern's port, so ports must match too
+		host, ok := strings.CutPrefix(origin, s.scheme)
+		if !ok {
+			continue
+		}
+		if sub, ok := strings.CutSuffix(host, s.domain); ok && validSubdomain(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// validSubdomain reports whether sub is one or more DNS labels, so a host such
+// as "evil.com/.example.com" or "evil.com?.example.com" is not taken for a
+// subdomain
+func validSubdomain(sub string) bool {
+	for _, label := range strings.Split(sub, ".") {
+		if label == "" {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *CORS) allowMethod(method string) bool {
+	for _, m := range c.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) allowHeaders(requested []string) bool {
+	if c.anyHeader {
+		return true
+	}
+	for _, h := range requested {
+		if !c.headers[h] {
+			return false
+		}
+	}
+	return true
+}
+
+// requestedHeaders returns the lowercased Access-Control-Request-Headers
+func requestedHeaders(r *http.Request) []string {
+	var requested []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, h := range strings.Split(value, ",") {
+			if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+				requested = append(requested, h)
+			}
+		}
+	}
+	return requested
+}
@@ -0,0 +1,213 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// TestAllowOrigin checks exact, wildcard-subdomain and any-origin matching
+func TestAllowOrigin(t *testing.T) {
+	c := NewCORS(&config.CORS{AllowedOrigins: []string{
+		"https://app.example.com",
+		"https://*.example.org",
+		"http://*.local.test:8080",
+	}})
+	anyOrigin := NewCORS(&config.CORS{AllowedOrigins: []string{"*"}})
+
+	tests := []struct {
+		name   string
+		cors   *CORS
+		origin string
+		want   bool
+	}{
+		{"exact", c, "https://app.example.com", true},
+		{"exact, uppercase", c, "HTTPS://APP.EXAMPLE.COM", true},
+		{"exact, other scheme", c, "http://app.example.com", false},
+		{"exact, other port", c, "https://app.example.com:8443", false},
+		{"exact, subdomain", c, "https://x.app.example.com", false},
+		{"wildcard subdomain", c, "https://app.example.org", true},
+		{"wildcard nested subdomain", c, "https://a.b.example.org", true},
+		{"wildcard apex", c, "https://example.org", false},
+		{"wildcard empty label", c, "https://.example.org", false},
+		{"wildcard other scheme", c, "http://app.example.org", false},
+		{"wildcard with port", c, "https://app.example.org:8443", false},
+		{"wildcard lookalike domain", c, "https://evilexample.org", false},
+		{"wildcard suffix of another domain", c, "https://app.example.org.evil.com", false},
+		{"wildcard path in host", c, "https://evil.com/.example.org", false},
+		{"wildcard query in host", c, "https://evil.com?.example.org", false},
+		{"wildcard userinfo", c, "https://evil.com@app.example.org", false},
+		{"wildcard port in subdomain", c, "https://evil.com:443.example.org", false},
+		{"wildcard pattern port", c, "http://api.local.test:8080", true},
+		{"wildcard pattern port missing", c, "http://api.local.test", false},
+		{"wildcard pattern port different", c, "http://api.local.test:9090", false},
+		{"null origin", c, "null", false},
+		{"any origin", anyOrigin, "https://anything.example", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cors.allowOrigin(tt.origin); got != tt.want {
+				t.Errorf("allowOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPreflight checks preflight answers and the Vary header
+func TestPreflight(t *testing.T) {
+	cfg := &config.CORS{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		MaxAgeSeconds:  600,
+	}
+
+	tests := []struct {
+		name        string
+		cfg         *config.CORS
+		origin      string
+		method      string
+		headers     string
+		wantStatus  int
+		wantOrigin  string
+		wantHeaders string
+	}{
+		{"allowed", cfg, "https://app.example.com", http.MethodPost, "Authorization, content-type", http.StatusNoContent, "https://app.example.com", "authorization, content-type"},
+		{"no requested headers", cfg, "https://app.example.com", http.MethodGet, "", http.StatusNoContent, "https://app.example.com", ""},
+		{"origin not allowed", cfg, "https://evil.example.com", http.MethodGet, "", http.StatusForbidden, "", ""},
+		{"no origin", cfg, "", http.MethodGet, "", http.StatusForbidden, "", ""},
+		{"method not allowed", cfg, "https://app.example.com", http.MethodDelete, "", http.StatusForbidden, "", ""},
+		{"header not allowed", cfg, "https://app.example.com", http.MethodGet, "Authorization, X-Secret", http.StatusForbidden, "", ""},
+		{"any header", &config.CORS{
+			AllowedOrigins: []string{"https://app.example.com"},
+			AllowedMethods: []string{http.MethodGet},
+			AllowedHeaders: []string{"*"},
+		}, "https://app.example.com", http.MethodGet, "X-Anything", http.StatusNoContent, "https://app.example.com", "x-anything"},
+		{"any origin without credentials", &config.CORS{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{http.MethodGet},
+		}, "https://other.example", http.MethodGet, "", http.StatusNoContent, "*", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := NewCORS(tt.cfg).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			r := httptest.NewRequest(http.MethodOptions, "/v1/jobs", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			r.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if called {
+				t.Errorf("preflight reached the wrapped handler")
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Headers"); got != tt.wantHeaders {
+				t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, tt.wantHeaders)
+			}
+			if tt.wantStatus == http.StatusNoContent {
+				if got := w.Header().Get("Access-Control-Allow-Methods"); got != strings.Join(tt.cfg.AllowedMethods, ", ") {
+					t.Errorf("Access-Control-Allow-Methods = %q", got)
+				}
+			}
+			vary := strings.Join(w.Header().Values("Vary"), ", ")
+			if vary != "Origin, Access-Control-Request-Method, Access-Control-Request-Headers" {
+				t.Errorf("Vary = %q, want the origin and requested method and headers", vary)
+			}
+		})
+	}
+}
+
+// TestActualRequest checks CORS headers on non-preflight requests, which always
+// reach the wrapped handler
+func TestActualRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		cfg             *config.CORS
+		origin          string
+		wantOrigin      string
+		wantCredentials string
+		wantExposed     string
+		wantVary        string
+	}{
+		{"allowed", &config.CORS{AllowedOrigins: []string{"https://app.example.com"}, ExposedHeaders: []string{"Location", "Retry-After"}},
+			"https://app.example.com", "https://app.example.com", "", "Location, Retry-After", "Origin"},
+		{"not allowed", &config.CORS{AllowedOrigins: []string{"https://app.example.com"}, ExposedHeaders: []string{"Location"}},
+			"https://evil.example.com", "", "", "", "Origin"},
+		{"no origin", &config.CORS{AllowedOrigins: []string{"https://app.example.com"}},
+			"", "", "", "", "Origin"},
+		{"credentials", &config.CORS{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true},
+			"https://app.example.com", "https://app.example.com", "true", "", "Origin"},
+		{"any origin", &config.CORS{AllowedOrigins: []string{"*"}},
+			"https://other.example", "*", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := NewCORS(tt.cfg).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if !called {
+				t.Errorf("request did not reach the wrapped handler")
+			}
+			header := w.Header()
+			if got := header.Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := header.Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCredentials)
+			}
+			if got := header.Get("Access-Control-Expose-Headers"); got != tt.wantExposed {
+				t.Errorf("Access-Control-Expose-Headers = %q, want %q", got, tt.wantExposed)
+			}
+			if got := strings.Join(header.Values("Vary"), ", "); got != tt.wantVary {
+				t.Errorf("Vary = %q, want %q", got, tt.wantVary)
+			}
+		})
+	}
+}
+
+// TestNilCORS checks that without a cors block requests pass untouched
+func TestNilCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := NewCORS(nil).Handler(next)
+
+	r := httptest.NewRequest(http.MethodOptions, "/v1/jobs", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusTeapot || len(w.Header()) != 0 {
+		t.Errorf("without cors: status %d, headers %v; want the wrapped handler's response only", w.Code, w.Header())
+	}
+}
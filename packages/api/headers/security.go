@@ -0,0 +1,65 @@
+package headers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// Security adds the server.security_headers block's headers to every
+// response. A nil Security adds none.
+type Security struct {
+	headers map[string]string
+}
+
+// NewSecurity builds the header set from cfg, leaving out empty values.
+// Returns nil when cfg is nil.
+func NewSecurity(cfg *config.SecurityHeaders) *Security {
+	if cfg == nil {
+		return nil
+	}
+
+	s := &Security{headers: map[string]string{}}
+
+	if cfg.HSTSMaxAgeSeconds > 0 {
+		hsts := "max-age=" + strconv.Itoa(cfg.HSTSMaxAgeSeconds)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+		s.headers["Strict-Transport-Security"] = hsts
+	}
+	if cfg.ContentTypeNosniff != nil && *cfg.ContentTypeNosniff {
+		s.headers["X-Content-Type-Options"] = "nosniff"
+	}
+	if cfg.ContentSecurityPolicy != nil && *cfg.ContentSecurityPolicy != "" {
+		s.headers["Content-Security-Policy"] = *cfg.ContentSecurityPolicy
+	}
+	if cfg.FrameOptions != nil && *cfg.FrameOptions != "" {
+		s.headers["X-Frame-Options"] = *cfg.FrameOptions
+	}
+	if cfg.ReferrerPolicy != nil && *cfg.ReferrerPolicy != "" {
+		s.headers["Referrer-Policy"] = *cfg.ReferrerPolicy
+	}
+
+	return s
+}
+
+// Handler sets the headers before next runs, so they are also on error
+// responses such as 401, 404 and 429
+func (s *Security) Handler(next http.Handler) http.Handler {
+	if s == nil || len(s.headers) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		for name, value := range s.headers {
+			header.Set(name, value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// sweepInterval is how often idle buckets are dropped
+	sweepInterval = time.Minute
+
+	// maxClients caps the buckets of a route group; once full, new clients
+	// share the overflow bucket until a sweep frees room
+	maxClients = 100000
+
+	// overflowKey is the bucket shared by clients over maxClients
+	overflowKey = "overflow"
+)
+
+// bucket is one client's token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// decision is the outcome of taking a token, for the RateLimit headers
+type decision struct {
+	allowed    bool
+	remaining  int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// buckets holds the token buckets of a route group's clients
+type buckets struct {
+	rate       float64
+	burst      float64
+	maxClients int
+
+	mu        sync.Mutex
+	clients   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newBuckets(rate float64, burst int) *buckets {
+	return &buckets{
+		rate:       rate,
+		burst:      float64(burst),
+		maxClients: maxClients,
+		clients:    map[string]*bucket{},
+		lastSweep:  time.Now(),
+	}
+}
+
+// take refills the client's bucket and takes a token from it if one is left
+func (b *buckets) take(key string, now time.Time) decision {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Sub(b.lastSweep) >= sweepInterval {
+		b.sweep(now)
+	}
+
+	c, ok := b.clients[key]
+	if !ok && len(b.clients) >= b.maxClients {
+		// Sweeps at most once a second so a flood of new clients does not
+		// scan the table on every request
+		if now.Sub(b.lastSweep) >= time.Second {
+			b.sweep(now)
+		}
+		if len(b.clients) >= b.maxClients {
+			key = overflowKey
+			c, ok = b.clients[key]
+		}
+	}
+	if !ok {
+		c = &bucket{tokens: b.burst, last: now}
+		b.clients[key] = c
+	}
+	b.refill(c, now)
+
+	if c.tokens < 1 {
+		wait := b.duration(1 - c.tokens)
+		return decision{reset: wait, retryAfter: wait}
+	}
+
+	c.tokens--
+	return decision{
+		allowed:   true,
+		remaining: int(c.tokens),
+		reset:     b.duration(b.burst - c.tokens),
+	}
+}
+
+// refill adds the tokens earned since the bucket was last used
+func (b *buckets) refill(c *bucket, now time.Time) {
+	if elapsed := now.Sub(c.last).Seconds(); elapsed > 0 {
+		c.tokens = math.Min(b.burst, c.tokens+elapsed*b.rate)
+	}
+	c.last = now
+}
+
+// sweep drops buckets that have refilled, which behave like new ones
+func (b *buckets) sweep(now time.Time) {
+	for key, c := range b.clients {
+		if c.tokens+now.Sub(c.last).Seconds()*b.rate >= b.burst {
+			delete(b.clients, key)
+		}
+	}
+	b.lastSweep = now
+}
+
+// duration is how long the bucket takes to earn the given tokens
+func (b *buckets) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / b.rate * float64(time.Second))
+}
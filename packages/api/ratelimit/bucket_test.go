@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestBucketsTake checks the burst, refill and the durations behind the
+// RateLimit-Reset and Retry-After headers
+func TestBucketsTake(t *testing.T) {
+	start := time.Now()
+	b := newBuckets(2, 4)
+
+	// The burst is served at once, then the client waits for tokens
+	for i := 3; i >= 0; i-- {
+		d := b.take("a", start)
+		if !d.allowed || d.remaining != i {
+			t.Fatalf("take() = allowed %v, remaining %d, want allowed with %d left", d.allowed, d.remaining, i)
+		}
+		if want := time.Duration(4-i) * 500 * time.Millisecond; d.reset != want {
+			t.Errorf("take() reset = %v, want %v", d.reset, want)
+		}
+	}
+
+	d := b.take("a", start)
+	if d.allowed {
+		t.Fatal("take() allowed a request over the burst")
+	}
+	if d.retryAfter != 500*time.Millisecond || d.reset != d.retryAfter {
+		t.Errorf("take() retryAfter = %v, reset = %v, want 500ms", d.retryAfter, d.reset)
+	}
+
+	// Part of a token is not enough
+	d = b.take("a", start.Add(250*time.Millisecond))
+	if d.allowed || d.retryAfter != 250*time.Millisecond {
+		t.Errorf("take() after 250ms = allowed %v, retryAfter %v, want throttled for 250ms", d.allowed, d.retryAfter)
+	}
+
+	// One second earns two tokens
+	now := start.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		if !b.take("a", now).allowed {
+			t.Fatalf("take() %d after refill was throttled", i)
+		}
+	}
+	if b.take("a", now).allowed {
+		t.Error("take() allowed more than the refilled tokens")
+	}
+
+	// Refill stops at the burst
+	d = b.take("a", now.Add(time.Hour))
+	if !d.allowed || d.remaining != 3 {
+		t.Errorf("take() after an hour = allowed %v, remaining %d, want 3 left", d.allowed, d.remaining)
+	}
+
+	// Clients have their own buckets
+	if d := b.take("b", now); !d.allowed || d.remaining != 3 {
+		t.Errorf("take() for another client = allowed %v, remaining %d, want a full bucket", d.allowed, d.remaining)
+	}
+}
+
+// TestBucketsSweep checks refilled buckets are dropped and others kept
+func TestBucketsSweep(t *testing.T) {
+	start := time.Now()
+	b := newBuckets(1, 2)
+	b.lastSweep = start
+
+	b.take("idle", start)
+	for i := 0; i < 2; i++ {
+		b.take("busy", start.Add(sweepInterval))
+	}
+
+	if _, ok := b.clients["idle"]; ok {
+		t.Error("sweep kept a refilled bucket")
+	}
+	if _, ok := b.clients["busy"]; !ok {
+		t.Error("sweep dropped a bucket that is not full")
+	}
+}
+
+// TestBucketsMaxClients checks clients over the cap share the overflow bucket
+// until a sweep frees room
+func TestBucketsMaxClients(t *testing.T) {
+	start := time.Now()
+	b := newBuckets(1, 1)
+	b.maxClients = 3
+	b.lastSweep = start
+
+	for i := 0; i < 3; i++ {
+		if !b.take(fmt.Sprintf("client-%d", i), start).allowed {
+			t.Fatalf("take() for client-%d was throttled", i)
+		}
+	}
+
+	// New clients share one bucket, existing ones keep theirs
+	if !b.take("new-1", start).allowed {
+		t.Fatal("take() for the first overflow client was throttled")
+	}
+	if b.take("new-2", start).allowed {
+		t.Error("take() for a second overflow client was allowed; the overflow bucket is shared")
+	}
+	if _, ok := b.clients["new-1"]; ok {
+		t.Error("client over the cap got its own bucket")
+	}
+	if !b.take("client-0", start.Add(time.Second)).allowed {
+		t.Error("take() for a client under the cap was throttled")
+	}
+
+	// Once the buckets refill, a sweep frees room for new clients
+	now := start.Add(2 * time.Second)
+	if d := b.take("new-3", now); !d.allowed {
+		t.Fatal("take() after the sweep was throttled")
+	}
+	if _, ok := b.clients["new-3"]; !ok {
+		t.Error("sweep did not free room for a new client")
+	}
+}
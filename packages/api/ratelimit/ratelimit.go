@@ -0,0 +1,237 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/api/auth"
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/stats"
+)
+
+// Throttle reasons recorded by stats.RecordThrottled
+const (
+	ReasonRate     = "rate"
+	ReasonInFlight = "in_flight"
+)
+
+// groupLimit is a route group's per-client limit
+type groupLimit struct {
+	key     string
+	buckets *buckets
+}
+
+// Limiter throttles API requests according to the rate_limit block.
+// A nil Limiter lets every request through.
+type Limiter struct {
+	inFlight       chan struct{}
+	trustedProxies []*net.IPNet
+	apiKeyHeader   string
+	apiKeys        map[string]bool
+	groups         map[string]*groupLimit
+}
+
+// New builds a Limiter for the given route groups from cfg. Returns nil when
+// cfg is nil.
+func New(cfg *config.RateLimit, groups []string) (*Limiter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	l := &Limiter{
+		apiKeyHeader: cfg.APIKeyHeader,
+		apiKeys:      map[string]bool{},
+		groups:       map[string]*groupLimit{},
+	}
+	for _, key := range cfg.APIKeys {
+		l.apiKeys[hashKey(key)] = true
+	}
+	if cfg.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, cfg.MaxInFlight)
+	}
+
+	for _, cidr := range cfg.TrustedProxies {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("trusted_proxies: invalid CIDR %q", cidr)
+		}
+		l.trustedProxies = append(l.trustedProxies, network)
+	}
+
+	for _, g := range cfg.RouteGroups {
+		if !contains(groups, g.Name) {
+			return nil, fmt.Errorf("route_group %q: unknown route group (want one of %s)", g.Name, strings.Join(groups, ", "))
+		}
+		l.groups[g.Name] = &groupLimit{key: g.Key, buckets: newBuckets(g.RequestsPerSecond, g.Burst)}
+	}
+
+	return l, nil
+}
+
+// InFlight caps the requests served at once across every route it wraps.
+// Requests over max_in_flight get 429 straight away rather than queueing.
+func (l *Limiter) InFlight(group string, next http.HandlerFunc) http.HandlerFunc {
+	if l == nil || l.inFlight == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		select {
+		case l.inFlight <- struct{}{}:
+		default:
+			stats.RecordThrottled(ctx, group, ReasonInFlight)
+			tooManyRequests(w, time.Second)
+			return
+		}
+		stats.RecordInFlight(ctx, 1)
+		defer func() {
+			<-l.inFlight
+			stats.RecordInFlight(ctx, -1)
+		}()
+
+		next(w, r)
+	}
+}
+
+// Middleware limits each client of a route group keyed by ip or api_key to
+// its token bucket and sets the RateLimit-* headers on every response. It
+// runs before authentication so failed attempts are limited too. Groups
+// without a rate_limit route_group block are not limited.
+func (l *Limiter) Middleware(group string, next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+	limit := l.groups[group]
+	if limit == nil || limit.key == config.RateLimitKeyPrincipal {
+		return next
+	}
+	return l.limit(group, limit, next)
+}
+
+// PrincipalMiddleware is Middleware for route groups keyed by principal. It
+// runs after authentication, which sets the principal.
+func (l *Limiter) PrincipalMiddleware(group string, next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+	limit := l.groups[group]
+	if limit == nil || limit.key != config.RateLimitKeyPrincipal {
+		return next
+	}
+	return l.limit(group, limit, next)
+}
+
+// limit wraps next with the group's token buckets
+func (l *Limiter) limit(group string, limit *groupLimit, next http.HandlerFunc) http.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d", int(limit.buckets.burst), seconds(limit.buckets.duration(limit.buckets.burst)))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		d := limit.buckets.take(l.clientKey(r, limit.key), time.Now())
+
+		header := w.Header()
+		header.Set("RateLimit-Policy", policy)
+		header.Set("RateLimit-Limit", strconv.Itoa(int(limit.buckets.burst)))
+		header.Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(seconds(d.reset)))
+
+		if !d.allowed {
+			stats.RecordThrottled(r.Context(), group, ReasonRate)
+			tooManyRequests(w, d.retryAfter)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// clientKey identifies the request's client for the group's key. Anonymous
+// requests and requests without one of the configured API keys fall back to
+// the client IP, so clients cannot earn fresh buckets by changing the key.
+func (l *Limiter) clientKey(r *http.Request, key string) string {
+	switch key {
+	case config.RateLimitKeyPrincipal:
+		if p, ok := auth.FromContext(r.Context()); ok {
+			return "principal:" + p.Method + ":" + p.Name
+		}
+	case config.RateLimitKeyAPIKey:
+		if value := r.Header.Get(l.apiKeyHeader); value != "" {
+			if hash := hashKey(value); l.apiKeys[hash] {
+				return "api_key:" + hash
+			}
+		}
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+// hashKey returns the SHA-256 of an API key, so raw keys are not kept in memory
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// clientIP returns the request's remote address or, when it is a trusted
+// proxy, the nearest untrusted address in X-Forwarded-For
+func (l *Limiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !l.trusted(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		host = hop
+		if !l.trusted(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// trusted reports whether addr is one of the trusted proxies
+func (l *Limiter) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// tooManyRequests answers 429 with Retry-After in whole seconds
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, seconds(retryAfter))))
+	http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+}
+
+// seconds rounds d up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudputation/service-seed/packages/api/auth"
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// newTestLimiter limits the api group by the given key to one request per
+// client
+func newTestLimiter(t *testing.T, key string) *Limiter {
+	t.Helper()
+
+	l, err := New(&config.RateLimit{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		APIKeyHeader:   "X-API-Key",
+		APIKeys:        []string{"partner-key"},
+		RouteGroups:    []*config.RateLimitRouteGroup{{Name: "api", Key: key, RequestsPerSecond: 1, Burst: 1}},
+	}, []string{"api", "system"})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	return l
+}
+
+// TestClientKey checks how clients are identified for each key
+func TestClientKey(t *testing.T) {
+	l := newTestLimiter(t, config.RateLimitKeyIP)
+	alice := &auth.Principal{Name: "alice", Method: "token"}
+
+	tests := []struct {
+		name       string
+		key        string
+		remoteAddr string
+		forwarded  string
+		apiKey     string
+		principal  *auth.Principal
+		want       string
+	}{
+		{"ip", config.RateLimitKeyIP, "192.0.2.1:4000", "", "", nil, "ip:192.0.2.1"},
+		{"untrusted forwarded for", config.RateLimitKeyIP, "192.0.2.1:4000", "198.51.100.7", "", nil, "ip:192.0.2.1"},
+		{"trusted proxy", config.RateLimitKeyIP, "10.0.0.2:4000", "198.51.100.7, 10.0.0.9", "", nil, "ip:198.51.100.7"},
+		{"spoofed hop before the client", config.RateLimitKeyIP, "10.0.0.2:4000", "203.0.113.1, 198.51.100.7", "", nil, "ip:198.51.100.7"},
+		{"configured api key", config.RateLimitKeyAPIKey, "192.0.2.1:4000", "", "partner-key", nil, "api_key:" + hashKey("partner-key")},
+		{"unknown api key", config.RateLimitKeyAPIKey, "192.0.2.1:4000", "", "made-up-key", nil, "ip:192.0.2.1"},
+		{"no api key", config.RateLimitKeyAPIKey, "192.0.2.1:4000", "", "", nil, "ip:192.0.2.1"},
+		{"principal", config.RateLimitKeyPrincipal, "192.0.2.1:4000", "", "", alice, "principal:token:alice"},
+		{"anonymous", config.RateLimitKeyPrincipal, "192.0.2.1:4000", "", "", nil, "ip:192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/jobs", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.apiKey != "" {
+				r.Header.Set("X-API-Key", tt.apiKey)
+			}
+			if tt.principal != nil {
+				r = r.WithContext(auth.NewContext(context.Background(), tt.principal))
+			}
+
+			if got := l.clientKey(r, tt.key); got != tt.want {
+				t.Errorf("clientKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMiddlewarePlacement checks each middleware only limits the groups keyed
+// for its side of authentication, and that throttled requests get 429 with
+// Retry-After
+func TestMiddlewarePlacement(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	tests := []struct {
+		name      string
+		key       string
+		principal bool
+		wantLimit bool
+	}{
+		{"ip before authentication", config.RateLimitKeyIP, false, true},
+		{"ip after authentication", config.RateLimitKeyIP, true, false},
+		{"principal before authentication", config.RateLimitKeyPrincipal, false, false},
+		{"principal after authentication", config.RateLimitKeyPrincipal, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestLimiter(t, tt.key)
+			handler := l.Middleware("api", ok)
+			if tt.principal {
+				handler = l.PrincipalMiddleware("api", ok)
+			}
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				w = httptest.NewRecorder()
+				handler(w, httptest.NewRequest(http.MethodGet, "/v1/jobs", nil))
+			}
+
+			if !tt.wantLimit {
+				if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+					t.Errorf("status = %d with RateLimit-Limit %q, want 200 without limit headers", w.Code, w.Header().Get("RateLimit-Limit"))
+				}
+				return
+			}
+			if w.Code != http.StatusTooManyRequests {
+				t.Fatalf("status = %d, want 429", w.Code)
+			}
+			if got := w.Header().Get("Retry-After"); got != "1" {
+				t.Errorf("Retry-After = %q, want 1", got)
+			}
+			if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+				t.Errorf("RateLimit-Remaining = %q, want 0", got)
+			}
+		})
+	}
+
+	// Groups without a route_group block pass through
+	l := newTestLimiter(t, config.RateLimitKeyIP)
+	w := httptest.NewRecorder()
+	l.Middleware("system", ok)(w, httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+	if w.Header().Get("RateLimit-Limit") != "" {
+		t.Error("Middleware() limited a group without a route_group block")
+	}
+}
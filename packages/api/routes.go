@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/api/auth"
+	"github.com/cloudputation/service-seed/packages/api/headers"
+	"github.com/cloudputation/service-seed/packages/api/ratelimit"
+	"github.com/cloudputation/service-seed/packages/config"
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/stats"
+)
+
+// Route groups; authentication and rate limits are configured per group with
+// auth.route_group and rate_limit.route_group blocks
+const (
+	// GroupSystem holds health, status and metrics
+	GroupSystem = "system"
+
+	// GroupAPI holds the job queue and the service's resources
+	GroupAPI = "api"
+
+	// GroupAdmin holds operational endpoints such as snapshots
+	GroupAdmin = "admin"
+)
+
+// Groups lists the route groups
+func Groups() []string {
+	return []string{GroupSystem, GroupAPI, GroupAdmin}
+}
+
+// Route is an endpoint pattern served by a handler in a route group.
+// RequiresAuth routes are not registered without an auth block.
+type Route struct {
+	Group        string
+	Pattern      string
+	Handler      http.HandlerFunc
+	RequiresAuth bool
+}
+
+// served lists the routes registered with the current configuration
+func served() []Route {
+	var served []Route
+	for _, route := range routes() {
+		if route.RequiresAuth && config.AppConfig.Auth == nil {
+			continue
+		}
+		served = append(served, route)
+	}
+	return served
+}
+
+// Patterns lists the route patterns served
+func Patterns() []string {
+	var patterns []string
+	for _, route := range served() {
+		patterns = append(patterns, route.Pattern)
+	}
+	return patterns
+}
+
+// CheckConfig checks the auth and rate_limit blocks against the route groups
+// and patterns, reading auth secret files and a local JWKS, without starting
+// the server (config validate)
+func CheckConfig() error {
+	_, err := auth.New(config.AppConfig.Auth, Groups(), Patterns())
+	if err != nil {
+		return fmt.Errorf("auth: %v", err)
+	}
+	_, err = ratelimit.New(config.AppConfig.RateLimit, Groups())
+	if err != nil {
+		return fmt.Errorf("rate_limit: %v", err)
+	}
+	return nil
+}
+
+// newHandler serves the routes behind the server block's security headers and
+// CORS, which apply to every response, preflight requests and 404s included
+func newHandler(authenticator *auth.Authenticator, limiter *ratelimit.Limiter) http.Handler {
+	server := config.AppConfig.Server
+	handler := headers.NewCORS(server.CORS).Handler(newMux(authenticator, limiter))
+	return headers.NewSecurity(server.SecurityHeaders).Handler(handler)
+}
+
+// newMux registers every route behind the request middleware: a span and
+// HTTP metrics, the access log, the in-flight cap, the group's ip and api_key
+// rate limit, authentication for the route's group, then the group's
+// principal rate limit. Limits that do not need a principal run before
+// authentication so failed attempts are throttled too.
+func newMux(authenticator *auth.Authenticator, limiter *ratelimit.Limiter) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, route := range served() {
+		handler := limiter.PrincipalMiddleware(route.Group, route.Handler)
+		handler = authenticator.Middleware(route.Group, handler)
+		handler = limiter.Middleware(route.Group, handler)
+		handler = limiter.InFlight(route.Group, handler)
+		mux.HandleFunc(route.Pattern, stats.MetricsMiddleware(route.Pattern, accessLog(handler)))
+	}
+	return mux
+}
+
+// accessLog logs every request with its status, duration and principal,
+// including requests rejected by authentication or the in-flight cap
+func accessLog(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		// The principal is set by authentication, further down the chain
+		ctx, principalOf := auth.Track(r.Context())
+		next(recorder, r.WithContext(ctx))
+
+		principal := "-"
+		if p := principalOf(); p != nil {
+			principal = p.Method + ":" + p.Name
+		}
+		log.Info(fmt.Sprintf("%s %s %d %s (principal: %s)",
+			r.Method, r.URL.RequestURI(), recorder.status, time.Since(start).Round(time.Microsecond), principal))
+	}
+}
+
+// statusRecorder captures the response status for the access log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status before writing it
+func (w *statusRecorder) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher if the underlying ResponseWriter supports it
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying ResponseWriter supports it
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
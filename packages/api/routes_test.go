@@ -0,0 +1,40 @@
+package api
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// TestPatterns checks that routes exposing data_dir and job payloads are only
+// served when an auth block is configured
+func TestPatterns(t *testing.T) {
+	saved := config.AppConfig.Auth
+	t.Cleanup(func() { config.AppConfig.Auth = saved })
+
+	tests := []struct {
+		name   string
+		auth   *config.Auth
+		served bool
+	}{
+		{"without auth", nil, false},
+		{"with auth", &config.Auth{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AppConfig.Auth = tt.auth
+			patterns := Patterns()
+
+			if !slices.Contains(patterns, "/v1/health") {
+				t.Errorf("Patterns() = %v, want /v1/health", patterns)
+			}
+			for _, pattern := range []string{"/v1/admin/snapshot", "/v1/jobs", "/v1/jobs/"} {
+				if got := slices.Contains(patterns, pattern); got != tt.served {
+					t.Errorf("%s served = %v, want %v", pattern, got, tt.served)
+				}
+			}
+		})
+	}
+}
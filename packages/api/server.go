@@ -1,28 +1,91 @@
 package api
 
 import (
+    "context"
+    "errors"
     "fmt"
+    "net"
     "net/http"
+    "os/signal"
+    "syscall"
+    "time"
 
     "github.com/prometheus/client_golang/prometheus/promhttp"
 
+    "github.com/cloudputation/service-seed/packages/api/auth"
+    "github.com/cloudputation/service-seed/packages/api/ratelimit"
     "github.com/cloudputation/service-seed/packages/config"
     log "github.com/cloudputation/service-seed/packages/logger"
     "github.com/cloudputation/service-seed/packages/api/v1"
 )
 
 
-const MaxWorkers = 10
+// ShutdownTimeout bounds how long in-flight requests may run after SIGINT/SIGTERM
+const ShutdownTimeout = 10 * time.Second
 
-func StartServer() {
-  serverPort := fmt.Sprintf(":%s", config.AppConfig.Server.ServerPort)
-  log.Info("Starting server on port %s", serverPort)
+// routes lists every endpoint with its route group. Scaffolded resources are
+// added after the last /v1/ route. Routes marked RequiresAuth, which expose
+// data_dir and job payloads, are only served when an auth block is configured.
+func routes() []Route {
+  return []Route{
+      {Group: GroupSystem, Pattern: "/v1/health", Handler: v1.HealthHandler},
+      {Group: GroupSystem, Pattern: "/v1/system/status", Handler: v1.SystemStatusHandler},
+      {Group: GroupSystem, Pattern: "/v1/system/metrics", Handler: promhttp.Handler().ServeHTTP},
+      {Group: GroupAPI, Pattern: "/v1/jobs", Handler: v1.JobsHandler, RequiresAuth: true},
+      {Group: GroupAPI, Pattern: "/v1/jobs/", Handler: v1.JobHandler, RequiresAuth: true},
+      {Group: GroupAdmin, Pattern: "/v1/admin/snapshot", Handler: v1.AdminSnapshotHandler, RequiresAuth: true},
+  }
+}
 
-  http.HandleFunc("/v1/health", v1.HealthHandler)
-  http.Handle("/v1/system/metrics", promhttp.Handler())
+// StartServer serves the API until SIGINT or SIGTERM, then drains in-flight
+// requests and returns so the caller can release runtime resources. Errors are
+// returned rather than exiting, so the caller still stops the runtime.
+func StartServer() error {
+  // Bind the address checked by doctor
+  serverAddress := net.JoinHostPort(config.AppConfig.Server.ServerAddress, config.AppConfig.Server.ServerPort)
+  log.Info(fmt.Sprintf("Starting server on %s", serverAddress))
+
+  authenticator, err := auth.New(config.AppConfig.Auth, Groups(), Patterns())
+  if err != nil {
+      return fmt.Errorf("Failed to initialize authentication: %v", err)
+  }
+  authenticator.Start()
+  defer authenticator.Stop()
+
+  if config.AppConfig.Auth == nil {
+      log.Warn("No auth block configured; admin and job routes are disabled")
+  }
 
-  err := http.ListenAndServe(serverPort, nil)
+  limiter, err := ratelimit.New(config.AppConfig.RateLimit, Groups())
   if err != nil {
-      log.Fatal("HTTP server failed to start: %v", err)
+      return fmt.Errorf("Failed to initialize rate limiting: %v", err)
   }
+
+  server := &http.Server{Addr: serverAddress, Handler: newHandler(authenticator, limiter)}
+
+  ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+  defer stop()
+
+  serverErr := make(chan error, 1)
+  go func() {
+      serverErr <- server.ListenAndServe()
+  }()
+
+  select {
+  case err := <-serverErr:
+      if err != nil && !errors.Is(err, http.ErrServerClosed) {
+          return fmt.Errorf("HTTP server failed to start: %v", err)
+      }
+  case <-ctx.Done():
+      log.Info("Shutdown signal received, draining HTTP server")
+
+      shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+      defer cancel()
+      err := server.Shutdown(shutdownCtx)
+      if err != nil {
+          log.Error(fmt.Sprintf("HTTP server shutdown failed: %v", err))
+      }
+  }
+
+  return nil
 }
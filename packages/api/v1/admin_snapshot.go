@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/snapshot"
+	"github.com/cloudputation/service-seed/packages/stats"
+)
+
+// SnapshotChecksumTrailer carries the manifest checksum once a streamed
+// snapshot is complete; a response without it was cut short
+const SnapshotChecksumTrailer = "X-Snapshot-Checksum"
+
+// SnapshotResponse is returned by POST /v1/admin/snapshot
+type SnapshotResponse struct {
+	Path     string             `json:"path"`
+	Manifest *snapshot.Manifest `json:"manifest"`
+}
+
+// AdminSnapshotHandler streams a point-in-time snapshot of data_dir (GET) or
+// saves one in the snapshot directory (POST)
+func AdminSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		streamSnapshot(w, r)
+	case http.MethodPost:
+		saveSnapshot(w, r)
+	default:
+		log.Error(fmt.Sprintf("AdminSnapshotHandler: invalid request method %s", r.Method))
+		stats.ErrorCounter.Add(r.Context(), 1)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+func streamSnapshot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+snapshot.NewName(time.Now())+`"`)
+	w.Header().Set("Trailer", SnapshotChecksumTrailer)
+	w.WriteHeader(http.StatusOK)
+
+	timer := stats.NewTimer()
+	counter := &countingWriter{w: w}
+	manifest, err := snapshot.Write(r.Context(), counter)
+	stats.RecordSnapshot(r.Context(), "api", counter.n, timer.Elapsed(), err)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to stream snapshot: %v", err))
+		// Headers are sent; drop the connection so the client never sees a
+		// complete-looking archive
+		panic(http.ErrAbortHandler)
+	}
+
+	w.Header().Set(SnapshotChecksumTrailer, manifest.Checksum)
+	log.Info(fmt.Sprintf("Streamed snapshot (%d files, %d bytes)", len(manifest.Files), counter.n))
+}
+
+func saveSnapshot(w http.ResponseWriter, r *http.Request) {
+	path, manifest, err := snapshot.Take(r.Context(), "api")
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to save snapshot: %v", err))
+		http.Error(w, "Failed to save snapshot", http.StatusInternalServerError)
+		return
+	}
+	log.Info(fmt.Sprintf("Snapshot written: %s (%d files)", path, len(manifest.Files)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(SnapshotResponse{Path: path, Manifest: manifest}); err != nil {
+		log.Error(fmt.Sprintf("Failed to encode snapshot response: %v", err))
+		stats.ErrorCounter.Add(r.Context(), 1)
+	}
+}
+
+// countingWriter counts bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/jobs"
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/stats"
+)
+
+// EnqueueJobRequest is the body of POST /v1/jobs
+type EnqueueJobRequest struct {
+	Type         string          `json:"type"`
+	Payload      json.RawMessage `json:"payload,omitempty"`
+	MaxAttempts  int             `json:"max_attempts,omitempty"`
+	DelaySeconds int             `json:"delay_seconds,omitempty"`
+}
+
+// JobsHandler enqueues a job (POST) or lists jobs, optionally filtered with
+// ?status= (GET); ?status=dead is the dead-letter list
+func JobsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		enqueueJob(w, r)
+	case http.MethodGet:
+		listJobs(w, r)
+	default:
+		log.Error(fmt.Sprintf("JobsHandler: invalid request method %s", r.Method))
+		stats.ErrorCounter.Add(r.Context(), 1)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+// JobHandler returns a job's status (GET /v1/jobs/{id}) or retries a dead
+// job (POST /v1/jobs/{id}/retry)
+func JobHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/jobs/")
+	id, retry := strings.CutSuffix(id, "/retry")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case retry && r.Method == http.MethodPost:
+		job, err := jobs.Retry(r.Context(), id)
+		if err != nil {
+			writeJobError(w, r, err)
+			return
+		}
+		log.Info(fmt.Sprintf("Retrying dead job: %s", id))
+		writeJobJSON(w, r, http.StatusAccepted, job)
+	case !retry && r.Method == http.MethodGet:
+		job, err := jobs.Get(r.Context(), id)
+		if err != nil {
+			writeJobError(w, r, err)
+			return
+		}
+		writeJobJSON(w, r, http.StatusOK, job)
+	default:
+		log.Error(fmt.Sprintf("JobHandler: invalid request method %s", r.Method))
+		stats.ErrorCounter.Add(r.Context(), 1)
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+	}
+}
+
+func enqueueJob(w http.ResponseWriter, r *http.Request) {
+	var req EnqueueJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error(fmt.Sprintf("Failed to decode job request: %v", err))
+		stats.ErrorCounter.Add(r.Context(), 1)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.MaxAttempts < 0 || req.DelaySeconds < 0 {
+		http.Error(w, "max_attempts and delay_seconds must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	job, err := jobs.Enqueue(r.Context(), req.Type, req.Payload, &jobs.Options{
+		MaxAttempts: req.MaxAttempts,
+		Delay:       time.Duration(req.DelaySeconds) * time.Second,
+	})
+	if err != nil {
+		writeJobError(w, r, err)
+		return
+	}
+
+	log.Info(fmt.Sprintf("Enqueued job %s (%s)", job.ID, job.Type))
+	w.Header().Set("Location", "/v1/jobs/"+job.ID)
+	writeJobJSON(w, r, http.StatusAccepted, job)
+}
+
+func listJobs(w http.ResponseWriter, r *http.Request) {
+	status := jobs.Status(r.URL.Query().Get("status"))
+	if status != "" && !jobs.ValidStatus(status) {
+		http.Error(w, "status must be pending, running, succeeded or dead", http.StatusBadRequest)
+		return
+	}
+
+	list, err := jobs.List(r.Context(), status)
+	if err != nil {
+		writeJobError(w, r, err)
+		return
+	}
+	writeJobJSON(w, r, http.StatusOK, list)
+}
+
+// writeJobError maps job queue errors to HTTP statuses
+func writeJobError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, jobs.ErrUnknownType):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, jobs.ErrNotDead):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, jobs.ErrNotRunning):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		log.Error(fmt.Sprintf("Job queue error: %v", err))
+		stats.ErrorCounter.Add(r.Context(), 1)
+		http.Error(w, "Job queue error", http.StatusInternalServerError)
+	}
+}
+
+func writeJobJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error(fmt.Sprintf("Failed to encode job response: %v", err))
+		stats.ErrorCounter.Add(r.Context(), 1)
+	}
+}
@@ -3,17 +3,24 @@ package v1
 import (
 		"encoding/json"
 		"fmt"
+		"net"
 		"net/http"
 
+		"github.com/cloudputation/service-seed/packages/buildinfo"
 		"github.com/cloudputation/service-seed/packages/config"
+		"github.com/cloudputation/service-seed/packages/scheduler"
 		"github.com/cloudputation/service-seed/packages/stats"
 		log "github.com/cloudputation/service-seed/packages/logger"
 )
 
 type SystemStatusResponse struct {
-	Status  string `json:"status"`
-	DataDir string `json:"data_dir"`
-	LogDir  string `json:"log_dir"`
+	Status   string                 `json:"status"`
+	Version  string                 `json:"version"`
+	Profile  string                 `json:"profile,omitempty"`
+	Address  string                 `json:"address"`
+	DataDir  string                 `json:"data_dir"`
+	LogDir   string                 `json:"log_dir"`
+	Schedule []scheduler.TaskStatus `json:"schedule,omitempty"`
 }
 
 func SystemStatusHandlerWrapper(w http.ResponseWriter, r *http.Request) {
@@ -23,23 +30,27 @@ func SystemStatusHandlerWrapper(w http.ResponseWriter, r *http.Request) {
 func SystemStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		err := http.StatusMethodNotAllowed
-		log.Error("Received an invalid request method: %v", err)
+		log.Error(fmt.Sprintf("Received an invalid request method: %v", err))
 		stats.ErrorCounter.Add(r.Context(), 1)
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 	stats.SystemStatusEndpointCounter.Add(r.Context(), 1)
 
-	// Build simple system status response
+	// Build simple system status response; the configuration is not exposed
 	response := SystemStatusResponse{
-		Status:  "running",
-		DataDir: config.AppConfig.DataDir,
-		LogDir:  config.AppConfig.LogDir,
+		Status:   "running",
+		Version:  buildinfo.Version,
+		Profile:  config.ActiveProfile,
+		Address:  net.JoinHostPort(config.AppConfig.Server.ServerAddress, config.AppConfig.Server.ServerPort),
+		DataDir:  config.AppConfig.DataDir,
+		LogDir:   config.AppConfig.LogDir,
+		Schedule: scheduler.Status(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Error("Failed to encode system status response: %v", err)
+		log.Error(fmt.Sprintf("Failed to encode system status response: %v", err))
 		stats.ErrorCounter.Add(r.Context(), 1)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
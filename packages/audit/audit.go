@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/cloudputation/service-seed/packages/logger"
+)
+
+// Decisions recorded in Event.Decision
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Event is one audit record, written as a JSON line
+type Event struct {
+	Time time.Time `json:"time"`
+
+	// Type is the kind of event, e.g. "authz"
+	Type string `json:"type"`
+
+	// Principal is "<method>:<name>", or "anonymous"
+	Principal string   `json:"principal"`
+	Roles     []string `json:"roles,omitempty"`
+
+	// Action and Resource are what was attempted, e.g. POST /v1/admin/snapshot
+	Action   string `json:"action"`
+	Resource string `json:"resource"`
+
+	Decision string `json:"decision"`
+
+	// Policy names the policy that allowed the request
+	Policy string `json:"policy,omitempty"`
+
+	// Reason explains a denial
+	Reason string `json:"reason,omitempty"`
+
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	TraceID    string `json:"trace_id,omitempty"`
+}
+
+var (
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+)
+
+// Init opens the audit log for appending. An empty path turns auditing off.
+func Init(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if file != nil {
+		return fmt.Errorf("audit log is already open")
+	}
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to open audit log at path %s: %v", path, err)
+	}
+	file = f
+	enc = json.NewEncoder(f)
+	return nil
+}
+
+// Record appends an event; Time defaults to now. No-op when the audit log is
+// not open. Write failures are logged, not returned, so auditing never fails
+// a request.
+func Record(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if file == nil {
+		return
+	}
+	if err := enc.Encode(e); err != nil {
+		log.Error(fmt.Sprintf("Failed to write audit record: %v", err))
+	}
+}
+
+// Close flushes and closes the audit log
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if file == nil {
+		return nil
+	}
+	err := file.Sync()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	file, enc = nil, nil
+	return err
+}
@@ -1,28 +1,144 @@
 package bootstrap
 
 import (
-    "fmt"
-    "os"
+	"fmt"
+	"os"
 
-    "github.com/cloudputation/service-seed/packages/config"
-    log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/config"
+	log "github.com/cloudputation/service-seed/packages/logger"
 )
 
+// Directory is a configured directory prepared by BootstrapFileSystem
+type Directory struct {
+	// Name is the configuration key (e.g. "log_dir")
+	Name string
+
+	// Path is the resolved absolute path
+	Path string
+
+	// Created reports whether the directory was created by this run
+	Created bool
+}
+
+// Directories lists the directories prepared by the last BootstrapFileSystem call
+var Directories []Directory
+
+// warnings are logged by LogSummary once the logger is available
+var warnings []string
+
+// BootstrapFileSystem resolves and creates every configured directory with the
+// configured permissions, checks ownership and writability, takes the
+// single-instance lock in data_dir and migrates data_dir to the layout version
+// of this binary. It runs before the logger is initialized, so it does not log;
+// call LogSummary afterwards and ReleaseLock on shutdown.
 func BootstrapFileSystem() error {
-  log.Info("Starting Service agent.. Bootstrapping filesystem.")
-  dataDir := config.AppConfig.DataDir
-  rootDir := config.RootDir
-  log.Info("Loaded configuration file: %s", config.ConfigPath)
-
-  // Ensure data directory exists
-  dataDirPath := rootDir + "/" + dataDir
-  err := os.MkdirAll(dataDirPath, 0755)
-  if err != nil {
-      return fmt.Errorf("Failed to create data directory: %v", err)
-  }
-
-  log.Info("Data directory initialized at: %s", dataDirPath)
-  log.Info("FileSystem bootstrapping done!")
-
-  return nil
+	Directories = nil
+	warnings = nil
+
+	configured := []struct{ name, path string }{
+		{"log_dir", config.AppConfig.LogDir},
+		{"data_dir", config.AppConfig.DataDir},
+	}
+
+	for _, c := range configured {
+		dir, err := prepareDirectory(c.name, config.ResolvePath(c.path), config.AppConfig.Filesystem)
+		if err != nil {
+			return err
+		}
+		Directories = append(Directories, dir)
+	}
+
+	// Refuse to share data_dir with another live instance
+	dataDir := config.ResolvePath(config.AppConfig.DataDir)
+	err := AcquireLock(dataDir)
+	if err != nil {
+		return err
+	}
+
+	// Migrations run under the lock so no other instance sees a partial layout
+	err = migrateDataDir(dataDir)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogSummary logs the outcome of BootstrapFileSystem
+func LogSummary() {
+	log.Info(fmt.Sprintf("Loaded configuration file: %s", config.ConfigPath))
+	if config.ActiveProfile != "" {
+		log.Info(fmt.Sprintf("Active configuration profile: %s", config.ActiveProfile))
+	}
+
+	for _, dir := range Directories {
+		if dir.Created {
+			log.Info(fmt.Sprintf("Created %s at: %s", dir.Name, dir.Path))
+		} else {
+			log.Info(fmt.Sprintf("Using %s at: %s", dir.Name, dir.Path))
+		}
+	}
+	if lockFile != nil {
+		log.Info(fmt.Sprintf("Acquired instance lock: %s", lockFile.Name()))
+	}
+	if MigrationBackup != "" {
+		log.Info(fmt.Sprintf("Backed up data_dir before migrating to: %s", MigrationBackup))
+	}
+	for _, m := range Migrated {
+		log.Info(fmt.Sprintf("Migrated data_dir to layout version %d: %s", m.Version, m.Description))
+	}
+	log.Info(fmt.Sprintf("Data directory layout version: %d", DataVersion()))
+	for _, warning := range warnings {
+		log.Warn(warning)
+	}
+
+	log.Info("FileSystem bootstrapping done!")
+}
+
+func prepareDirectory(name, path string, fs *config.Filesystem) (Directory, error) {
+	dir := Directory{Name: name, Path: path}
+	mode := fs.Mode()
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		err = os.MkdirAll(path, mode)
+		if err != nil {
+			return dir, fmt.Errorf("Failed to create %s %s: %v", name, path, err)
+		}
+		// MkdirAll is subject to the umask; apply the configured mode explicitly
+		err = os.Chmod(path, mode)
+		if err != nil {
+			return dir, fmt.Errorf("Failed to set permissions on %s %s: %v", name, path, err)
+		}
+		dir.Created = true
+	case err != nil:
+		return dir, fmt.Errorf("Failed to access %s %s: %v", name, path, err)
+	case !info.IsDir():
+		return dir, fmt.Errorf("%s %s is not a directory", name, path)
+	case info.Mode().Perm() != mode:
+		warnings = append(warnings, fmt.Sprintf("%s %s has mode %#o, expected %#o", name, path, info.Mode().Perm(), mode))
+	}
+
+	err = CheckOwnership(path, fs)
+	if err != nil {
+		return dir, fmt.Errorf("%s %s: %v", name, path, err)
+	}
+
+	err = CheckWritable(path)
+	if err != nil {
+		return dir, fmt.Errorf("%s %s is not writable: %v", name, path, err)
+	}
+
+	return dir, nil
+}
+
+// CheckWritable creates and removes a temporary file in dir
+func CheckWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".bootstrap-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
 }
@@ -0,0 +1,156 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/buildinfo"
+)
+
+// LockFileName is the single-instance lock file created in data_dir
+const LockFileName = buildinfo.ServiceName + ".lock"
+
+// LockInfo is the metadata written to the lock file by the holding instance
+type LockInfo struct {
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	Version   string    `json:"version"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+func (i *LockInfo) String() string {
+	return fmt.Sprintf("pid %d on %s, version %s, started %s", i.PID, i.Hostname, i.Version, i.StartedAt.Format(time.RFC3339))
+}
+
+// lockFile is the open, locked file held until ReleaseLock
+var lockFile *os.File
+
+// AcquireLock takes an exclusive lock on data_dir/LockFileName and records this
+// process in it. Called by BootstrapFileSystem, and by offline commands (backup,
+// restore) that must not run next to an agent. A lock file with content but no
+// holder was left by a crashed instance; it is reported as a warning and overwritten.
+func AcquireLock(dataDir string) error {
+	path := filepath.Join(dataDir, LockFileName)
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to open lock file %s: %v", path, err)
+	}
+
+	locked, err := tryLock(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("Failed to lock %s: %v", path, err)
+	}
+	if !locked {
+		holder, _ := readLockInfo(f)
+		f.Close()
+		if holder != nil {
+			return fmt.Errorf("data_dir %s is in use by another instance (%s)", dataDir, holder)
+		}
+		return fmt.Errorf("data_dir %s is in use by another instance (lock file %s)", dataDir, path)
+	}
+
+	// The previous holder exited without releasing the lock (crash or kill)
+	if stale, _ := readLockInfo(f); stale != nil {
+		warnings = append(warnings, fmt.Sprintf("Removed stale lock left by %s", stale))
+	}
+
+	hostname, _ := os.Hostname()
+	info := LockInfo{
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		Version:   buildinfo.Version,
+		StartedAt: time.Now().UTC(),
+	}
+	if err := writeLockInfo(f, &info); err != nil {
+		unlock(f)
+		f.Close()
+		return fmt.Errorf("Failed to write lock file %s: %v", path, err)
+	}
+
+	lockFile = f
+	return nil
+}
+
+// ReleaseLock clears and unlocks the lock file. An empty lock file marks a clean
+// shutdown; the file itself is kept so waiting instances never lock an unlinked file.
+func ReleaseLock() error {
+	if lockFile == nil {
+		return nil
+	}
+	f := lockFile
+	lockFile = nil
+
+	truncErr := f.Truncate(0)
+	unlock(f)
+	closeErr := f.Close()
+
+	if truncErr != nil {
+		return truncErr
+	}
+	return closeErr
+}
+
+// LockHolder reports the live instance holding the lock in dataDir, or nil when
+// the lock is free. Used by preflight checks.
+func LockHolder(dataDir string) (*LockInfo, error) {
+	f, err := os.OpenFile(filepath.Join(dataDir, LockFileName), os.O_RDONLY, 0)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	locked, err := tryLock(f)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		unlock(f)
+		return nil, nil
+	}
+
+	holder, err := readLockInfo(f)
+	if holder == nil && err == nil {
+		holder = &LockInfo{}
+	}
+	return holder, err
+}
+
+// readLockInfo returns the metadata in the lock file, or nil when it is empty
+func readLockInfo(f *os.File) (*LockInfo, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid lock file content: %v", err)
+	}
+	return &info, nil
+}
+
+func writeLockInfo(f *os.File, info *LockInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(append(data, '\n'), 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
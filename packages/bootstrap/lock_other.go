@@ -0,0 +1,12 @@
+//go:build !unix
+
+package bootstrap
+
+import "os"
+
+// tryLock always succeeds: single-instance locking is only enforced on Unix
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
+
+func unlock(f *os.File) {}
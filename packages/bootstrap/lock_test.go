@@ -0,0 +1,140 @@
+//go:build unix
+
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// releaseOnCleanup releases the lock when the test ends, whatever its outcome
+func releaseOnCleanup(t *testing.T) {
+	t.Cleanup(func() {
+		if err := ReleaseLock(); err != nil {
+			t.Errorf("ReleaseLock() failed: %v", err)
+		}
+	})
+}
+
+// TestLockHeld checks that a second instance cannot take the lock and is told
+// which process holds it
+func TestLockHeld(t *testing.T) {
+	dataDir := t.TempDir()
+	releaseOnCleanup(t)
+
+	if err := AcquireLock(dataDir); err != nil {
+		t.Fatalf("AcquireLock() failed: %v", err)
+	}
+	held := lockFile
+
+	hostname, _ := os.Hostname()
+	want := fmt.Sprintf("pid %d on %s", os.Getpid(), hostname)
+	err := AcquireLock(dataDir)
+	if err == nil {
+		t.Fatalf("second AcquireLock() succeeded")
+	}
+	if !strings.Contains(err.Error(), "is in use by another instance") || !strings.Contains(err.Error(), want) {
+		t.Errorf("second AcquireLock() error = %q, want the holder (%s)", err, want)
+	}
+	if lockFile != held {
+		t.Errorf("failed AcquireLock() replaced the held lock file")
+	}
+
+	holder, err := LockHolder(dataDir)
+	if err != nil {
+		t.Fatalf("LockHolder() failed: %v", err)
+	}
+	if holder == nil || holder.PID != os.Getpid() || holder.Hostname != hostname || holder.StartedAt.IsZero() {
+		t.Errorf("LockHolder() = %+v, want this process", holder)
+	}
+}
+
+// TestLockStale checks that a lock file left by a crashed instance is taken
+// over with a warning
+func TestLockStale(t *testing.T) {
+	dataDir := t.TempDir()
+	releaseOnCleanup(t)
+	t.Cleanup(func() { warnings = nil })
+	warnings = nil
+
+	stale := LockInfo{PID: 999999, Hostname: "crashed-host", Version: "0.0.1", StartedAt: time.Now().Add(-time.Hour).UTC()}
+	data, err := json.Marshal(stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dataDir, LockFileName)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody holds the flock, so the file does not make the data_dir busy
+	if holder, err := LockHolder(dataDir); err != nil || holder != nil {
+		t.Errorf("LockHolder() of a stale lock = %+v, %v; want nil", holder, err)
+	}
+
+	if err := AcquireLock(dataDir); err != nil {
+		t.Fatalf("AcquireLock() over a stale lock failed: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "pid 999999 on crashed-host") {
+		t.Errorf("warnings = %q, want the stale lock reported", warnings)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info LockInfo
+	if err := json.Unmarshal(content, &info); err != nil {
+		t.Fatalf("lock file is not valid JSON: %v\n%s", err, content)
+	}
+	if info.PID != os.Getpid() {
+		t.Errorf("lock file records pid %d, want %d", info.PID, os.Getpid())
+	}
+}
+
+// TestReleaseLock checks that releasing empties the lock file and frees the
+// data_dir for the next instance
+func TestReleaseLock(t *testing.T) {
+	dataDir := t.TempDir()
+	t.Cleanup(func() { warnings = nil })
+	warnings = nil
+
+	if holder, err := LockHolder(dataDir); err != nil || holder != nil {
+		t.Errorf("LockHolder() without a lock file = %+v, %v; want nil", holder, err)
+	}
+
+	if err := AcquireLock(dataDir); err != nil {
+		t.Fatalf("AcquireLock() failed: %v", err)
+	}
+	if err := ReleaseLock(); err != nil {
+		t.Fatalf("ReleaseLock() failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dataDir, LockFileName))
+	if err != nil {
+		t.Fatalf("lock file removed by ReleaseLock(): %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("lock file has %d bytes after ReleaseLock(), want 0", info.Size())
+	}
+	if holder, err := LockHolder(dataDir); err != nil || holder != nil {
+		t.Errorf("LockHolder() after ReleaseLock() = %+v, %v; want nil", holder, err)
+	}
+	if err := ReleaseLock(); err != nil {
+		t.Errorf("second ReleaseLock() = %v, want a no-op", err)
+	}
+
+	// A clean shutdown leaves no stale lock warning for the next instance
+	if err := AcquireLock(dataDir); err != nil {
+		t.Fatalf("AcquireLock() after ReleaseLock() failed: %v", err)
+	}
+	defer ReleaseLock()
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %q after a clean release, want none", warnings)
+	}
+}
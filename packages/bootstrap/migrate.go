@@ -0,0 +1,289 @@
+package bootstrap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// VersionFileName is the layout version marker written in data_dir
+const VersionFileName = "VERSION"
+
+// BackupDirName holds the copies of data_dir taken before migrations run
+const BackupDirName = "migration-backups"
+
+// BaseDataVersion is the layout version of a data_dir that predates any migration
+const BaseDataVersion = 1
+
+// Migration upgrades data_dir from layout Version-1 to Version
+type Migration struct {
+	// Version is the layout version the migration produces
+	Version int
+
+	// Description is logged when the migration is applied
+	Description string
+
+	// Migrate rewrites the contents of dataDir in place
+	Migrate func(dataDir string) error
+}
+
+// migrations are the registered migrations, in registration order
+var migrations []Migration
+
+// RegisterMigration adds a data_dir migration. Packages owning persistent state
+// register migrations from init(); versions must follow on from BaseDataVersion
+// without gaps.
+func RegisterMigration(migration Migration) {
+	migrations = append(migrations, migration)
+}
+
+// DataVersion returns the data_dir layout version this binary writes
+func DataVersion() int {
+	version := BaseDataVersion
+	for _, m := range migrations {
+		if m.Version > version {
+			version = m.Version
+		}
+	}
+	return version
+}
+
+// Migrated lists the migrations applied by the last BootstrapFileSystem call
+var Migrated []Migration
+
+// MigrationBackup is the backup taken before the last migrations ran, if any
+var MigrationBackup string
+
+// ReadDataVersion reads the layout version from dataDir. found is false when
+// the VERSION file does not exist.
+func ReadDataVersion(dataDir string) (version int, found bool, err error) {
+	path := filepath.Join(dataDir, VersionFileName)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	version, err = strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || version < 1 {
+		return 0, true, fmt.Errorf("invalid layout version in %s: %q", path, strings.TrimSpace(string(data)))
+	}
+	return version, true, nil
+}
+
+// migrateDataDir brings dataDir to DataVersion. A new data_dir is stamped with
+// the current version; an existing one is backed up and migrated step by step,
+// recording the version after each step so a failed run resumes where it stopped.
+func migrateDataDir(dataDir string) error {
+	Migrated = nil
+	MigrationBackup = ""
+
+	ordered, err := orderedMigrations()
+	if err != nil {
+		return err
+	}
+	target := DataVersion()
+
+	current, found, err := ReadDataVersion(dataDir)
+	if err != nil {
+		return err
+	}
+	if !found {
+		empty, err := isEmptyDataDir(dataDir)
+		if err != nil {
+			return fmt.Errorf("Failed to read data_dir %s: %v", dataDir, err)
+		}
+		if empty {
+			return writeDataVersion(dataDir, target)
+		}
+		// Content written before the VERSION marker existed
+		current = BaseDataVersion
+	}
+
+	if current > target {
+		return fmt.Errorf("data_dir %s has layout version %d, newer than this binary supports (%d); upgrade the binary or restore a backup", dataDir, current, target)
+	}
+	if current == target {
+		if !found {
+			return writeDataVersion(dataDir, target)
+		}
+		return nil
+	}
+
+	backup, err := backupDataDir(dataDir, current)
+	if err != nil {
+		return fmt.Errorf("Failed to back up data_dir %s before migrating: %v", dataDir, err)
+	}
+	MigrationBackup = backup
+
+	for _, m := range ordered {
+		if m.Version <= current {
+			continue
+		}
+		if err := m.Migrate(dataDir); err != nil {
+			return fmt.Errorf("Migration to data_dir layout version %d (%s) failed: %v; a backup of the previous layout is at %s", m.Version, m.Description, err, backup)
+		}
+		if err := writeDataVersion(dataDir, m.Version); err != nil {
+			return err
+		}
+		Migrated = append(Migrated, m)
+	}
+
+	return nil
+}
+
+// orderedMigrations sorts the registry and checks it has no gaps or duplicates
+func orderedMigrations() ([]Migration, error) {
+	ordered := make([]Migration, len(migrations))
+	copy(ordered, migrations)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Version < ordered[j].Version })
+
+	for i, m := range ordered {
+		expected := BaseDataVersion + 1 + i
+		if m.Version != expected {
+			return nil, fmt.Errorf("invalid data_dir migrations: expected version %d, got %d (%s)", expected, m.Version, m.Description)
+		}
+		if m.Migrate == nil {
+			return nil, fmt.Errorf("invalid data_dir migrations: version %d (%s) has no Migrate function", m.Version, m.Description)
+		}
+	}
+	return ordered, nil
+}
+
+// isEmptyDataDir reports whether dataDir holds nothing besides bootstrap files
+func isEmptyDataDir(dataDir string) (bool, error) {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if !isBootstrapFile(entry.Name()) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isBootstrapFile reports whether a data_dir entry is managed by this package
+func isBootstrapFile(name string) bool {
+	return name == LockFileName || name == VersionFileName || name == BackupDirName
+}
+
+// writeDataVersion replaces the VERSION file atomically
+func writeDataVersion(dataDir string, version int) error {
+	path := filepath.Join(dataDir, VersionFileName)
+
+	tmp, err := os.CreateTemp(dataDir, ".VERSION-*")
+	if err != nil {
+		return fmt.Errorf("Failed to write %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = fmt.Fprintf(tmp, "%d\n", version)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// backupDataDir copies dataDir into BackupDirName/v<version>-<timestamp> and
+// returns the backup path. Snapshots are left out: they are archives of data_dir
+// already and would make every backup grow with them.
+func backupDataDir(dataDir string, version int) (string, error) {
+	name := fmt.Sprintf("v%d-%s", version, time.Now().UTC().Format("20060102T150405Z"))
+	backup := filepath.Join(dataDir, BackupDirName, name)
+
+	if err := os.MkdirAll(backup, 0700); err != nil {
+		return "", err
+	}
+	snapshotDir := config.SnapshotDir()
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.Name() == LockFileName || entry.Name() == BackupDirName {
+			continue
+		}
+		err := copyTree(filepath.Join(dataDir, entry.Name()), filepath.Join(backup, entry.Name()), snapshotDir)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return backup, nil
+}
+
+// copyTree copies a file or directory, preserving permissions, except the
+// directory skip
+func copyTree(src, dst, skip string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir() && path == skip:
+			return filepath.SkipDir
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Sockets, pipes and devices are not data
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
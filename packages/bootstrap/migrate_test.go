@@ -0,0 +1,232 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// setMigrations replaces the registry for the duration of a test
+func setMigrations(t *testing.T, registered ...Migration) {
+	t.Helper()
+	saved := migrations
+	t.Cleanup(func() { migrations = saved })
+	migrations = registered
+}
+
+func noop(dataDir string) error { return nil }
+
+// TestOrderedMigrations checks ordering and gap and duplicate detection
+func TestOrderedMigrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []int
+		nilFunc  bool
+		want     []int
+		wantErr  string
+	}{
+		{"none", nil, false, nil, ""},
+		{"in order", []int{2, 3}, false, []int{2, 3}, ""},
+		{"registered out of order", []int{4, 2, 3}, false, []int{2, 3, 4}, ""},
+		{"gap", []int{2, 4}, false, nil, "expected version 3, got 4"},
+		{"duplicate", []int{2, 2}, false, nil, "expected version 3, got 2"},
+		{"not following the base version", []int{3}, false, nil, "expected version 2, got 3"},
+		{"base version", []int{BaseDataVersion}, false, nil, "expected version 2, got 1"},
+		{"no Migrate function", []int{2}, true, nil, "has no Migrate function"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var registered []Migration
+			for _, v := range tt.versions {
+				m := Migration{Version: v, Description: fmt.Sprintf("v%d", v), Migrate: noop}
+				if tt.nilFunc {
+					m.Migrate = nil
+				}
+				registered = append(registered, m)
+			}
+			setMigrations(t, registered...)
+
+			ordered, err := orderedMigrations()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("orderedMigrations() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("orderedMigrations() failed: %v", err)
+			}
+			var got []int
+			for _, m := range ordered {
+				got = append(got, m.Version)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("orderedMigrations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// setupMigrationDir points the configuration at an empty data_dir
+func setupMigrationDir(t *testing.T) string {
+	t.Helper()
+
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+
+	dataDir := t.TempDir()
+	config.AppConfig = config.Configuration{
+		DataDir:  dataDir,
+		Snapshot: &config.Snapshot{Dir: "snapshots"},
+	}
+	return dataDir
+}
+
+func readVersion(t *testing.T, dataDir string) int {
+	t.Helper()
+	version, found, err := ReadDataVersion(dataDir)
+	if err != nil || !found {
+		t.Fatalf("ReadDataVersion() = %d, %v, %v", version, found, err)
+	}
+	return version
+}
+
+// recordingMigrations registers migrations 2..last that record their order;
+// failAt makes that version fail
+func recordingMigrations(t *testing.T, last, failAt int) *[]int {
+	t.Helper()
+
+	var applied []int
+	var registered []Migration
+	for v := BaseDataVersion + 1; v <= last; v++ {
+		version := v
+		registered = append(registered, Migration{
+			Version:     version,
+			Description: fmt.Sprintf("v%d", version),
+			Migrate: func(dataDir string) error {
+				if version == failAt {
+					return fmt.Errorf("boom")
+				}
+				applied = append(applied, version)
+				return os.WriteFile(filepath.Join(dataDir, fmt.Sprintf("v%d", version)), nil, 0644)
+			},
+		})
+	}
+	setMigrations(t, registered...)
+	return &applied
+}
+
+// TestMigrateNewDataDir checks that an empty data_dir is stamped with the
+// current version without running migrations
+func TestMigrateNewDataDir(t *testing.T) {
+	dataDir := setupMigrationDir(t)
+	applied := recordingMigrations(t, 3, 0)
+
+	if err := migrateDataDir(dataDir); err != nil {
+		t.Fatalf("migrateDataDir() failed: %v", err)
+	}
+	if got := readVersion(t, dataDir); got != 3 {
+		t.Errorf("VERSION = %d, want 3", got)
+	}
+	if len(*applied) != 0 || MigrationBackup != "" {
+		t.Errorf("new data_dir ran migrations %v with backup %q", *applied, MigrationBackup)
+	}
+}
+
+// TestMigrateExistingDataDir checks that data written before the VERSION
+// marker is backed up and migrated in order, and that snapshots are left out
+// of the backup
+func TestMigrateExistingDataDir(t *testing.T) {
+	dataDir := setupMigrationDir(t)
+	applied := recordingMigrations(t, 3, 0)
+
+	if err := os.WriteFile(filepath.Join(dataDir, "data"), []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(config.SnapshotDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateDataDir(dataDir); err != nil {
+		t.Fatalf("migrateDataDir() failed: %v", err)
+	}
+	if fmt.Sprint(*applied) != "[2 3]" || len(Migrated) != 2 {
+		t.Errorf("applied %v (Migrated %d), want [2 3]", *applied, len(Migrated))
+	}
+	if got := readVersion(t, dataDir); got != 3 {
+		t.Errorf("VERSION = %d, want 3", got)
+	}
+
+	if !strings.HasPrefix(filepath.Base(MigrationBackup), "v1-") {
+		t.Fatalf("MigrationBackup = %q, want a v1-* backup", MigrationBackup)
+	}
+	if data, err := os.ReadFile(filepath.Join(MigrationBackup, "data")); err != nil || string(data) != "v1" {
+		t.Errorf("backup data = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(MigrationBackup, "snapshots")); !os.IsNotExist(err) {
+		t.Errorf("snapshot directory was copied into the migration backup")
+	}
+
+	// Up to date: nothing runs
+	*applied = nil
+	if err := migrateDataDir(dataDir); err != nil {
+		t.Fatalf("second migrateDataDir() failed: %v", err)
+	}
+	if len(*applied) != 0 || MigrationBackup != "" {
+		t.Errorf("up-to-date data_dir ran migrations %v with backup %q", *applied, MigrationBackup)
+	}
+}
+
+// TestMigrateResumes checks that a failed migration leaves the last applied
+// version recorded so the next run resumes there
+func TestMigrateResumes(t *testing.T) {
+	dataDir := setupMigrationDir(t)
+	if err := writeDataVersion(dataDir, BaseDataVersion); err != nil {
+		t.Fatal(err)
+	}
+	recordingMigrations(t, 4, 3)
+
+	err := migrateDataDir(dataDir)
+	if err == nil || !strings.Contains(err.Error(), "version 3") || !strings.Contains(err.Error(), MigrationBackup) {
+		t.Fatalf("migrateDataDir() error = %v, want version 3 to fail with the backup path", err)
+	}
+	if got := readVersion(t, dataDir); got != 2 {
+		t.Errorf("VERSION = %d after failure, want 2", got)
+	}
+
+	applied := recordingMigrations(t, 4, 0)
+	if err := migrateDataDir(dataDir); err != nil {
+		t.Fatalf("resumed migrateDataDir() failed: %v", err)
+	}
+	if fmt.Sprint(*applied) != "[3 4]" {
+		t.Errorf("resumed run applied %v, want [3 4]", *applied)
+	}
+	if got := readVersion(t, dataDir); got != 4 {
+		t.Errorf("VERSION = %d, want 4", got)
+	}
+	if !strings.HasPrefix(filepath.Base(MigrationBackup), "v2-") {
+		t.Errorf("MigrationBackup = %q, want a v2-* backup", MigrationBackup)
+	}
+}
+
+// TestMigrateNewerDataDir checks that a layout newer than the binary is refused
+func TestMigrateNewerDataDir(t *testing.T) {
+	dataDir := setupMigrationDir(t)
+	recordingMigrations(t, 2, 0)
+	if err := writeDataVersion(dataDir, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	err := migrateDataDir(dataDir)
+	if err == nil || !strings.Contains(err.Error(), "newer than this binary supports") {
+		t.Fatalf("migrateDataDir() error = %v, want a newer layout error", err)
+	}
+	if got := readVersion(t, dataDir); got != 5 {
+		t.Errorf("VERSION = %d, want it untouched", got)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package bootstrap
+
+import (
+	"fmt"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// CheckOwnership is only supported on Unix; configuring an owner or group elsewhere is an error
+func CheckOwnership(path string, fs *config.Filesystem) error {
+	if fs == nil || (fs.Owner == "" && fs.Group == "") {
+		return nil
+	}
+	return fmt.Errorf("filesystem.owner and filesystem.group are only supported on Unix")
+}
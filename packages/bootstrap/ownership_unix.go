@@ -0,0 +1,81 @@
+//go:build unix
+
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// CheckOwnership verifies path is owned by the configured filesystem owner and group
+func CheckOwnership(path string, fs *config.Filesystem) error {
+	if fs == nil || (fs.Owner == "" && fs.Group == "") {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot read ownership")
+	}
+
+	if fs.Owner != "" {
+		uid, err := lookupID(fs.Owner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("unknown filesystem.owner %q: %v", fs.Owner, err)
+		}
+		if stat.Uid != uid {
+			return fmt.Errorf("owned by uid %d, expected %s (uid %d)", stat.Uid, fs.Owner, uid)
+		}
+	}
+
+	if fs.Group != "" {
+		gid, err := lookupID(fs.Group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("unknown filesystem.group %q: %v", fs.Group, err)
+		}
+		if stat.Gid != gid {
+			return fmt.Errorf("group is gid %d, expected %s (gid %d)", stat.Gid, fs.Group, gid)
+		}
+	}
+
+	return nil
+}
+
+// lookupID accepts a numeric id or resolves a name with lookup
+func lookupID(nameOrID string, lookup func(string) (string, error)) (uint32, error) {
+	id, err := strconv.ParseUint(nameOrID, 10, 32)
+	if err == nil {
+		return uint32(id), nil
+	}
+
+	resolved, err := lookup(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+	id, err = strconv.ParseUint(resolved, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(id), nil
+}
@@ -0,0 +1,125 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// ServiceName identifies the service in telemetry and CLI output
+const ServiceName = "service-seed"
+
+// Build-time variables injected via ldflags:
+//
+//	-X github.com/cloudputation/service-seed/packages/buildinfo.Version=1.2.3
+//	-X github.com/cloudputation/service-seed/packages/buildinfo.Environment=production
+var (
+	Version     = "dev"
+	Environment = "development"
+)
+
+// Info describes the running binary
+type Info struct {
+	Service      string       `json:"service"`
+	Version      string       `json:"version"`
+	Environment  string       `json:"environment"`
+	Revision     string       `json:"revision,omitempty"`
+	RevisionTime string       `json:"revision_time,omitempty"`
+	Dirty        bool         `json:"dirty"`
+	GoVersion    string       `json:"go_version"`
+	Platform     string       `json:"platform"`
+	Module       string       `json:"module,omitempty"`
+	Deps         []Dependency `json:"deps,omitempty"`
+}
+
+// Dependency is a module compiled into the binary
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"`
+}
+
+var (
+	infoOnce sync.Once
+	info     Info
+)
+
+// Get returns build information from ldflags and runtime/debug.ReadBuildInfo
+func Get() Info {
+	infoOnce.Do(func() {
+		info = Info{
+			Service:     ServiceName,
+			Version:     Version,
+			Environment: Environment,
+			GoVersion:   runtime.Version(),
+			Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		}
+
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+
+		info.Module = bi.Main.Path
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Revision = setting.Value
+			case "vcs.time":
+				info.RevisionTime = setting.Value
+			case "vcs.modified":
+				info.Dirty = setting.Value == "true"
+			}
+		}
+
+		for _, dep := range bi.Deps {
+			d := Dependency{Path: dep.Path, Version: dep.Version}
+			if dep.Replace != nil {
+				d.Replace = dep.Replace.Path + "@" + dep.Replace.Version
+			}
+			info.Deps = append(info.Deps, d)
+		}
+	})
+
+	return info
+}
+
+// ShortRevision returns the first 12 characters of the VCS revision
+func (i Info) ShortRevision() string {
+	if len(i.Revision) > 12 {
+		return i.Revision[:12]
+	}
+	return i.Revision
+}
+
+// Attributes returns build attributes shared by the build info gauge and telemetry resources
+func Attributes() []attribute.KeyValue {
+	i := Get()
+	return []attribute.KeyValue{
+		attribute.String("version", i.Version),
+		attribute.String("environment", i.Environment),
+		attribute.String("revision", i.ShortRevision()),
+		attribute.Bool("dirty", i.Dirty),
+		attribute.String("go_version", i.GoVersion),
+	}
+}
+
+// Resource returns the OpenTelemetry resource used by metrics, traces and logs
+func Resource() *resource.Resource {
+	i := Get()
+	// Note: Using NewWithAttributes directly to avoid schema version conflicts
+	// between resource.Default() (v1.37.0) and semconv (v1.24.0)
+	return resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(i.Service),
+		semconv.ServiceVersion(i.Version),
+		semconv.DeploymentEnvironment(i.Environment),
+		attribute.String("service.build.revision", i.ShortRevision()),
+		attribute.Bool("service.build.dirty", i.Dirty),
+		attribute.String("service.build.go_version", i.GoVersion),
+	)
+}
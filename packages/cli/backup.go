@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cloudputation/service-seed/packages/bootstrap"
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/snapshot"
+)
+
+// newBackupCommand builds the `backup` command
+func newBackupCommand() *cobra.Command {
+	var flags clientFlags
+	var out string
+	var offline bool
+
+	var cmdBackup = &cobra.Command{
+		Use:   "backup",
+		Short: "Write a snapshot of data_dir to a tar.gz archive",
+		Long: `Write a snapshot of data_dir to a tar.gz archive with a manifest (service,
+version, data layout version, created-at and SHA-256 checksums).
+
+When the agent is stopped, data_dir is locked and archived directly. When it is
+running, the snapshot is streamed from its admin API (/v1/admin/snapshot), which
+reads the storage database in a transaction so the agent keeps serving. The
+admin API is only served when an auth block is configured.
+
+Without --out the archive is written to the snapshot directory (snapshot.dir)
+as manual-<service>-<timestamp>.tar.gz, which scheduled retention never removes.`,
+		Example: `  service-seed backup --out backup.tar.gz
+  service-seed backup --offline`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if err := config.LoadConfiguration(); err != nil {
+				return fmt.Errorf("Failed to load configuration: %v", err)
+			}
+			dataDir := config.ResolvePath(config.AppConfig.DataDir)
+
+			if out == "" {
+				if err := os.MkdirAll(config.SnapshotDir(), config.AppConfig.Filesystem.Mode()); err != nil {
+					return err
+				}
+				out = filepath.Join(config.SnapshotDir(), snapshot.ManualName(time.Now()))
+			}
+
+			holder, err := bootstrap.LockHolder(dataDir)
+			if err != nil {
+				return fmt.Errorf("Failed to inspect the instance lock: %v", err)
+			}
+
+			var manifest *snapshot.Manifest
+			if holder != nil {
+				if offline {
+					return fmt.Errorf("data_dir %s is in use by a running agent (%s); stop it or drop --offline", dataDir, holder)
+				}
+				manifest, err = downloadSnapshot(ctx, cmd, &flags, out)
+			} else {
+				manifest, err = writeOfflineSnapshot(ctx, dataDir, out)
+			}
+			if err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return writeJSON(cmd.OutOrStdout(), manifest)
+			}
+			printManifest(cmd.OutOrStdout(), "Wrote snapshot to "+out, manifest)
+			return nil
+		},
+	}
+	cmdBackup.Flags().StringVarP(&out, "out", "o", "", "Archive path (default: <snapshot.dir>/manual-<service>-<timestamp>.tar.gz)")
+	cmdBackup.Flags().BoolVar(&offline, "offline", false, "Fail instead of using the API when the agent is running")
+	flags.register(cmdBackup)
+
+	return cmdBackup
+}
+
+// writeOfflineSnapshot archives data_dir while holding the instance lock
+func writeOfflineSnapshot(ctx context.Context, dataDir, out string) (*snapshot.Manifest, error) {
+	if _, err := os.Stat(dataDir); err != nil {
+		return nil, fmt.Errorf("Failed to access data_dir: %v", err)
+	}
+	if err := bootstrap.AcquireLock(dataDir); err != nil {
+		return nil, err
+	}
+	defer bootstrap.ReleaseLock()
+
+	manifest, _, err := snapshot.WriteFile(ctx, out)
+	return manifest, err
+}
+
+// downloadSnapshot streams a snapshot from the running agent and verifies it
+func downloadSnapshot(ctx context.Context, cmd *cobra.Command, flags *clientFlags, out string) (*snapshot.Manifest, error) {
+	c, err := flags.newClient(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(out), ".snapshot-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	checksum, err := c.Snapshot(ctx, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := snapshot.Inspect(ctx, tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("Downloaded snapshot is invalid: %v", err)
+	}
+	if manifest.Checksum != checksum {
+		return nil, fmt.Errorf("Downloaded snapshot checksum %s does not match the agent's %s", manifest.Checksum, checksum)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return nil, err
+	}
+	if err := snapshot.MoveNew(tmp.Name(), out); err != nil {
+		return nil, fmt.Errorf("Failed to write %s: %v", out, err)
+	}
+	return manifest, nil
+}
+
+// newRestoreCommand builds the `restore` command
+func newRestoreCommand() *cobra.Command {
+	var in string
+	var force, dryRun, asJSON bool
+
+	var cmdRestore = &cobra.Command{
+		Use:   "restore",
+		Short: "Replace data_dir with the contents of a snapshot archive",
+		Long: `Replace data_dir with the contents of a snapshot archive written by backup or
+the admin API.
+
+The agent must be stopped. The archive is extracted and verified against its
+manifest before data_dir is touched. A data_dir that already holds data is only
+replaced with --force, after its contents are saved as a pre-restore snapshot in
+the snapshot directory. Snapshots from an older data layout are migrated on the
+next start; snapshots from a newer one are refused.`,
+		Example: `  service-seed restore --in backup.tar.gz --dry-run
+  service-seed restore --in backup.tar.gz --force`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+			stdout := cmd.OutOrStdout()
+
+			if dryRun {
+				manifest, err := snapshot.Inspect(ctx, in)
+				if err != nil {
+					return err
+				}
+				if asJSON {
+					return writeJSON(stdout, manifest)
+				}
+				printManifest(stdout, "Snapshot "+in+" is valid", manifest)
+				return nil
+			}
+
+			if err := config.LoadConfiguration(); err != nil {
+				return fmt.Errorf("Failed to load configuration: %v", err)
+			}
+			dataDir := config.ResolvePath(config.AppConfig.DataDir)
+
+			if err := os.MkdirAll(dataDir, config.AppConfig.Filesystem.Mode()); err != nil {
+				return fmt.Errorf("Failed to create data_dir %s: %v", dataDir, err)
+			}
+			if err := bootstrap.AcquireLock(dataDir); err != nil {
+				return fmt.Errorf("%v; stop the agent before restoring", err)
+			}
+			defer bootstrap.ReleaseLock()
+
+			manifest, preRestore, err := snapshot.Restore(ctx, in, force)
+			if preRestore != "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Saved previous data_dir contents to %s\n", preRestore)
+			}
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return writeJSON(stdout, manifest)
+			}
+			printManifest(stdout, "Restored "+in+" into "+dataDir, manifest)
+			if manifest.DataVersion < bootstrap.DataVersion() {
+				fmt.Fprintf(stdout, "Data layout version %d will be migrated to %d on the next start\n", manifest.DataVersion, bootstrap.DataVersion())
+			}
+			return nil
+		},
+	}
+	cmdRestore.Flags().StringVarP(&in, "in", "i", "", "Snapshot archive to restore")
+	cmdRestore.Flags().BoolVar(&force, "force", false, "Replace a data_dir that already holds data")
+	cmdRestore.Flags().BoolVar(&dryRun, "dry-run", false, "Only verify the archive against its manifest")
+	cmdRestore.Flags().BoolVar(&asJSON, "json", false, "Print the manifest as JSON")
+	cmdRestore.MarkFlagRequired("in")
+
+	return cmdRestore
+}
+
+// printManifest prints a one-screen summary of a snapshot manifest
+func printManifest(out io.Writer, title string, m *snapshot.Manifest) {
+	fmt.Fprintln(out, title)
+	fmt.Fprintf(out, "  Service:       %s %s\n", m.Service, m.Version)
+	fmt.Fprintf(out, "  Data version:  %d\n", m.DataVersion)
+	fmt.Fprintf(out, "  Created at:    %s\n", m.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(out, "  Files:         %d (%d bytes)\n", len(m.Files), m.Size())
+	fmt.Fprintf(out, "  Checksum:      %s\n", m.Checksum)
+}
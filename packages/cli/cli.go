@@ -2,32 +2,67 @@ package cli
 
 import (
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/cloudputation/service-seed/packages/api"
-	"github.com/cloudputation/service-seed/packages/bootstrap"
-	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/buildinfo"
 )
 
 func SetupRootCommand() *cobra.Command {
 	var rootCmd = &cobra.Command{
-		Use:   "service-seed",
-		Short: "Service Seed - A production-ready Go application template",
+		Use:     "service-seed",
+		Short:   "Service Seed - A production-ready Go application template",
+		Version: buildinfo.Version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Flags parsed successfully; remaining errors are not usage errors
+			cmd.SilenceUsage = true
+			return initRuntime(cmd)
+		},
 	}
-	rootCmd.CompletionOptions.HiddenDefaultCmd = true
+	// Replaced by the explicit `completion` command (see completion.go)
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+
+	// Global flags (apply to every command, override config file values)
+	flags := rootCmd.PersistentFlags()
+	flags.String("config", "", "Configuration file path (env: SS_CONFIG_FILE_PATH)")
+	flags.String("profile", "", "Configuration profile to overlay on the base settings (env: SS_PROFILE)")
+	flags.String("log-level", "", "Log level: debug, info, warn, error (env: SS_LOG_LEVEL)")
+	flags.String("log-dir", "", "Log directory (env: SS_LOG_DIR)")
+	flags.String("data-dir", "", "Data directory (env: SS_DATA_DIR)")
+	flags.String("port", "", "HTTP server port (env: SS_SERVER_PORT)")
+
+	viper.BindPFlag("ConfigPath", flags.Lookup("config"))
+	viper.BindPFlag("Profile", flags.Lookup("profile"))
+	viper.BindPFlag("LogLevel", flags.Lookup("log-level"))
+	viper.BindPFlag("LogDir", flags.Lookup("log-dir"))
+	viper.BindPFlag("DataDir", flags.Lookup("data-dir"))
+	viper.BindPFlag("ServerPort", flags.Lookup("port"))
 
 	var cmdAgent = &cobra.Command{
 		Use:   "agent",
 		Short: "Start the service agent",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := bootstrap.BootstrapFileSystem()
-			if err != nil {
-				log.Fatal("Failed to bootstrap the filesystem: %v", err)
-			}
-			api.StartServer()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The filesystem is bootstrapped by initRuntime before the logger starts.
+			// Errors are returned so main stops the runtime before exiting.
+			return api.StartServer()
 		},
 	}
 
 	rootCmd.AddCommand(cmdAgent)
+	rootCmd.AddCommand(newConfigCommand())
+	rootCmd.AddCommand(newVersionCommand())
+	rootCmd.AddCommand(newStatusCommand())
+	rootCmd.AddCommand(newHealthCommand())
+	rootCmd.AddCommand(newMetricsCommand())
+	rootCmd.AddCommand(newInitCommand())
+	rootCmd.AddCommand(newScaffoldCommand())
+	rootCmd.AddCommand(newDoctorCommand())
+	rootCmd.AddCommand(newBackupCommand())
+	rootCmd.AddCommand(newRestoreCommand())
+	rootCmd.AddCommand(newCompletionCommand())
+	rootCmd.AddCommand(newDocsCommand())
+
+	registerCompletions(rootCmd)
 
 	return rootCmd
 }
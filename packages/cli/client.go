@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cloudputation/service-seed/packages/client"
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// errUnhealthy signals a failed health check after the result has been printed
+var errUnhealthy = errors.New("agent is unhealthy")
+
+// clientFlags are shared by commands that talk to a running agent
+type clientFlags struct {
+	address       string
+	token         string
+	caFile        string
+	certFile      string
+	keyFile       string
+	tlsSkipVerify bool
+	timeout       int
+	asJSON        bool
+}
+
+func (f *clientFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.address, "address", "", "Agent base URL (default: client.address or derived from the server block)")
+	cmd.Flags().StringVar(&f.token, "token", "", "Bearer token for the agent API (default: client.token)")
+	cmd.Flags().StringVar(&f.caFile, "ca-file", "", "CA certificate to verify the agent")
+	cmd.Flags().StringVar(&f.certFile, "cert-file", "", "Client certificate (mutual TLS)")
+	cmd.Flags().StringVar(&f.keyFile, "key-file", "", "Client key (mutual TLS)")
+	cmd.Flags().BoolVar(&f.tlsSkipVerify, "tls-skip-verify", false, "Skip TLS certificate verification")
+	cmd.Flags().IntVar(&f.timeout, "timeout", 0, "Request timeout in seconds (default: client.timeout_seconds)")
+	cmd.Flags().BoolVar(&f.asJSON, "json", false, "Print output as JSON")
+}
+
+// newClient builds an API client from the config file, with flags taking precedence.
+// The config file is optional when --address is given.
+func (f *clientFlags) newClient(cmd *cobra.Command) (*client.Client, error) {
+	cfg := config.Client{}
+
+	err := config.LoadConfiguration()
+	switch {
+	case err == nil:
+		cfg = *config.AppConfig.Client
+	case !cmd.Flags().Changed("address"):
+		return nil, fmt.Errorf("Failed to load configuration (use --address to skip it): %v", err)
+	}
+
+	if cmd.Flags().Changed("address") {
+		cfg.Address = f.address
+	}
+	if cmd.Flags().Changed("token") {
+		cfg.Token = f.token
+	}
+	if cmd.Flags().Changed("timeout") {
+		cfg.TimeoutSeconds = f.timeout
+	}
+
+	if f.caFile != "" || f.certFile != "" || f.keyFile != "" || f.tlsSkipVerify {
+		tlsCfg := config.ClientTLSConfig{}
+		if cfg.TLS != nil {
+			tlsCfg = *cfg.TLS
+		}
+		if f.caFile != "" {
+			tlsCfg.CAFile = f.caFile
+		}
+		if f.certFile != "" {
+			tlsCfg.CertFile = f.certFile
+		}
+		if f.keyFile != "" {
+			tlsCfg.KeyFile = f.keyFile
+		}
+		if f.tlsSkipVerify {
+			tlsCfg.Insecure = true
+		}
+		cfg.TLS = &tlsCfg
+	}
+
+	return client.New(&cfg)
+}
+
+// newHealthCommand builds the `health` command (usable as a Docker HEALTHCHECK)
+func newHealthCommand() *cobra.Command {
+	var flags clientFlags
+
+	var cmdHealth = &cobra.Command{
+		Use:          "health",
+		Short:        "Check the health of a running agent (exits non-zero when unhealthy)",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := flags.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			result := c.Health(context.Background())
+			out := cmd.OutOrStdout()
+
+			if flags.asJSON {
+				if err := writeJSON(out, result); err != nil {
+					return err
+				}
+			} else if result.Healthy {
+				fmt.Fprintf(out, "healthy (HTTP %d, %.1fms)\n", result.StatusCode, result.LatencyMS)
+			} else if result.Error != "" {
+				fmt.Fprintf(out, "unhealthy: %s\n", result.Error)
+			} else {
+				fmt.Fprintf(out, "unhealthy: HTTP %d %s\n", result.StatusCode, result.Body)
+			}
+
+			if !result.Healthy {
+				cmd.SilenceErrors = true
+				return errUnhealthy
+			}
+			return nil
+		},
+	}
+	flags.register(cmdHealth)
+
+	return cmdHealth
+}
+
+// newStatusCommand builds the `status` command
+func newStatusCommand() *cobra.Command {
+	var flags clientFlags
+
+	var cmdStatus = &cobra.Command{
+		Use:          "status",
+		Short:        "Show the status of a running agent",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := flags.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			status, err := c.Status(context.Background())
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if flags.asJSON {
+				return writeJSON(out, status)
+			}
+
+			profile := status.Profile
+			if profile == "" {
+				profile = "(none)"
+			}
+			fmt.Fprintf(out, "Status:   %s\n", status.Status)
+			fmt.Fprintf(out, "Version:  %s\n", status.Version)
+			fmt.Fprintf(out, "Profile:  %s\n", profile)
+			fmt.Fprintf(out, "Server:   %s\n", status.Address)
+			fmt.Fprintf(out, "Data dir: %s\n", status.DataDir)
+			fmt.Fprintf(out, "Log dir:  %s\n", status.LogDir)
+			return nil
+		},
+	}
+	flags.register(cmdStatus)
+
+	return cmdStatus
+}
+
+// newMetricsCommand builds the `metrics` command
+func newMetricsCommand() *cobra.Command {
+	var flags clientFlags
+	var filter string
+	var raw bool
+
+	var cmdMetrics = &cobra.Command{
+		Use:          "metrics",
+		Short:        "Show metrics from a running agent",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := flags.newClient(cmd)
+			if err != nil {
+				return err
+			}
+
+			text, err := c.MetricsText(context.Background())
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if raw {
+				_, err := io.WriteString(out, text)
+				return err
+			}
+
+			families, err := client.ParseMetrics(text)
+			if err != nil {
+				return err
+			}
+
+			// Keep only families matching the name prefix, sorted by name
+			filtered := families[:0]
+			for _, family := range families {
+				if strings.HasPrefix(family.Name, filter) {
+					filtered = append(filtered, family)
+				}
+			}
+			sort.Slice(filtered, func(i, j int) bool { return filtered[i].Name < filtered[j].Name })
+
+			if flags.asJSON {
+				return writeJSON(out, filtered)
+			}
+
+			for _, family := range filtered {
+				for _, sample := range family.Samples {
+					fmt.Fprintf(out, "%s%s %g\n", family.Name, formatLabels(sample.Labels), sample.Value)
+				}
+			}
+			return nil
+		},
+	}
+	flags.register(cmdMetrics)
+	cmdMetrics.Flags().StringVar(&filter, "filter", "", "Only show metrics whose name starts with this prefix")
+	cmdMetrics.Flags().BoolVar(&raw, "raw", false, "Print the raw Prometheus exposition format")
+
+	return cmdMetrics
+}
+
+// formatLabels renders labels as {k="v",...} in key order, skipping OTel scope labels
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if strings.HasPrefix(k, "otel_scope_") {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", k, labels[k])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// writeJSON prints an indented JSON document
+func writeJSON(out io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
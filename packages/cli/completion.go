@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+
+	"github.com/cloudputation/service-seed/packages/buildinfo"
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/scaffold"
+)
+
+// newCompletionCommand builds the `completion` command
+func newCompletionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate a shell completion script",
+		Long: `Generate a shell completion script. Profile names, configuration keys and
+enumerated flag values are completed dynamically from the configuration file.
+
+  bash:       source <(service-seed completion bash)
+  zsh:        service-seed completion zsh > "${fpath[1]}/_service-seed"
+  fish:       service-seed completion fish > ~/.config/fish/completions/service-seed.fish
+  powershell: service-seed completion powershell | Out-String | Invoke-Expression`,
+		Args:                  cobra.ExactArgs(1),
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		Annotations:           map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			out := cmd.OutOrStdout()
+
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(out, true)
+			case "zsh":
+				return root.GenZshCompletion(out)
+			case "fish":
+				return root.GenFishCompletion(out, true)
+			case "powershell":
+				return root.GenPowerShellCompletionWithDesc(out)
+			default:
+				return fmt.Errorf("unsupported shell %q (expected bash, zsh, fish or powershell)", args[0])
+			}
+		},
+	}
+}
+
+// newDocsCommand builds the `docs` command that renders CLI reference pages
+func newDocsCommand() *cobra.Command {
+	var dir string
+
+	var cmdDocs = &cobra.Command{
+		Use:          "docs man|markdown",
+		Short:        "Generate man pages or markdown reference for every command",
+		Args:         cobra.ExactArgs(1),
+		ValidArgs:    []string{"man", "markdown"},
+		SilenceUsage: true,
+		Annotations:  map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			root.DisableAutoGenTag = true
+
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create %s: %v", dir, err)
+			}
+
+			switch args[0] {
+			case "man":
+				header := &doc.GenManHeader{
+					Title:   strings.ToUpper(buildinfo.ServiceName),
+					Section: "1",
+					Source:  buildinfo.ServiceName + " " + buildinfo.Version,
+				}
+				if err := doc.GenManTree(root, header, dir); err != nil {
+					return fmt.Errorf("failed to generate man pages: %v", err)
+				}
+			case "markdown":
+				if err := doc.GenMarkdownTree(root, dir); err != nil {
+					return fmt.Errorf("failed to generate markdown: %v", err)
+				}
+			default:
+				return fmt.Errorf("unsupported format %q (expected man or markdown)", args[0])
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s reference to %s\n", args[0], dir)
+			return nil
+		},
+	}
+	cmdDocs.Flags().StringVarP(&dir, "dir", "d", "docs/cli", "Output directory")
+
+	return cmdDocs
+}
+
+// registerCompletions wires dynamic completion for flags and arguments that
+// take configuration-dependent values
+func registerCompletions(root *cobra.Command) {
+	root.RegisterFlagCompletionFunc("profile", completeProfiles)
+	root.RegisterFlagCompletionFunc("log-level", cobra.FixedCompletions(
+		[]string{"debug", "info", "warn", "error", "fatal"}, cobra.ShellCompDirectiveNoFileComp))
+
+	for _, cmd := range root.Commands() {
+		switch cmd.Name() {
+		case "init":
+			cmd.RegisterFlagCompletionFunc("with", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				return scaffold.FeatureNames(), cobra.ShellCompDirectiveNoFileComp
+			})
+		case "scaffold":
+			for _, sub := range cmd.Commands() {
+				if sub.Flags().Lookup("field") != nil {
+					sub.RegisterFlagCompletionFunc("field", completeFieldTypes)
+				}
+			}
+		}
+	}
+}
+
+// completeProfiles lists profiles declared in the configuration file selected by
+// --config or SS_CONFIG_FILE_PATH
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	config.ResolveSettings()
+
+	profiles, err := config.DeclaredProfiles(config.ConfigPath)
+	if err != nil {
+		cobra.CompDebugln(fmt.Sprintf("failed to read profiles: %v", err), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return profiles, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeConfigKeys lists dotted configuration keys (e.g. server.port)
+func completeConfigKeys(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		// Second argument is the configuration file
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return config.ConfigKeys(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFieldTypes completes the type part of scaffold name:type fields
+func completeFieldTypes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	name, _, ok := strings.Cut(toComplete, ":")
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for fieldType := range scaffold.FieldTypes {
+		completions = append(completions, name+":"+fieldType)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
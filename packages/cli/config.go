@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cloudputation/service-seed/packages/api"
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/scheduler"
+)
+
+// standaloneAnnotation marks commands that run without the agent runtime
+// (logger, metrics, traces) being initialized
+const standaloneAnnotation = "service-seed/standalone"
+
+// IsStandalone reports whether a command runs without the agent runtime
+func IsStandalone(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		// Shell completion requests never start the agent runtime
+		if c.Name() == cobra.ShellCompRequestCmd || c.Annotations[standaloneAnnotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// newConfigCommand builds the `config` command group
+func newConfigCommand() *cobra.Command {
+	var cmdConfig = &cobra.Command{
+		Use:         "config",
+		Short:       "Inspect and validate configuration files",
+		Annotations: map[string]string{standaloneAnnotation: "true"},
+	}
+
+	cmdConfig.AddCommand(
+		newConfigValidateCommand(),
+		newConfigShowCommand(),
+		newConfigGetCommand(),
+		newConfigSchemaCommand(),
+		newConfigDefaultsCommand(),
+	)
+
+	return cmdConfig
+}
+
+func newConfigValidateCommand() *cobra.Command {
+	var allProfiles bool
+
+	var cmdValidate = &cobra.Command{
+		Use:          "validate [file]",
+		Short:        "Validate a configuration file without starting the agent",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPathArg(args)
+
+			if err := loadAndCheck(path); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Configuration file %s is valid%s\n", path, profileSuffix(config.ActiveProfile))
+
+			if !allProfiles {
+				return nil
+			}
+
+			// Validate every declared profile overlaid on the base settings, then
+			// restore the selected profile's configuration for later code
+			selected, selectedConfig := config.ActiveProfile, config.AppConfig
+			defer func() {
+				config.ActiveProfile = selected
+				config.AppConfig = selectedConfig
+			}()
+			for _, name := range config.ProfileNames {
+				config.ActiveProfile = name
+				if err := loadAndCheck(path); err != nil {
+					return fmt.Errorf("profile %q: %v", name, err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Configuration file %s is valid%s\n", path, profileSuffix(name))
+			}
+
+			return nil
+		},
+	}
+	cmdValidate.Flags().BoolVar(&allProfiles, "all-profiles", false, "Also validate every declared profile")
+
+	return cmdValidate
+}
+
+// loadAndCheck loads a configuration file and checks the settings resolved
+// against code: schedule blocks must name registered tasks or job types, auth
+// and rate_limit route groups must exist, and auth secret files be readable
+func loadAndCheck(path string) error {
+	if err := config.LoadConfigurationFile(path); err != nil {
+		return err
+	}
+	if err := scheduler.CheckConfig(); err != nil {
+		return fmt.Errorf("Invalid configuration: %v", err)
+	}
+	if err := api.CheckConfig(); err != nil {
+		return fmt.Errorf("Invalid configuration: %v", err)
+	}
+	return nil
+}
+
+func newConfigShowCommand() *cobra.Command {
+	var format string
+
+	var cmdShow = &cobra.Command{
+		Use:          "show [file]",
+		Short:        "Print the fully resolved configuration with defaults applied and secrets redacted",
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.LoadConfigurationFile(configPathArg(args)); err != nil {
+				return err
+			}
+
+			data, err := config.MarshalConfiguration(config.RedactedConfig(), format)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+	cmdShow.Flags().StringVarP(&format, "output", "o", config.FormatHCL, "Output format: hcl, json or yaml")
+	cmdShow.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(
+		[]string{config.FormatHCL, config.FormatJSON, config.FormatYAML}, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmdShow
+}
+
+func newConfigGetCommand() *cobra.Command {
+	var cmdGet = &cobra.Command{
+		Use:               "get <key> [file]",
+		Short:             "Print a single resolved setting by dotted key (e.g. server.port)",
+		Args:              cobra.RangeArgs(1, 2),
+		ValidArgsFunction: completeConfigKeys,
+		SilenceUsage:      true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.LoadConfigurationFile(configPathArg(args[1:])); err != nil {
+				return err
+			}
+
+			data, err := json.Marshal(config.RedactedConfig())
+			if err != nil {
+				return err
+			}
+			var value interface{}
+			if err := json.Unmarshal(data, &value); err != nil {
+				return err
+			}
+
+			if !slices.Contains(config.ConfigKeys(), args[0]) {
+				return fmt.Errorf("unknown configuration key %q (see `config schema`)", args[0])
+			}
+			for _, part := range strings.Split(args[0], ".") {
+				object, ok := value.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("unknown configuration key %q", args[0])
+				}
+				if value, ok = object[part]; !ok {
+					return fmt.Errorf("configuration key %q is not set", args[0])
+				}
+			}
+
+			out := cmd.OutOrStdout()
+			switch v := value.(type) {
+			case map[string]interface{}, []interface{}:
+				return writeJSON(out, v)
+			default:
+				fmt.Fprintln(out, v)
+				return nil
+			}
+		},
+	}
+
+	return cmdGet
+}
+
+func newConfigSchemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:          "schema",
+		Short:        "Print the JSON Schema of the configuration file",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(config.JSONSchema()); err != nil {
+				return fmt.Errorf("failed to encode schema: %v", err)
+			}
+			return nil
+		},
+	}
+}
+
+func newConfigDefaultsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "defaults",
+		Short: "Print a commented example configuration with default values",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Fprint(cmd.OutOrStdout(), config.ExampleConfiguration)
+		},
+	}
+}
+
+// configPathArg returns the file argument, falling back to the configured path
+func configPathArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return config.GetConfigPath()
+}
+
+func profileSuffix(profile string) string {
+	if profile == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (profile: %s)", profile)
+}
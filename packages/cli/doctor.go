@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cloudputation/service-seed/packages/doctor"
+)
+
+// errChecksFailed signals a failed doctor run after the report has been printed
+var errChecksFailed = errors.New("preflight checks failed")
+
+// newDoctorCommand builds the `doctor` command
+func newDoctorCommand() *cobra.Command {
+	var asJSON, strict bool
+
+	var cmdDoctor = &cobra.Command{
+		Use:   "doctor",
+		Short: "Run preflight checks against the resolved configuration",
+		Long: `Run preflight checks against the resolved configuration: configuration validity,
+log_dir and data_dir writability, server port availability, TLS certificate and key
+files, and OTLP collector reachability.
+
+Exits non-zero when a check fails (or warns, with --strict).`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report := doctor.Run()
+			out := cmd.OutOrStdout()
+
+			if asJSON {
+				if strict && report.Summary.Warn > 0 {
+					report.OK = false
+				}
+				if err := writeJSON(out, report); err != nil {
+					return err
+				}
+			} else {
+				width := 0
+				for _, check := range report.Checks {
+					width = max(width, len(check.Name))
+				}
+				for _, check := range report.Checks {
+					fmt.Fprintf(out, "%-4s  %-*s  %s\n", strings.ToUpper(check.Status), width, check.Name, check.Message)
+				}
+				fmt.Fprintf(out, "\n%d passed, %d warnings, %d failed\n", report.Summary.Pass, report.Summary.Warn, report.Summary.Fail)
+			}
+
+			if report.Summary.Fail > 0 || (strict && report.Summary.Warn > 0) {
+				cmd.SilenceErrors = true
+				return errChecksFailed
+			}
+			return nil
+		},
+	}
+	cmdDoctor.Flags().BoolVar(&asJSON, "json", false, "Print the report as JSON")
+	cmdDoctor.Flags().BoolVar(&strict, "strict", false, "Also fail on warnings")
+
+	return cmdDoctor
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cloudputation/service-seed/packages/audit"
+	"github.com/cloudputation/service-seed/packages/bootstrap"
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/jobs"
+	"github.com/cloudputation/service-seed/packages/lifecycle"
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/scheduler"
+	"github.com/cloudputation/service-seed/packages/stats"
+	"github.com/cloudputation/service-seed/packages/storage"
+	"github.com/cloudputation/service-seed/packages/workers"
+)
+
+// telemetryShutdownTimeout bounds how long each telemetry exporter may take
+// to flush on shutdown
+const telemetryShutdownTimeout = 10 * time.Second
+
+// loggerRunning reports whether log calls reach the logger; stop failures
+// are printed to stderr otherwise
+var loggerRunning bool
+
+// initRuntime loads configuration and starts the runtime components
+// (filesystem, logging, the audit log, metrics, traces, storage, the worker
+// pool, the job queue and the scheduler) together with any registered by the service.
+// Runs from the root PersistentPreRunE once cobra has parsed the global flags.
+func initRuntime(cmd *cobra.Command) error {
+	// Standalone commands only need the config path and profile resolved
+	if IsStandalone(cmd) {
+		config.ResolveSettings()
+		return nil
+	}
+
+	fmt.Printf("INFO: Starting service-seed agent..\n\n")
+
+	// Load main configuration file (flags and env overrides applied)
+	err := config.LoadConfiguration()
+	if err != nil {
+		return fmt.Errorf("Failed to load configuration: %v", err)
+	}
+
+	registerRuntimeComponents(lifecycle.Default)
+	lifecycle.Default.OnStopError = func(name string, err error) {
+		if loggerRunning {
+			log.Error(fmt.Sprintf("Failed to stop %s: %v", name, err))
+		} else {
+			fmt.Fprintf(os.Stderr, "Failed to stop %s: %v\n", name, err)
+		}
+	}
+
+	// Components already started are stopped again if one fails
+	return lifecycle.Default.Start(context.Background())
+}
+
+// registerRuntimeComponents adds the agent's own components to m
+func registerRuntimeComponents(m *lifecycle.Manager) {
+	// Create and check log_dir and data_dir before anything writes to disk,
+	// and hold the data_dir instance lock until shutdown
+	m.Register(lifecycle.Filesystem, lifecycle.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := bootstrap.BootstrapFileSystem(); err != nil {
+				bootstrap.ReleaseLock()
+				return err
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return bootstrap.ReleaseLock()
+		},
+	})
+
+	m.Register(lifecycle.Logger, lifecycle.Hook{
+		OnStart: startLogger,
+		OnStop: func(ctx context.Context) error {
+			loggerRunning = false
+
+			ctx, cancel := context.WithTimeout(ctx, telemetryShutdownTimeout)
+			defer cancel()
+			err := log.ShutdownOTLPLogs(ctx)
+			log.CloseLogger()
+			return err
+		},
+	}, lifecycle.Filesystem)
+
+	// Authorization decisions are appended to auth.audit_log under log_dir
+	m.Register(lifecycle.Audit, lifecycle.Hook{
+		OnStart: func(ctx context.Context) error {
+			path := config.AuditLogPath()
+			if err := audit.Init(path); err != nil {
+				return err
+			}
+			if path != "" {
+				log.Info(fmt.Sprintf("Audit log opened: %s", path))
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return audit.Close()
+		},
+	}, lifecycle.Logger)
+
+	m.Register(lifecycle.Metrics, lifecycle.Hook{
+		OnStart: func(ctx context.Context) error {
+			return stats.InitMetrics()
+		},
+		OnStop: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, telemetryShutdownTimeout)
+			defer cancel()
+			return stats.Shutdown(ctx)
+		},
+	}, lifecycle.Logger)
+
+	m.Register(lifecycle.Traces, lifecycle.Hook{
+		OnStart: func(ctx context.Context) error {
+			telemetry := config.AppConfig.Telemetry
+			if telemetry == nil || telemetry.Traces == nil || !telemetry.Traces.Enabled {
+				return nil
+			}
+			if err := stats.InitTraces(telemetry); err != nil {
+				return err
+			}
+			log.Info(fmt.Sprintf("OTLP traces enabled (endpoint: %s, sampling: %.2f)",
+				telemetry.Traces.Endpoint, telemetry.Traces.SamplingRate))
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, telemetryShutdownTimeout)
+			defer cancel()
+			return stats.ShutdownTraces(ctx)
+		},
+	}, lifecycle.Logger)
+
+	// Storage records metrics and spans, so it opens after both
+	m.Register(lifecycle.Storage, lifecycle.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := storage.InitStorage(); err != nil {
+				return err
+			}
+			if config.AppConfig.Storage.Engine == "memory" {
+				log.Warn("Storage engine is memory; data is lost on shutdown")
+			} else {
+				log.Info(fmt.Sprintf("Storage opened: %s", config.StoragePath()))
+			}
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return storage.CloseStorage()
+		},
+	}, lifecycle.Metrics, lifecycle.Traces)
+
+	// Tasks may use storage, so the pool drains before it closes
+	m.Register(lifecycle.Workers, lifecycle.Hook{
+		OnStart: func(ctx context.Context) error {
+			workers.InitWorkers()
+			log.Info(fmt.Sprintf("Worker pool started (workers: %d, queue: %d)",
+				config.AppConfig.Workers.MaxWorkers, config.AppConfig.Workers.QueueSize))
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return workers.ShutdownWorkers()
+		},
+	}, lifecycle.Storage)
+
+	m.Register(lifecycle.Jobs, lifecycle.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := jobs.InitJobs(); err != nil {
+				return err
+			}
+			log.Info(fmt.Sprintf("Job queue started (concurrency: %d, handlers: %d)",
+				config.AppConfig.Jobs.Concurrency, len(jobs.Types())))
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return jobs.ShutdownJobs()
+		},
+	}, lifecycle.Storage, lifecycle.Workers)
+
+	// Scheduled tasks may enqueue jobs
+	m.Register(lifecycle.Scheduler, lifecycle.Hook{
+		OnStart: func(ctx context.Context) error {
+			return scheduler.InitScheduler()
+		},
+		OnStop: func(ctx context.Context) error {
+			return scheduler.ShutdownScheduler()
+		},
+	}, lifecycle.Jobs)
+}
+
+// startLogger starts OTLP log export (if enabled) and the logger
+func startLogger(ctx context.Context) error {
+	// Resolved secrets (file(), env(), secret()) are masked in every log line
+	logOpts := &log.LoggerOptions{Redactor: config.RedactSecrets}
+
+	telemetry := config.AppConfig.Telemetry
+	if telemetry != nil && telemetry.Logs != nil && telemetry.Logs.Enabled {
+		otlpWriter, err := log.InitOTLPLogs(otlpLogsOptions(telemetry))
+		if err != nil {
+			return fmt.Errorf("Failed to initialize OTLP logs: %v", err)
+		}
+		logOpts.ExtraWriter = otlpWriter
+	}
+
+	err := log.InitLoggerWithOptions(config.ResolvePath(config.AppConfig.LogDir), config.AppConfig.LogLevel, logOpts)
+	if err != nil {
+		log.ShutdownOTLPLogs(ctx)
+		return err
+	}
+	loggerRunning = true
+	bootstrap.LogSummary()
+
+	return nil
+}
+
+// ShutdownRuntime stops the runtime components in reverse start order: the
+// scheduler, the job queue and worker pool, storage, telemetry exporters, the
+// log file and finally the instance lock. Failures are reported through
+// OnStopError. Safe to call when the runtime was never started.
+func ShutdownRuntime() {
+	lifecycle.Default.Stop(context.Background())
+}
+
+// otlpLogsOptions maps telemetry configuration to logger OTLP options
+func otlpLogsOptions(t *config.Telemetry) *log.OTLPLogsOptions {
+	opts := &log.OTLPLogsOptions{
+		Endpoint: t.Logs.Endpoint,
+		Headers:  t.Headers,
+	}
+	if t.TLS != nil {
+		opts.TLS = &log.OTLPLogsTLSOptions{
+			Enabled:  t.TLS.Enabled,
+			Insecure: t.TLS.Insecure,
+			CAFile:   t.TLS.CAFile,
+			CertFile: t.TLS.CertFile,
+			KeyFile:  t.TLS.KeyFile,
+		}
+	}
+	return opts
+}
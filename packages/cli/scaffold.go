@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cloudputation/service-seed/packages/scaffold"
+)
+
+// newScaffoldCommand builds the `scaffold` command group of code generators
+func newScaffoldCommand() *cobra.Command {
+	var cmdScaffold = &cobra.Command{
+		Use:         "scaffold",
+		Short:       "Generate code in this service",
+		Annotations: map[string]string{standaloneAnnotation: "true"},
+	}
+
+	cmdScaffold.AddCommand(newScaffoldEndpointCommand())
+
+	return cmdScaffold
+}
+
+func newScaffoldEndpointCommand() *cobra.Command {
+	var opts scaffold.EndpointOptions
+
+	var cmdEndpoint = &cobra.Command{
+		Use:   "endpoint <name>",
+		Short: "Generate a CRUD API resource with storage, metrics and tests",
+		Long: `Generate a CRUD API resource served at /v1/<plural>.
+
+Writes the model and handlers (packages/api/v1/<name>.go), a storage interface
+backed by the key-value store in data_dir (<name>_storage.go), table-driven handler tests
+(<name>_test.go) and operation counters (packages/stats/<name>_metrics.go), then
+registers the routes in packages/api/server.go.
+
+Field types: string, int, int64, float, float64, bool, time`,
+		Example:      "  service-seed scaffold endpoint todo --field title:string --field completed:bool --field due:time",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Name = args[0]
+
+			written, err := scaffold.RenderEndpoint(opts)
+			for _, path := range written {
+				fmt.Fprintf(cmd.OutOrStdout(), "wrote %s\n", path)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "\nRun go test ./packages/api/v1/ to check the generated resource\n")
+			return nil
+		},
+	}
+	cmdEndpoint.Flags().StringArrayVarP(&opts.Fields, "field", "f", nil, "Field as name:type (repeatable)")
+	cmdEndpoint.Flags().StringVar(&opts.Plural, "plural", "", "Plural name used in the route (default: <name>s)")
+	cmdEndpoint.Flags().StringVar(&opts.Dir, "dir", ".", "Service root containing go.mod")
+	cmdEndpoint.Flags().BoolVar(&opts.Force, "force", false, "Overwrite previously generated files")
+
+	return cmdEndpoint
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cloudputation/service-seed/packages/buildinfo"
+)
+
+// newVersionCommand builds the `version` command
+func newVersionCommand() *cobra.Command {
+	var asJSON, withDeps bool
+
+	var cmdVersion = &cobra.Command{
+		Use:          "version",
+		Short:        "Print version and build information",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{standaloneAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := buildinfo.Get()
+			out := cmd.OutOrStdout()
+
+			if asJSON {
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(info)
+			}
+
+			fmt.Fprintf(out, "%s %s\n", info.Service, info.Version)
+			fmt.Fprintf(out, "  environment: %s\n", info.Environment)
+			if info.Revision != "" {
+				dirty := ""
+				if info.Dirty {
+					dirty = " (dirty)"
+				}
+				fmt.Fprintf(out, "  revision:    %s%s\n", info.Revision, dirty)
+			}
+			if info.RevisionTime != "" {
+				fmt.Fprintf(out, "  built from:  %s\n", info.RevisionTime)
+			}
+			fmt.Fprintf(out, "  go version:  %s\n", info.GoVersion)
+			fmt.Fprintf(out, "  platform:    %s\n", info.Platform)
+
+			if withDeps {
+				fmt.Fprintf(out, "  modules:\n")
+				for _, dep := range info.Deps {
+					if dep.Replace != "" {
+						fmt.Fprintf(out, "    %s %s => %s\n", dep.Path, dep.Version, dep.Replace)
+						continue
+					}
+					fmt.Fprintf(out, "    %s %s\n", dep.Path, dep.Version)
+				}
+			}
+
+			return nil
+		},
+	}
+	cmdVersion.Flags().BoolVar(&asJSON, "json", false, "Print build information as JSON (includes module dependencies)")
+	cmdVersion.Flags().BoolVar(&withDeps, "deps", false, "Include module dependencies")
+
+	return cmdVersion
+}
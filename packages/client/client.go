@@ -0,0 +1,257 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+
+	v1 "github.com/cloudputation/service-seed/packages/api/v1"
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// Client calls the HTTP API of a running agent
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// New creates a client from client configuration (address, token, TLS, timeout)
+func New(cfg *config.Client) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.TLS != nil {
+		tlsCfg, err := loadTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS config: %v", err)
+		}
+		transport.TLSClientConfig = tlsCfg
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	return &Client{
+		baseURL: strings.TrimRight(cfg.Address, "/"),
+		token:   cfg.Token,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+	}, nil
+}
+
+// HealthResult describes the outcome of a health check
+type HealthResult struct {
+	Healthy    bool    `json:"healthy"`
+	StatusCode int     `json:"status_code,omitempty"`
+	Body       string  `json:"body,omitempty"`
+	LatencyMS  float64 `json:"latency_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Health calls /v1/health. Transport errors are reported in the result, not returned.
+func (c *Client) Health(ctx context.Context) HealthResult {
+	start := time.Now()
+	resp, body, err := c.get(ctx, "/v1/health")
+	result := HealthResult{LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.StatusCode = resp.StatusCode
+	result.Body = strings.TrimSpace(string(body))
+	result.Healthy = resp.StatusCode == http.StatusOK
+	return result
+}
+
+// Status calls /v1/system/status
+func (c *Client) Status(ctx context.Context) (*v1.SystemStatusResponse, error) {
+	resp, body, err := c.get(ctx, "/v1/system/status")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var status v1.SystemStatusResponse
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("failed to decode status response: %v", err)
+	}
+	return &status, nil
+}
+
+// MetricsText calls /v1/system/metrics and returns the Prometheus exposition text
+func (c *Client) MetricsText(ctx context.Context) (string, error) {
+	resp, body, err := c.get(ctx, "/v1/system/metrics")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return string(body), nil
+}
+
+// Snapshot streams a point-in-time data_dir snapshot from /v1/admin/snapshot
+// into w and returns the manifest checksum. The client timeout does not apply;
+// bound the transfer with ctx instead.
+func (c *Client) Snapshot(ctx context.Context, w io.Writer) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/admin/snapshot", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build request: %v", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	streaming := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := streaming.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach agent at %s: %v", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("the agent does not serve /v1/admin/snapshot without an auth block; stop it and run backup offline, or configure auth")
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return "", fmt.Errorf("snapshot transfer failed: %v", err)
+	}
+
+	// The agent sets the trailer only after the archive is complete
+	checksum := resp.Trailer.Get(v1.SnapshotChecksumTrailer)
+	if checksum == "" {
+		return "", fmt.Errorf("snapshot transfer was cut short")
+	}
+	return checksum, nil
+}
+
+// MetricFamily is a JSON-friendly view of a Prometheus metric family
+type MetricFamily struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Help    string   `json:"help,omitempty"`
+	Samples []Sample `json:"samples"`
+}
+
+// Sample is a single labelled value (histograms report sum and count)
+type Sample struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Value  float64           `json:"value"`
+	Count  uint64            `json:"count,omitempty"`
+}
+
+// ParseMetrics converts Prometheus exposition text into metric families
+func ParseMetrics(text string) ([]MetricFamily, error) {
+	parser := expfmt.NewTextParser(model.UTF8Validation)
+	families, err := parser.TextToMetricFamilies(strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %v", err)
+	}
+
+	var result []MetricFamily
+	for name, mf := range families {
+		family := MetricFamily{
+			Name: name,
+			Type: strings.ToLower(mf.GetType().String()),
+			Help: mf.GetHelp(),
+		}
+		for _, m := range mf.GetMetric() {
+			sample := Sample{Labels: map[string]string{}}
+			for _, label := range m.GetLabel() {
+				sample.Labels[label.GetName()] = label.GetValue()
+			}
+			switch {
+			case m.Counter != nil:
+				sample.Value = m.GetCounter().GetValue()
+			case m.Gauge != nil:
+				sample.Value = m.GetGauge().GetValue()
+			case m.Histogram != nil:
+				sample.Value = m.GetHistogram().GetSampleSum()
+				sample.Count = m.GetHistogram().GetSampleCount()
+			case m.Summary != nil:
+				sample.Value = m.GetSummary().GetSampleSum()
+				sample.Count = m.GetSummary().GetSampleCount()
+			case m.Untyped != nil:
+				sample.Value = m.GetUntyped().GetValue()
+			}
+			family.Samples = append(family.Samples, sample)
+		}
+		result = append(result, family)
+	}
+
+	return result, nil
+}
+
+// get performs an authenticated GET request and reads the full body
+func (c *Client) get(ctx context.Context, path string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build request: %v", err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to reach agent at %s: %v", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	return resp, body, nil
+}
+
+// loadTLSConfig creates a TLS configuration from client TLS settings
+func loadTLSConfig(cfg *config.ClientTLSConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		InsecureSkipVerify: cfg.Insecure,
+	}
+
+	// Load client certificate if provided (mutual TLS)
+	if cfg.CertFile != "" && cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client cert/key: %v", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	// Load CA certificate if provided
+	if cfg.CAFile != "" {
+		caCert, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse CA certificate")
+		}
+		tlsCfg.RootCAs = caCertPool
+	}
+
+	return tlsCfg, nil
+}
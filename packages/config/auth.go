@@ -0,0 +1,352 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Authentication methods accepted in auth.route_group methods
+const (
+	AuthMethodToken = "token"
+	AuthMethodHMAC  = "hmac"
+	AuthMethodJWT   = "jwt"
+)
+
+// Policy roles matched by the request rather than granted to credentials
+const (
+	// AuthRoleAny matches every request, anonymous ones included
+	AuthRoleAny = "*"
+
+	// AuthRoleAuthenticated matches every request with verified credentials
+	AuthRoleAuthenticated = "authenticated"
+)
+
+// Auth configures API authentication. Without an auth block every endpoint is
+// open; with one, every route group requires credentials unless its
+// route_group block sets anonymous.
+type Auth struct {
+	// Tokens are static bearer tokens, each read from a secret file
+	Tokens []*AuthToken `hcl:"token,block" json:"tokens,omitempty"`
+
+	// HMACKeys are shared keys for HMAC-SHA256 signed requests
+	HMACKeys []*AuthHMACKey `hcl:"hmac,block" json:"hmac,omitempty"`
+
+	// HMACMaxSkewSeconds is how far a signed request's timestamp may be from
+	// the server clock (default: 300)
+	HMACMaxSkewSeconds int `hcl:"hmac_max_skew_seconds,optional" json:"hmac_max_skew_seconds,omitempty"`
+
+	// JWT verifies bearer tokens issued by an identity provider
+	JWT *AuthJWT `hcl:"jwt,block" json:"jwt,omitempty"`
+
+	// RouteGroups set the accepted methods of each route group
+	RouteGroups []*AuthRouteGroup `hcl:"route_group,block" json:"route_groups,omitempty"`
+
+	// Policies grant roles access to routes; requests no policy allows are
+	// denied. At least one is required.
+	Policies []*AuthPolicy `hcl:"policy,block" json:"policies,omitempty"`
+
+	// AuditLog is the file authorization decisions are appended to, relative
+	// to log_dir unless absolute (default: audit.log)
+	AuditLog string `hcl:"audit_log,optional" json:"audit_log,omitempty"`
+}
+
+// AuthToken is a static bearer token; the label is the principal name
+type AuthToken struct {
+	Name string `hcl:"name,label" json:"name"`
+
+	// File holds the token; surrounding whitespace is ignored
+	File string `hcl:"file" json:"file" redact:"true"`
+
+	// Roles are granted to requests using this token
+	Roles []string `hcl:"roles,optional" json:"roles,omitempty"`
+}
+
+// AuthHMACKey is a shared signing key; the label is the key ID sent by
+// clients and the principal name
+type AuthHMACKey struct {
+	ID string `hcl:"id,label" json:"id"`
+
+	// SecretFile holds the key; surrounding whitespace is ignored
+	SecretFile string `hcl:"secret_file" json:"secret_file" redact:"true"`
+
+	// Roles are granted to requests signed with this key
+	Roles []string `hcl:"roles,optional" json:"roles,omitempty"`
+}
+
+// AuthJWT verifies JWT bearer tokens against a JSON Web Key Set
+type AuthJWT struct {
+	// JWKSFile is a local JWKS file; set it or JWKSURL
+	JWKSFile string `hcl:"jwks_file,optional" json:"jwks_file,omitempty"`
+
+	// JWKSURL is fetched at startup and on every refresh
+	JWKSURL string `hcl:"jwks_url,optional" json:"jwks_url,omitempty"`
+
+	// JWKSRefreshSeconds is how often the key set is reloaded (default: 300)
+	JWKSRefreshSeconds int `hcl:"jwks_refresh_seconds,optional" json:"jwks_refresh_seconds,omitempty"`
+
+	// Issuer must equal the iss claim
+	Issuer string `hcl:"issuer" json:"issuer"`
+
+	// Audience lists accepted values; the aud claim must contain one of them
+	Audience []string `hcl:"audience" json:"audience"`
+
+	// LeewaySeconds tolerates clock drift in exp and nbf (default: 60)
+	LeewaySeconds int `hcl:"leeway_seconds,optional" json:"leeway_seconds,omitempty"`
+
+	// PrincipalClaim names the claim used as the principal name (default: sub)
+	PrincipalClaim string `hcl:"principal_claim,optional" json:"principal_claim,omitempty"`
+
+	// RolesClaim names the claim holding the principal's roles, a list or a
+	// space-separated string (default: roles)
+	RolesClaim string `hcl:"roles_claim,optional" json:"roles_claim,omitempty"`
+}
+
+// AuthRouteGroup sets how requests to a route group authenticate. The label
+// names a route group of the API (system, api, admin).
+type AuthRouteGroup struct {
+	Name string `hcl:"name,label" json:"name"`
+
+	// Methods lists the accepted methods: token, hmac, jwt (default: every
+	// configured method)
+	Methods []string `hcl:"methods,optional" json:"methods,omitempty"`
+
+	// Anonymous lets requests without credentials through; credentials that
+	// are sent are still verified (default: false)
+	Anonymous bool `hcl:"anonymous,optional" json:"anonymous,omitempty"`
+}
+
+// AuthPolicy allows principals holding one of Roles to call Routes with
+// Methods. The label names the policy in audit records and metrics.
+type AuthPolicy struct {
+	Name string `hcl:"name,label" json:"name"`
+
+	// Roles this policy applies to; "authenticated" matches every request
+	// with verified credentials, and "*" every request, anonymous ones included
+	Roles []string `hcl:"roles" json:"roles"`
+
+	// Routes are request paths: exact ("/v1/jobs") or a prefix ending in
+	// "/*" ("/v1/admin/*")
+	Routes []string `hcl:"routes" json:"routes"`
+
+	// Methods are HTTP methods (default: every method)
+	Methods []string `hcl:"methods,optional" json:"methods,omitempty"`
+}
+
+// applyAuthDefaults fills in skew, leeway and refresh settings when auth is configured
+func applyAuthDefaults() {
+	a := AppConfig.Auth
+	if a == nil {
+		return
+	}
+
+	if a.HMACMaxSkewSeconds == 0 {
+		a.HMACMaxSkewSeconds = 300
+	}
+
+	if j := a.JWT; j != nil {
+		if j.JWKSRefreshSeconds == 0 {
+			j.JWKSRefreshSeconds = 300
+		}
+		if j.LeewaySeconds == 0 {
+			j.LeewaySeconds = 60
+		}
+		if j.PrincipalClaim == "" {
+			j.PrincipalClaim = "sub"
+		}
+		if j.RolesClaim == "" {
+			j.RolesClaim = "roles"
+		}
+	}
+
+	if a.AuditLog == "" {
+		a.AuditLog = "audit.log"
+	}
+}
+
+// AuditLogPath returns the resolved audit log file: auth.audit_log under the
+// resolved log_dir unless absolute; empty when auditing is off
+func AuditLogPath() string {
+	a := AppConfig.Auth
+	if a == nil || a.AuditLog == "" {
+		return ""
+	}
+	if filepath.IsAbs(a.AuditLog) {
+		return filepath.Clean(a.AuditLog)
+	}
+	return filepath.Join(ResolvePath(AppConfig.LogDir), a.AuditLog)
+}
+
+// Methods returns the authentication methods with credentials configured
+func (a *Auth) Methods() []string {
+	var methods []string
+	if len(a.Tokens) > 0 {
+		methods = append(methods, AuthMethodToken)
+	}
+	if len(a.HMACKeys) > 0 {
+		methods = append(methods, AuthMethodHMAC)
+	}
+	if a.JWT != nil {
+		methods = append(methods, AuthMethodJWT)
+	}
+	return methods
+}
+
+// validateAuth checks auth settings after defaults are applied. Route group
+// names are checked by the api package, which owns the routes; secret files
+// and the JWKS are read when the server starts.
+func validateAuth() error {
+	a := AppConfig.Auth
+	if a == nil {
+		return nil
+	}
+
+	configured := a.Methods()
+	if len(configured) == 0 {
+		return fmt.Errorf("auth: configure at least one token, hmac or jwt block")
+	}
+
+	seen := map[string]bool{}
+	for _, t := range a.Tokens {
+		if seen[t.Name] {
+			return fmt.Errorf("auth: token %q is declared twice", t.Name)
+		}
+		seen[t.Name] = true
+		if t.File == "" {
+			return fmt.Errorf("auth: token %q: file must not be empty", t.Name)
+		}
+	}
+
+	seen = map[string]bool{}
+	for _, k := range a.HMACKeys {
+		if seen[k.ID] {
+			return fmt.Errorf("auth: hmac key %q is declared twice", k.ID)
+		}
+		seen[k.ID] = true
+		if k.SecretFile == "" {
+			return fmt.Errorf("auth: hmac key %q: secret_file must not be empty", k.ID)
+		}
+	}
+	if a.HMACMaxSkewSeconds < 1 {
+		return fmt.Errorf("auth.hmac_max_skew_seconds must be positive, got %d", a.HMACMaxSkewSeconds)
+	}
+
+	if j := a.JWT; j != nil {
+		if (j.JWKSFile == "") == (j.JWKSURL == "") {
+			return fmt.Errorf("auth.jwt: set either jwks_file or jwks_url")
+		}
+		if j.JWKSURL != "" {
+			u, err := url.Parse(j.JWKSURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("auth.jwt.jwks_url must be an http(s) URL, got %q", j.JWKSURL)
+			}
+		}
+		if j.Issuer == "" {
+			return fmt.Errorf("auth.jwt.issuer must not be empty")
+		}
+		if len(j.Audience) == 0 {
+			return fmt.Errorf("auth.jwt.audience must list at least one audience")
+		}
+		if j.JWKSRefreshSeconds < 1 {
+			return fmt.Errorf("auth.jwt.jwks_refresh_seconds must be positive, got %d", j.JWKSRefreshSeconds)
+		}
+		if j.LeewaySeconds < 0 {
+			return fmt.Errorf("auth.jwt.leeway_seconds must not be negative, got %d", j.LeewaySeconds)
+		}
+	}
+
+	seen = map[string]bool{}
+	for _, g := range a.RouteGroups {
+		if seen[g.Name] {
+			return fmt.Errorf("auth: route_group %q is declared twice", g.Name)
+		}
+		seen[g.Name] = true
+
+		for _, method := range g.Methods {
+			if !contains(configured, method) {
+				switch method {
+				case AuthMethodToken, AuthMethodHMAC, AuthMethodJWT:
+					return fmt.Errorf("auth: route_group %q accepts %s but no %s credentials are configured", g.Name, method, method)
+				default:
+					return fmt.Errorf("auth: route_group %q: unknown method %q (want token, hmac or jwt)", g.Name, method)
+				}
+			}
+		}
+	}
+
+	return validatePolicies(a)
+}
+
+// validatePolicies checks policy syntax and that their roles can be granted.
+// Whether routes exist is checked by the api package, which owns the routes.
+// Requests are denied unless a policy allows them, so one is required.
+func validatePolicies(a *Auth) error {
+	if len(a.Policies) == 0 {
+		return fmt.Errorf(`auth: declare at least one policy block; requests no policy allows are denied (policy "authenticated" { roles = ["authenticated"] routes = ["/v1/*"] } allows every authenticated request)`)
+	}
+
+	granted := map[string]bool{}
+	for _, t := range a.Tokens {
+		for _, role := range t.Roles {
+			granted[role] = true
+		}
+	}
+	for _, k := range a.HMACKeys {
+		for _, role := range k.Roles {
+			granted[role] = true
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, p := range a.Policies {
+		if seen[p.Name] {
+			return fmt.Errorf("auth: policy %q is declared twice", p.Name)
+		}
+		seen[p.Name] = true
+
+		if len(p.Roles) == 0 {
+			return fmt.Errorf("auth: policy %q: roles must not be empty", p.Name)
+		}
+		for _, role := range p.Roles {
+			// JWT roles are only known at request time
+			if role != AuthRoleAny && role != AuthRoleAuthenticated && !granted[role] && a.JWT == nil {
+				return fmt.Errorf("auth: policy %q: no token or hmac key has role %q", p.Name, role)
+			}
+		}
+
+		if len(p.Routes) == 0 {
+			return fmt.Errorf("auth: policy %q: routes must not be empty", p.Name)
+		}
+		for _, route := range p.Routes {
+			prefix := strings.TrimSuffix(route, "*")
+			if !strings.HasPrefix(route, "/") || strings.Contains(prefix, "*") || (prefix != route && !strings.HasSuffix(prefix, "/")) {
+				return fmt.Errorf("auth: policy %q: route %q must be a path, optionally ending in /*", p.Name, route)
+			}
+		}
+
+		for _, method := range p.Methods {
+			if !contains(httpMethods, method) {
+				return fmt.Errorf("auth: policy %q: unknown method %q (want an uppercase HTTP method or *)", p.Name, method)
+			}
+		}
+	}
+
+	return nil
+}
+
+// httpMethods are the methods accepted in policy blocks
+var httpMethods = []string{
+	"*", http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
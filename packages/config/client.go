@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Client holds settings used by CLI commands that talk to a running agent
+// (status, health, metrics)
+type Client struct {
+	// Address is the agent base URL (default: derived from the server block)
+	Address string `hcl:"address,optional" json:"address,omitempty"`
+
+	// Token is sent as a bearer token; reference secrets with file() or env()
+	Token string `hcl:"token,optional" json:"token,omitempty" redact:"true"`
+
+	// TimeoutSeconds bounds each request (default: 5)
+	TimeoutSeconds int `hcl:"timeout_seconds,optional" json:"timeout_seconds,omitempty"`
+
+	// TLS configures HTTPS connections to the agent
+	TLS *ClientTLSConfig `hcl:"tls,block" json:"tls,omitempty"`
+}
+
+// ClientTLSConfig holds TLS settings for agent API connections
+type ClientTLSConfig struct {
+	// Insecure skips certificate verification (not recommended for production)
+	Insecure bool `hcl:"insecure,optional" json:"insecure,omitempty"`
+
+	// CAFile is the path to CA certificate for server verification
+	CAFile string `hcl:"ca_file,optional" json:"ca_file,omitempty"`
+
+	// CertFile is the path to client certificate (for mutual TLS)
+	CertFile string `hcl:"cert_file,optional" json:"cert_file,omitempty"`
+
+	// KeyFile is the path to client key (for mutual TLS)
+	KeyFile string `hcl:"key_file,optional" json:"key_file,omitempty" redact:"true"`
+}
+
+// applyClientDefaults derives the client address from the server block when unset
+func applyClientDefaults() {
+	if AppConfig.Client == nil {
+		AppConfig.Client = &Client{}
+	}
+
+	c := AppConfig.Client
+
+	if c.Address == "" {
+		c.Address = DefaultClientAddress(AppConfig.Server)
+	}
+
+	// Default request timeout is 5 seconds
+	if c.TimeoutSeconds == 0 {
+		c.TimeoutSeconds = 5
+	}
+}
+
+// DefaultClientAddress builds the agent URL from the server block, replacing
+// wildcard bind addresses with loopback
+func DefaultClientAddress(server Server) string {
+	host := server.ServerAddress
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, server.ServerPort)
+}
+
+// validateClient checks client settings after defaults are applied
+func validateClient() error {
+	c := AppConfig.Client
+	if c == nil {
+		return nil
+	}
+
+	u, err := url.Parse(c.Address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("client.address must be an http(s) URL, got %q", c.Address)
+	}
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("client.timeout_seconds must be positive, got %d", c.TimeoutSeconds)
+	}
+
+	return nil
+}
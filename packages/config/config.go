@@ -3,6 +3,7 @@ package config
 import (
     "os"
     "fmt"
+    "strconv"
 
     "github.com/spf13/viper"
     "github.com/hashicorp/hcl/v2/gohcl"
@@ -11,58 +12,131 @@ import (
 
 
 type Configuration struct {
-    LogDir      string      `hcl:"log_dir"`
-    DataDir     string      `hcl:"data_dir"`
-    Server      Server      `hcl:"server,block"`
-    Telemetry   *Telemetry  `hcl:"telemetry,block"`
+    LogDir      string      `hcl:"log_dir" json:"log_dir"`
+    LogLevel    string      `hcl:"log_level,optional" json:"log_level,omitempty"`
+    DataDir     string      `hcl:"data_dir" json:"data_dir"`
+    Server      Server      `hcl:"server,block" json:"server"`
+    Telemetry   *Telemetry  `hcl:"telemetry,block" json:"telemetry,omitempty"`
+    Client      *Client     `hcl:"client,block" json:"client,omitempty"`
+    Filesystem  *Filesystem `hcl:"filesystem,block" json:"filesystem,omitempty"`
+    Storage     *Storage    `hcl:"storage,block" json:"storage,omitempty"`
+    Snapshot    *Snapshot   `hcl:"snapshot,block" json:"snapshot,omitempty"`
+    Workers     *Workers    `hcl:"workers,block" json:"workers,omitempty"`
+    Jobs        *Jobs       `hcl:"jobs,block" json:"jobs,omitempty"`
+    Schedule    []*ScheduleTask `hcl:"schedule,block" json:"schedule,omitempty"`
+    Auth        *Auth       `hcl:"auth,block" json:"auth,omitempty"`
+    RateLimit   *RateLimit  `hcl:"rate_limit,block" json:"rate_limit,omitempty"`
 }
 
 type Server struct {
-    ServerPort    string `hcl:"port"`
-    ServerAddress string `hcl:"address"`
+    ServerPort      string           `hcl:"port" json:"port"`
+    ServerAddress   string           `hcl:"address" json:"address"`
+    CORS            *CORS            `hcl:"cors,block" json:"cors,omitempty"`
+    SecurityHeaders *SecurityHeaders `hcl:"security_headers,block" json:"security_headers,omitempty"`
 }
 
 
 var AppConfig Configuration
 var ConfigPath string
 var RootDir string
-const MaxWorkers = 10
 
 
 func LoadConfiguration() error {
+  ResolveSettings()
+
+  return LoadConfigurationFile(ConfigPath)
+}
+
+// ResolveSettings reads the configuration path and profile from flags and environment
+func ResolveSettings() {
   viper.SetDefault("ConfigPath", "/etc/service-seed/config.hcl")
-  viper.BindEnv("ConfigPath", "SS_CONFIG_FILE_PATH")
+  viper.BindEnv("ConfigPath", EnvPrefix+"CONFIG_FILE_PATH")
 
   ConfigPath = viper.GetString("ConfigPath")
 
+  viper.BindEnv("Profile", EnvPrefix+"PROFILE")
+  ActiveProfile = viper.GetString("Profile")
+
+  viper.BindEnv("LogLevel", EnvPrefix+"LOG_LEVEL")
+  viper.BindEnv("LogDir", EnvPrefix+"LOG_DIR")
+  viper.BindEnv("DataDir", EnvPrefix+"DATA_DIR")
+  viper.BindEnv("ServerPort", EnvPrefix+"SERVER_PORT")
+}
+
+// applyOverrides replaces file values with flags or environment variables that were set
+func applyOverrides() {
+  if viper.IsSet("LogLevel") {
+      AppConfig.LogLevel = viper.GetString("LogLevel")
+  }
+  if viper.IsSet("LogDir") {
+      AppConfig.LogDir = viper.GetString("LogDir")
+  }
+  if viper.IsSet("DataDir") {
+      AppConfig.DataDir = viper.GetString("DataDir")
+  }
+  if viper.IsSet("ServerPort") {
+      AppConfig.Server.ServerPort = viper.GetString("ServerPort")
+  }
+}
+
+// LoadConfigurationFile parses, evaluates, defaults and validates a configuration
+// file into AppConfig using the currently selected profile
+func LoadConfigurationFile(path string) error {
+  ConfigPath = path
+  AppConfig = Configuration{}
+
   var err error
   RootDir, err = os.Getwd()
   if err != nil {
       return fmt.Errorf("Failed to get service root directory: %v", err)
   }
 
-  // Read the HCL file
+  // Read the configuration file
   data, err := os.ReadFile(ConfigPath)
   if err != nil {
       return fmt.Errorf("Failed to read configuration file: %v", err)
   }
 
-  // Parse the HCL file
+  // Parse the file (format detected from extension: .hcl, .json, .yaml)
   parser := hclparse.NewParser()
-  file, diags := parser.ParseHCL(data, ConfigPath)
+  file, diags := parseConfigFile(parser, data, ConfigPath)
   if diags.HasErrors() {
       return fmt.Errorf("Failed to parse configuration: %v", diags)
   }
 
-  // Populate the Config struct
-  diags = gohcl.DecodeBody(file.Body, nil, &AppConfig)
+  // Evaluate variable and locals blocks into the expression context
+  evalCtx, body, diags := buildEvalContext(file.Body)
+  if diags.HasErrors() {
+      return fmt.Errorf("Failed to evaluate configuration: %v", diags)
+  }
+
+  // Overlay the selected profile block (if any) on the base settings
+  body, diags = applyProfile(body, ActiveProfile)
+  if diags.HasErrors() {
+      return fmt.Errorf("Failed to apply profile: %v", diags)
+  }
+
+  // Populate the Config struct (functions such as file() and env() resolve secrets)
+  diags = gohcl.DecodeBody(body, evalCtx, &AppConfig)
   if diags.HasErrors() {
       return fmt.Errorf("Failed to apply configuration: %v", diags)
   }
 
+  // env() and file() values in sensitive fields are redacted like secret() values
+  recordSensitiveReferences(&AppConfig)
+
+  // Apply command-line flag and environment overrides
+  applyOverrides()
+
   // Apply defaults for any missing optional values
   applyDefaults()
 
+  // Validate the resolved configuration
+  err = validateConfiguration()
+  if err != nil {
+      return fmt.Errorf("Invalid configuration: %v", err)
+  }
+
   return nil
 }
 
@@ -74,5 +148,91 @@ func GetConfigPath() string {
 //
 // applyDefaults sets default values for optional configuration fields
 func applyDefaults() {
+  if AppConfig.LogLevel == "" {
+      AppConfig.LogLevel = "info"
+  }
+
+  applyServerDefaults()
   applyTelemetryDefaults()
+  applyClientDefaults()
+  applyFilesystemDefaults()
+  applyStorageDefaults()
+  applySnapshotDefaults()
+  applyWorkersDefaults()
+  applyJobsDefaults()
+  applyScheduleDefaults()
+  applyAuthDefaults()
+  applyRateLimitDefaults()
+}
+
+// CONFIGURATION VALIDATION
+//
+// validateConfiguration checks values the decoding schema cannot enforce
+func validateConfiguration() error {
+  port, err := strconv.Atoi(AppConfig.Server.ServerPort)
+  if err != nil || port < 1 || port > 65535 {
+      return fmt.Errorf("server.port must be a number between 1 and 65535, got %q", AppConfig.Server.ServerPort)
+  }
+
+  if AppConfig.Server.ServerAddress == "" {
+      return fmt.Errorf("server.address must not be empty")
+  }
+
+  switch AppConfig.LogLevel {
+  case "debug", "info", "warn", "error", "fatal":
+  default:
+      return fmt.Errorf("log_level must be one of debug, info, warn, error or fatal, got %q", AppConfig.LogLevel)
+  }
+
+  err = validateServerHeaders()
+  if err != nil {
+      return err
+  }
+
+  err = validateTelemetry()
+  if err != nil {
+      return err
+  }
+
+  err = validateFilesystem()
+  if err != nil {
+      return err
+  }
+
+  err = validateStorage()
+  if err != nil {
+      return err
+  }
+
+  err = validateSnapshot()
+  if err != nil {
+      return err
+  }
+
+  err = validateWorkers()
+  if err != nil {
+      return err
+  }
+
+  err = validateJobs()
+  if err != nil {
+      return err
+  }
+
+  err = validateSchedule()
+  if err != nil {
+      return err
+  }
+
+  err = validateAuth()
+  if err != nil {
+      return err
+  }
+
+  err = validateRateLimit()
+  if err != nil {
+      return err
+  }
+
+  return validateClient()
 }
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// EnvPrefix starts the name of every environment variable read by the service
+const EnvPrefix = "SS_"
+
+// VariableEnvPrefix is prepended to a variable name to override it from the environment
+// (e.g., SS_VAR_env=prod overrides variable "env")
+const VariableEnvPrefix = EnvPrefix + "VAR_"
+
+// VariableOverrides take precedence over environment and default values.
+// Set before LoadConfiguration() (e.g., from CLI flags).
+var VariableOverrides = map[string]string{}
+
+// evalSchema lists the blocks consumed by the evaluation context rather than the Configuration struct
+var evalSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "locals"},
+	},
+}
+
+// buildEvalContext extracts variable and locals blocks from the body and returns
+// the evaluation context along with the remaining body to decode into Configuration.
+//
+//	variable "env" {
+//	  default = "local"
+//	}
+//
+//	locals {
+//	  service = "service-seed-${var.env}"
+//	}
+//
+//	log_dir = "/var/log/${local.service}"
+func buildEvalContext(body hcl.Body) (*hcl.EvalContext, hcl.Body, hcl.Diagnostics) {
+	ctx := &hcl.EvalContext{
+		Functions: standardFunctions(),
+		Variables: map[string]cty.Value{},
+	}
+
+	content, remain, diags := body.PartialContent(evalSchema)
+	if diags.HasErrors() {
+		return nil, nil, diags
+	}
+
+	var variableBlocks, localsBlocks []*hcl.Block
+	for _, block := range content.Blocks {
+		switch block.Type {
+		case "variable":
+			variableBlocks = append(variableBlocks, block)
+		case "locals":
+			localsBlocks = append(localsBlocks, block)
+		}
+	}
+
+	vars, varDiags := evaluateVariables(variableBlocks, ctx)
+	diags = append(diags, varDiags...)
+	if diags.HasErrors() {
+		return nil, nil, diags
+	}
+	ctx.Variables["var"] = cty.ObjectVal(vars)
+
+	locals, localDiags := evaluateLocals(localsBlocks, ctx)
+	diags = append(diags, localDiags...)
+	if diags.HasErrors() {
+		return nil, nil, diags
+	}
+	ctx.Variables["local"] = cty.ObjectVal(locals)
+
+	return ctx, remain, diags
+}
+
+// evaluateVariables resolves each variable from overrides, environment, then default
+func evaluateVariables(blocks []*hcl.Block, ctx *hcl.EvalContext) (map[string]cty.Value, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	vars := map[string]cty.Value{}
+
+	// Variable defaults may only use functions, not other variables or locals
+	defaultCtx := &hcl.EvalContext{Functions: ctx.Functions}
+
+	for _, block := range blocks {
+		name := block.Labels[0]
+		if _, exists := vars[name]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate variable",
+				Detail:   fmt.Sprintf("Variable %q is declared more than once.", name),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+
+		attrs, attrDiags := block.Body.JustAttributes()
+		diags = append(diags, attrDiags...)
+
+		value := cty.NullVal(cty.DynamicPseudoType)
+		if attr, ok := attrs["default"]; ok {
+			v, valDiags := attr.Expr.Value(defaultCtx)
+			diags = append(diags, valDiags...)
+			value = v
+		}
+
+		if raw, ok := lookupVariableOverride(name); ok {
+			v, err := convertOverride(raw, value.Type())
+			if err != nil {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Invalid variable override",
+					Detail:   fmt.Sprintf("Variable %q: %v", name, err),
+					Subject:  block.DefRange.Ptr(),
+				})
+				continue
+			}
+			value = v
+		}
+
+		if value.IsNull() {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing variable value",
+				Detail:   fmt.Sprintf("Variable %q has no default; set %s%s.", name, VariableEnvPrefix, name),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+
+		vars[name] = value
+	}
+
+	return vars, diags
+}
+
+// lookupVariableOverride returns an explicit override or environment value for a variable
+func lookupVariableOverride(name string) (string, bool) {
+	if v, ok := VariableOverrides[name]; ok {
+		return v, true
+	}
+	return os.LookupEnv(VariableEnvPrefix + name)
+}
+
+// convertOverride converts a string override to the type of the variable default.
+// Lists and maps are written as HCL expressions (e.g., SS_VAR_zones='["a", "b"]').
+func convertOverride(raw string, ty cty.Type) (cty.Value, error) {
+	value := cty.StringVal(raw)
+	switch {
+	case ty == cty.DynamicPseudoType || ty == cty.String:
+		return value, nil
+	case ty.IsPrimitiveType():
+		return convert.Convert(value, ty)
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(raw), "override", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("%q is not a valid expression: %s", raw, diags.Error())
+	}
+	value, diags = expr.Value(nil)
+	if diags.HasErrors() {
+		return cty.NilVal, fmt.Errorf("%q is not a constant value: %s", raw, diags.Error())
+	}
+
+	// A list default has a tuple type of fixed length; keep the kind, not the length
+	isList := func(t cty.Type) bool { return t.IsTupleType() || t.IsListType() || t.IsSetType() }
+	isMap := func(t cty.Type) bool { return t.IsObjectType() || t.IsMapType() }
+	switch {
+	case isList(ty) && isList(value.Type()), isMap(ty) && isMap(value.Type()):
+		return value, nil
+	case isList(ty):
+		return cty.NilVal, fmt.Errorf("a list is required, got %s", value.Type().FriendlyName())
+	case isMap(ty):
+		return cty.NilVal, fmt.Errorf("a map is required, got %s", value.Type().FriendlyName())
+	}
+	return convert.Convert(value, ty)
+}
+
+// evaluateLocals resolves locals in dependency order, allowing locals to reference each other
+func evaluateLocals(blocks []*hcl.Block, ctx *hcl.EvalContext) (map[string]cty.Value, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	pending := map[string]*hcl.Attribute{}
+
+	for _, block := range blocks {
+		attrs, attrDiags := block.Body.JustAttributes()
+		diags = append(diags, attrDiags...)
+		for name, attr := range attrs {
+			if _, exists := pending[name]; exists {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Duplicate local value",
+					Detail:   fmt.Sprintf("Local %q is defined more than once.", name),
+					Subject:  attr.NameRange.Ptr(),
+				})
+				continue
+			}
+			pending[name] = attr
+		}
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	locals := map[string]cty.Value{}
+	for len(pending) > 0 {
+		progressed := false
+
+		for _, name := range sortedKeys(pending) {
+			attr := pending[name]
+			if !localDependenciesResolved(attr.Expr, locals, pending) {
+				continue
+			}
+
+			localCtx := ctx.NewChild()
+			localCtx.Variables = map[string]cty.Value{"local": cty.ObjectVal(locals)}
+			value, valDiags := attr.Expr.Value(localCtx)
+			diags = append(diags, valDiags...)
+			if valDiags.HasErrors() {
+				return nil, diags
+			}
+
+			locals[name] = value
+			delete(pending, name)
+			progressed = true
+		}
+
+		if !progressed {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Unresolvable local values",
+				Detail:   fmt.Sprintf("Locals %s reference each other in a cycle.", strings.Join(sortedKeys(pending), ", ")),
+			})
+			return nil, diags
+		}
+	}
+
+	return locals, diags
+}
+
+// localDependenciesResolved reports whether every local.* reference in expr is already evaluated.
+// References to undefined locals are left for expression evaluation to report.
+func localDependenciesResolved(expr hcl.Expression, locals map[string]cty.Value, pending map[string]*hcl.Attribute) bool {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "local" || len(traversal) < 2 {
+			continue
+		}
+		attr, ok := traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			continue
+		}
+		if _, done := locals[attr.Name]; done {
+			continue
+		}
+		if _, waiting := pending[attr.Name]; waiting {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]*hcl.Attribute) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// standardFunctions is the function library available in configuration files
+func standardFunctions() map[string]function.Function {
+	funcs := map[string]function.Function{
+		"lower":      stdlib.LowerFunc,
+		"upper":      stdlib.UpperFunc,
+		"format":     stdlib.FormatFunc,
+		"coalesce":   stdlib.CoalesceFunc,
+		"tonumber":   stdlib.MakeToFunc(cty.Number),
+		"tostring":   stdlib.MakeToFunc(cty.String),
+		"tobool":     stdlib.MakeToFunc(cty.Bool),
+		"timeadd":    stdlib.TimeAddFunc,
+		"trimspace":  stdlib.TrimSpaceFunc,
+		"replace":    stdlib.ReplaceFunc,
+		"join":       stdlib.JoinFunc,
+		"split":      stdlib.SplitFunc,
+		"merge":      stdlib.MergeFunc,
+		"jsonencode": stdlib.JSONEncodeFunc,
+	}
+
+	// Secret providers: file(), env(), secret()
+	for name, fn := range secretFunctions() {
+		funcs[name] = fn
+	}
+
+	return funcs
+}
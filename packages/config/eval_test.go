@@ -0,0 +1,171 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// evaluate builds the evaluation context of src and evaluates expr in it
+func evaluate(t *testing.T, src, expr string) (cty.Value, hcl.Diagnostics) {
+	t.Helper()
+
+	file, diags := hclsyntax.ParseConfig([]byte(src), "config.hcl", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("failed to parse %q: %v", src, diags)
+	}
+	ctx, _, diags := buildEvalContext(file.Body)
+	if diags.HasErrors() {
+		return cty.NilVal, diags
+	}
+
+	e, diags := hclsyntax.ParseExpression([]byte(expr), "expr", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("failed to parse %q: %v", expr, diags)
+	}
+	return e.Value(ctx)
+}
+
+// checkValue compares a result with the wanted value or error
+func checkValue(t *testing.T, got cty.Value, diags hcl.Diagnostics, want cty.Value, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if !diags.HasErrors() || !strings.Contains(diags.Error(), wantErr) {
+			t.Errorf("error = %v, want one containing %q", diags, wantErr)
+		}
+		return
+	}
+	if diags.HasErrors() {
+		t.Fatalf("evaluation failed: %v", diags)
+	}
+	if !got.RawEquals(want) {
+		t.Errorf("value = %#v, want %#v", got, want)
+	}
+}
+
+// TestLocals checks that locals resolve whatever order they reference each
+// other in, and that cycles are reported
+func TestLocals(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		expr    string
+		want    cty.Value
+		wantErr string
+	}{
+		{"literal", `locals { a = "x" }`, "local.a", cty.StringVal("x"), ""},
+		{"reference declared later", `locals {
+  a = "${local.b}-a"
+  b = "b"
+}`, "local.a", cty.StringVal("b-a"), ""},
+		{"chain in reverse order", `locals {
+  a = "${local.b}/a"
+  b = "${local.c}/b"
+  c = "c"
+}`, "local.a", cty.StringVal("c/b/a"), ""},
+		{"across blocks", `locals { z = upper(local.y) }
+locals { y = var.env }
+variable "env" { default = "prod" }`, "local.z", cty.StringVal("PROD"), ""},
+		{"cycle", `locals {
+  a = local.b
+  b = local.c
+  c = local.a
+  d = "free"
+}`, "local.d", cty.NilVal, "Locals a, b, c reference each other in a cycle"},
+		{"self reference", `locals { a = "${local.a}x" }`, "local.a", cty.NilVal, "Locals a reference each other in a cycle"},
+		{"undefined local", `locals { a = local.missing }`, "local.a", cty.NilVal, "Unsupported attribute"},
+		{"duplicate", `locals { a = 1 }
+locals { a = 2 }`, "local.a", cty.NilVal, "Local \"a\" is defined more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diags := evaluate(t, tt.src, tt.expr)
+			checkValue(t, got, diags, tt.want, tt.wantErr)
+		})
+	}
+}
+
+// TestVariableOverrides checks that SS_VAR_<name> values are converted to the
+// type of the variable default, and that explicit overrides win
+func TestVariableOverrides(t *testing.T) {
+	tests := []struct {
+		name     string
+		def      string
+		env      string
+		override string
+		want     cty.Value
+		wantErr  string
+	}{
+		{"default", `"local"`, "", "", cty.StringVal("local"), ""},
+		{"string", `"local"`, "prod", "", cty.StringVal("prod"), ""},
+		{"no default", "", "prod", "", cty.StringVal("prod"), ""},
+		{"no default and no override", "", "", "", cty.NilVal, "has no default; set SS_VAR_v"},
+		{"number", "8080", "9000", "", cty.NumberIntVal(9000), ""},
+		{"invalid number", "8080", "ninety", "", cty.NilVal, "Invalid variable override"},
+		{"bool", "false", "true", "", cty.True, ""},
+		{"invalid bool", "false", "maybe", "", cty.NilVal, "Invalid variable override"},
+		{"list", `["a"]`, `["b", "c"]`, "", cty.TupleVal([]cty.Value{cty.StringVal("b"), cty.StringVal("c")}), ""},
+		{"empty list", `["a"]`, `[]`, "", cty.EmptyTupleVal, ""},
+		{"list from a bare string", `["a"]`, "b,c", "", cty.NilVal, "is not a valid expression"},
+		{"list from a map", `["a"]`, `{ b = "c" }`, "", cty.NilVal, "a list is required"},
+		{"list with references", `["a"]`, `[var.other]`, "", cty.NilVal, "is not a constant value"},
+		{"map", `{ a = "b" }`, `{ c = "d", e = "f" }`, "", cty.ObjectVal(map[string]cty.Value{"c": cty.StringVal("d"), "e": cty.StringVal("f")}), ""},
+		{"map from a list", `{ a = "b" }`, `["c"]`, "", cty.NilVal, "a map is required"},
+		{"explicit override wins", `"local"`, "prod", "staging", cty.StringVal("staging"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv(VariableEnvPrefix+"v", tt.env)
+			}
+			saved := VariableOverrides
+			t.Cleanup(func() { VariableOverrides = saved })
+			VariableOverrides = map[string]string{}
+			if tt.override != "" {
+				VariableOverrides["v"] = tt.override
+			}
+
+			src := `variable "v" {}`
+			if tt.def != "" {
+				src = `variable "v" { default = ` + tt.def + ` }`
+			}
+			got, diags := evaluate(t, src, "var.v")
+			checkValue(t, got, diags, tt.want, tt.wantErr)
+		})
+	}
+}
+
+// TestFunctions checks a sample of the configuration function library
+func TestFunctions(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    cty.Value
+		wantErr string
+	}{
+		{`lower("ABC")`, cty.StringVal("abc"), ""},
+		{`upper("abc")`, cty.StringVal("ABC"), ""},
+		{`format("%s-%d", "node", 3)`, cty.StringVal("node-3"), ""},
+		{`coalesce(null, "fallback")`, cty.StringVal("fallback"), ""},
+		{`tonumber("42")`, cty.NumberIntVal(42), ""},
+		{`tonumber("x")`, cty.NilVal, "Invalid function argument"},
+		{`tobool("true")`, cty.True, ""},
+		{`trimspace("  padded  ")`, cty.StringVal("padded"), ""},
+		{`replace("a-b-c", "-", "_")`, cty.StringVal("a_b_c"), ""},
+		{`join(",", split("/", "a/b/c"))`, cty.StringVal("a,b,c"), ""},
+		{`jsonencode({ days = 30 })`, cty.StringVal(`{"days":30}`), ""},
+		{`merge({ a = "1" }, { b = "2" }).b`, cty.StringVal("2"), ""},
+		{`missing("x")`, cty.NilVal, "Call to unknown function"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, diags := evaluate(t, "", tt.expr)
+			checkValue(t, got, diags, tt.want, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestExampleConfigurationLoads checks that the file printed by
+// config defaults is a valid configuration as printed
+func TestExampleConfigurationLoads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.hcl")
+	if err := os.WriteFile(path, []byte(ExampleConfiguration), 0600); err != nil {
+		t.Fatalf("Failed to write example configuration: %v", err)
+	}
+
+	t.Cleanup(func() { AppConfig = Configuration{} })
+	if err := LoadConfigurationFile(path); err != nil {
+		t.Fatalf("Example configuration does not load: %v", err)
+	}
+}
+
+// TestExampleConfigurationCoversSettings checks that every setting of the
+// schema appears in the example, commented or not, so new blocks are
+// documented in config defaults
+func TestExampleConfigurationCoversSettings(t *testing.T) {
+	for _, key := range settingKeys("", structSchema(reflect.TypeOf(Configuration{}), false)) {
+		parts := strings.Split(key, ".")
+		name := parts[len(parts)-1]
+		pattern := regexp.MustCompile(`(?m)^[#\s]*` + regexp.QuoteMeta(name) + `\s*(=|\{|")`)
+		if !pattern.MatchString(ExampleConfiguration) {
+			t.Errorf("ExampleConfiguration does not mention %s", key)
+		}
+	}
+}
+
+// settingKeys lists the dotted settings of a schema, including those of
+// repeatable blocks, which ConfigKeys leaves out
+func settingKeys(prefix string, schema map[string]interface{}) []string {
+	var keys []string
+	props, _ := schema["properties"].(map[string]interface{})
+	for name, prop := range props {
+		key := prefix + name
+		keys = append(keys, key)
+
+		sub, _ := prop.(map[string]interface{})
+		if items, ok := sub["items"].(map[string]interface{}); ok {
+			sub = items
+		}
+		if sub["type"] == "object" {
+			keys = append(keys, settingKeys(key+".", sub)...)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Filesystem holds permissions and ownership enforced on configured directories
+// (log_dir, data_dir) when they are bootstrapped
+type Filesystem struct {
+	// DirMode is the octal permission mode for created directories (default: "0755")
+	DirMode string `hcl:"dir_mode,optional" json:"dir_mode,omitempty"`
+
+	// Owner, if set, is the user (name or uid) that must own the directories
+	Owner string `hcl:"owner,optional" json:"owner,omitempty"`
+
+	// Group, if set, is the group (name or gid) that must own the directories
+	Group string `hcl:"group,optional" json:"group,omitempty"`
+}
+
+// Mode returns DirMode as a file mode
+func (f *Filesystem) Mode() os.FileMode {
+	mode, _ := strconv.ParseUint(f.DirMode, 8, 32)
+	return os.FileMode(mode)
+}
+
+// ResolvePath interprets relative paths against the service root directory;
+// absolute paths are returned cleaned
+func ResolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(RootDir, path)
+}
+
+// applyFilesystemDefaults sets default directory permissions
+func applyFilesystemDefaults() {
+	if AppConfig.Filesystem == nil {
+		AppConfig.Filesystem = &Filesystem{}
+	}
+
+	// Default directory mode is rwxr-xr-x
+	if AppConfig.Filesystem.DirMode == "" {
+		AppConfig.Filesystem.DirMode = "0755"
+	}
+}
+
+// validateFilesystem checks directory settings after defaults are applied
+func validateFilesystem() error {
+	if AppConfig.LogDir == "" {
+		return fmt.Errorf("log_dir must not be empty")
+	}
+	if AppConfig.DataDir == "" {
+		return fmt.Errorf("data_dir must not be empty")
+	}
+
+	f := AppConfig.Filesystem
+	if f == nil {
+		return nil
+	}
+
+	mode, err := strconv.ParseUint(f.DirMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return fmt.Errorf("filesystem.dir_mode must be an octal permission such as \"0750\", got %q", f.DirMode)
+	}
+	if mode&0700 != 0700 {
+		return fmt.Errorf("filesystem.dir_mode must give the owner rwx permissions, got %q", f.DirMode)
+	}
+
+	return nil
+}
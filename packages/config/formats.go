@@ -0,0 +1,166 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"go.yaml.in/yaml/v3"
+)
+
+// Supported configuration file formats
+const (
+	FormatHCL  = "hcl"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// DetectFormat returns the configuration format implied by the file extension.
+// Files without a recognised extension are treated as HCL.
+func DetectFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".hcl", "":
+		return FormatHCL, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("unsupported configuration file extension %q (expected .hcl, .json or .yaml)", filepath.Ext(path))
+	}
+}
+
+// parseConfigFile parses raw configuration data into an HCL file regardless of format.
+// JSON uses HCL's native JSON syntax; YAML is converted to JSON first so every format
+// shares the same variables, locals, functions, decoding and validation.
+func parseConfigFile(parser *hclparse.Parser, data []byte, path string) (*hcl.File, hcl.Diagnostics) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported configuration format",
+			Detail:   err.Error(),
+		}}
+	}
+
+	switch format {
+	case FormatJSON:
+		return parser.ParseJSON(data, path)
+	case FormatYAML:
+		jsonData, err := yamlToJSON(data)
+		if err != nil {
+			return nil, hcl.Diagnostics{{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid YAML configuration",
+				Detail:   err.Error(),
+			}}
+		}
+		return parser.ParseJSON(jsonData, path)
+	default:
+		return parser.ParseHCL(data, path)
+	}
+}
+
+// yamlToJSON converts a YAML document to JSON
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	normalized, err := normalizeYAML(doc)
+	if err != nil {
+		return nil, err
+	}
+	if normalized == nil {
+		normalized = map[string]interface{}{}
+	}
+
+	return json.Marshal(normalized)
+}
+
+// normalizeYAML converts YAML maps with non-string keys into JSON-compatible maps
+func normalizeYAML(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			n, err := normalizeYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = n
+		}
+		return out, nil
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			n, err := normalizeYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprintf("%v", k)] = n
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			n, err := normalizeYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = n
+		}
+		return out, nil
+	default:
+		return val, nil
+	}
+}
+
+// MarshalConfiguration renders a configuration in the requested format (hcl, json or yaml)
+func MarshalConfiguration(cfg Configuration, format string) ([]byte, error) {
+	switch format {
+	case FormatHCL:
+		file := hclwrite.NewEmptyFile()
+		gohcl.EncodeIntoBody(&cfg, file.Body())
+		removeNullAttributes(file.Body())
+		return hclwrite.Format(file.Bytes()), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		// Go through JSON so YAML keys follow the json tags
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(doc)
+	default:
+		return nil, fmt.Errorf("unsupported output format %q (expected hcl, json or yaml)", format)
+	}
+}
+
+// removeNullAttributes drops the "null" attributes gohcl writes for unset
+// maps, slices and pointers, in body and its nested blocks
+func removeNullAttributes(body *hclwrite.Body) {
+	for name, attr := range body.Attributes() {
+		if strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes())) == "null" {
+			body.RemoveAttribute(name)
+		}
+	}
+	for _, block := range body.Blocks() {
+		removeNullAttributes(block.Body())
+	}
+}
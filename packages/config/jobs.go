@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Jobs configures the persistent background job queue
+type Jobs struct {
+	// Concurrency is the number of jobs run at once (default: 4)
+	Concurrency int `hcl:"concurrency,optional" json:"concurrency,omitempty"`
+
+	// MaxAttempts is the default number of runs before a job is dead-lettered
+	// (default: 5)
+	MaxAttempts int `hcl:"max_attempts,optional" json:"max_attempts,omitempty"`
+
+	// BackoffSeconds is the delay before the first retry, doubled for each
+	// later one (default: 1)
+	BackoffSeconds int `hcl:"backoff_seconds,optional" json:"backoff_seconds,omitempty"`
+
+	// MaxBackoffSeconds caps the retry delay (default: 300)
+	MaxBackoffSeconds int `hcl:"max_backoff_seconds,optional" json:"max_backoff_seconds,omitempty"`
+
+	// PollIntervalSeconds is how often delayed and retried jobs are checked
+	// for (default: 1). New jobs start without waiting for the poll.
+	PollIntervalSeconds int `hcl:"poll_interval_seconds,optional" json:"poll_interval_seconds,omitempty"`
+
+	// RetentionHours is how long succeeded jobs stay queryable (default: 168).
+	// Dead jobs are kept until retried.
+	RetentionHours int `hcl:"retention_hours,optional" json:"retention_hours,omitempty"`
+}
+
+// applyJobsDefaults retries a failing job four times over about 15 seconds before dead-lettering it
+func applyJobsDefaults() {
+	if AppConfig.Jobs == nil {
+		AppConfig.Jobs = &Jobs{}
+	}
+
+	j := AppConfig.Jobs
+
+	if j.Concurrency == 0 {
+		j.Concurrency = 4
+	}
+	if j.MaxAttempts == 0 {
+		j.MaxAttempts = 5
+	}
+	if j.BackoffSeconds == 0 {
+		j.BackoffSeconds = 1
+	}
+	if j.MaxBackoffSeconds == 0 {
+		j.MaxBackoffSeconds = 300
+	}
+	if j.PollIntervalSeconds == 0 {
+		j.PollIntervalSeconds = 1
+	}
+	if j.RetentionHours == 0 {
+		j.RetentionHours = 168
+	}
+}
+
+// validateJobs checks job queue settings after defaults are applied
+func validateJobs() error {
+	j := AppConfig.Jobs
+	if j == nil {
+		return nil
+	}
+
+	settings := []struct {
+		name  string
+		value int
+	}{
+		{"concurrency", j.Concurrency},
+		{"max_attempts", j.MaxAttempts},
+		{"backoff_seconds", j.BackoffSeconds},
+		{"max_backoff_seconds", j.MaxBackoffSeconds},
+		{"poll_interval_seconds", j.PollIntervalSeconds},
+		{"retention_hours", j.RetentionHours},
+	}
+	for _, s := range settings {
+		if s.value < 1 {
+			return fmt.Errorf("jobs.%s must be positive, got %d", s.name, s.value)
+		}
+	}
+	if j.MaxBackoffSeconds < j.BackoffSeconds {
+		return fmt.Errorf("jobs.max_backoff_seconds (%d) must not be less than jobs.backoff_seconds (%d)", j.MaxBackoffSeconds, j.BackoffSeconds)
+	}
+
+	return nil
+}
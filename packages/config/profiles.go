@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// ActiveProfile is the profile overlaid on the base configuration ("" when none).
+// Selected with --profile or SS_PROFILE.
+var ActiveProfile string
+
+// ProfileNames lists the profiles declared in the loaded configuration file
+var ProfileNames []string
+
+// profileSchema extracts profile blocks from the configuration body
+var profileSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "profile", LabelNames: []string{"name"}},
+	},
+}
+
+// DeclaredProfiles returns the profile names declared in a configuration file
+// without evaluating or validating it (used for shell completion)
+func DeclaredProfiles(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, diags := parseConfigFile(hclparse.NewParser(), data, path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	content, _, diags := file.Body.PartialContent(profileSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	var names []string
+	for _, block := range content.Blocks {
+		names = append(names, block.Labels[0])
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// applyProfile removes profile blocks from the body and, when a profile is selected,
+// returns a body where the profile's settings are overlaid on the base settings.
+//
+//	server {
+//	  port    = "8080"
+//	  address = "127.0.0.1"
+//	}
+//
+//	profile "prod" {
+//	  server {
+//	    address = "0.0.0.0"   # port is inherited from the base block
+//	  }
+//	}
+func applyProfile(body hcl.Body, selected string) (hcl.Body, hcl.Diagnostics) {
+	content, remain, diags := body.PartialContent(profileSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	profiles := map[string]*hcl.Block{}
+	ProfileNames = nil
+	for _, block := range content.Blocks {
+		name := block.Labels[0]
+		if _, exists := profiles[name]; exists {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Duplicate profile",
+				Detail:   fmt.Sprintf("Profile %q is declared more than once.", name),
+				Subject:  block.DefRange.Ptr(),
+			})
+			continue
+		}
+		profiles[name] = block
+		ProfileNames = append(ProfileNames, name)
+	}
+	sort.Strings(ProfileNames)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if selected == "" {
+		return remain, diags
+	}
+
+	block, ok := profiles[selected]
+	if !ok {
+		available := "none declared"
+		if len(ProfileNames) > 0 {
+			available = strings.Join(ProfileNames, ", ")
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unknown profile",
+			Detail:   fmt.Sprintf("Profile %q is not declared (available: %s).", selected, available),
+			Subject:  body.MissingItemRange().Ptr(),
+		})
+		return nil, diags
+	}
+
+	return &overlayBody{base: remain, overlay: block.Body}, diags
+}
+
+// overlayBody merges two bodies: overlay attributes replace base attributes, and
+// blocks with the same type and labels are merged recursively.
+type overlayBody struct {
+	base    hcl.Body
+	overlay hcl.Body
+}
+
+func (b *overlayBody) Content(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Diagnostics) {
+	content, baseRemain, overlayRemain, diags := b.mergeContent(schema)
+
+	// Anything left over in either body is not part of the schema
+	_, extraDiags := baseRemain.Content(&hcl.BodySchema{})
+	diags = append(diags, extraDiags...)
+	_, extraDiags = overlayRemain.Content(&hcl.BodySchema{})
+	diags = append(diags, extraDiags...)
+
+	return content, diags
+}
+
+func (b *overlayBody) PartialContent(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Body, hcl.Diagnostics) {
+	content, baseRemain, overlayRemain, diags := b.mergeContent(schema)
+	return content, &overlayBody{base: baseRemain, overlay: overlayRemain}, diags
+}
+
+// mergeContent extracts the schema from both bodies and merges the results
+func (b *overlayBody) mergeContent(schema *hcl.BodySchema) (*hcl.BodyContent, hcl.Body, hcl.Body, hcl.Diagnostics) {
+	// Required attributes only need to be present in one of the bodies
+	optional := &hcl.BodySchema{Blocks: schema.Blocks}
+	for _, attr := range schema.Attributes {
+		attr.Required = false
+		optional.Attributes = append(optional.Attributes, attr)
+	}
+
+	baseContent, baseRemain, diags := b.base.PartialContent(optional)
+	overlayContent, overlayRemain, overlayDiags := b.overlay.PartialContent(optional)
+	diags = append(diags, overlayDiags...)
+
+	merged := &hcl.BodyContent{
+		Attributes:       hcl.Attributes{},
+		MissingItemRange: baseContent.MissingItemRange,
+	}
+	for name, attr := range baseContent.Attributes {
+		merged.Attributes[name] = attr
+	}
+	for name, attr := range overlayContent.Attributes {
+		merged.Attributes[name] = attr
+	}
+
+	for _, attr := range schema.Attributes {
+		if _, ok := merged.Attributes[attr.Name]; attr.Required && !ok {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Missing required argument",
+				Detail:   fmt.Sprintf("The argument %q is required, but no definition was found.", attr.Name),
+				Subject:  merged.MissingItemRange.Ptr(),
+			})
+		}
+	}
+
+	merged.Blocks = mergeBlocks(baseContent.Blocks, overlayContent.Blocks)
+
+	return merged, baseRemain, overlayRemain, diags
+}
+
+func (b *overlayBody) JustAttributes() (hcl.Attributes, hcl.Diagnostics) {
+	attrs, diags := b.base.JustAttributes()
+	overlayAttrs, overlayDiags := b.overlay.JustAttributes()
+	diags = append(diags, overlayDiags...)
+
+	merged := hcl.Attributes{}
+	for name, attr := range attrs {
+		merged[name] = attr
+	}
+	for name, attr := range overlayAttrs {
+		merged[name] = attr
+	}
+	return merged, diags
+}
+
+func (b *overlayBody) MissingItemRange() hcl.Range {
+	return b.base.MissingItemRange()
+}
+
+// mergeBlocks overlays blocks matched by type and labels; unmatched overlay blocks are appended
+func mergeBlocks(base, overlay hcl.Blocks) hcl.Blocks {
+	merged := make(hcl.Blocks, 0, len(base)+len(overlay))
+	index := map[string]int{}
+
+	for _, block := range base {
+		index[blockKey(block)] = len(merged)
+		merged = append(merged, block)
+	}
+
+	for _, block := range overlay {
+		i, ok := index[blockKey(block)]
+		if !ok {
+			merged = append(merged, block)
+			continue
+		}
+		combined := *merged[i]
+		combined.Body = &overlayBody{base: merged[i].Body, overlay: block.Body}
+		merged[i] = &combined
+	}
+
+	return merged
+}
+
+func blockKey(block *hcl.Block) string {
+	return block.Type + "\x00" + strings.Join(block.Labels, "\x00")
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+)
+
+// Rate limit keys accepted in rate_limit.route_group key
+const (
+	RateLimitKeyIP        = "ip"
+	RateLimitKeyPrincipal = "principal"
+	RateLimitKeyAPIKey    = "api_key"
+)
+
+// RateLimit configures API throttling: a global cap on requests in flight and
+// a token bucket per client in each route group. Without a rate_limit block
+// nothing is throttled.
+type RateLimit struct {
+	// MaxInFlight caps the requests served at once across every route group;
+	// 0 means no cap (default: 0)
+	MaxInFlight int `hcl:"max_in_flight,optional" json:"max_in_flight,omitempty"`
+
+	// TrustedProxies are CIDRs of proxies whose X-Forwarded-For header is
+	// trusted to carry the client IP
+	TrustedProxies []string `hcl:"trusted_proxies,optional" json:"trusted_proxies,omitempty"`
+
+	// APIKeyHeader is the request header read by the api_key key
+	// (default: X-API-Key)
+	APIKeyHeader string `hcl:"api_key_header,optional" json:"api_key_header,omitempty"`
+
+	// APIKeys are the keys clients are limited by with the api_key key,
+	// usually read with file() or env(); other values of the header fall
+	// back to the client IP
+	APIKeys []string `hcl:"api_keys,optional" json:"api_keys,omitempty" redact:"true"`
+
+	// RouteGroups set the per-client limit of each route group; groups
+	// without one are not rate limited
+	RouteGroups []*RateLimitRouteGroup `hcl:"route_group,block" json:"route_groups,omitempty"`
+}
+
+// RateLimitRouteGroup limits each client of a route group to a token bucket.
+// The label names a route group of the API (system, api, admin).
+type RateLimitRouteGroup struct {
+	Name string `hcl:"name,label" json:"name"`
+
+	// Key identifies clients: ip, principal or api_key (default: ip).
+	// Anonymous requests and requests without one of the api_keys fall back
+	// to the client IP.
+	Key string `hcl:"key,optional" json:"key,omitempty"`
+
+	// RequestsPerSecond is the sustained rate each client is allowed
+	RequestsPerSecond float64 `hcl:"requests_per_second" json:"requests_per_second"`
+
+	// Burst is how many requests a client may send at once
+	// (default: requests_per_second rounded up)
+	Burst int `hcl:"burst,optional" json:"burst,omitempty"`
+}
+
+// applyRateLimitDefaults fills in the API key header and bursts when rate
+// limiting is configured
+func applyRateLimitDefaults() {
+	rl := AppConfig.RateLimit
+	if rl == nil {
+		return
+	}
+
+	if rl.APIKeyHeader == "" {
+		rl.APIKeyHeader = "X-API-Key"
+	}
+
+	for _, g := range rl.RouteGroups {
+		if g.Key == "" {
+			g.Key = RateLimitKeyIP
+		}
+		if g.Burst == 0 && g.RequestsPerSecond > 0 {
+			g.Burst = int(math.Ceil(g.RequestsPerSecond))
+		}
+	}
+}
+
+// validateRateLimit checks rate limit settings after defaults are applied.
+// Route group names are checked by the api package, which owns the routes.
+func validateRateLimit() error {
+	rl := AppConfig.RateLimit
+	if rl == nil {
+		return nil
+	}
+
+	if rl.MaxInFlight < 0 {
+		return fmt.Errorf("rate_limit.max_in_flight must not be negative, got %d", rl.MaxInFlight)
+	}
+
+	for _, cidr := range rl.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("rate_limit.trusted_proxies: invalid CIDR %q", cidr)
+		}
+	}
+
+	if http.CanonicalHeaderKey(rl.APIKeyHeader) == "Authorization" {
+		return fmt.Errorf("rate_limit.api_key_header must not be Authorization; use key = \"principal\" to limit authenticated callers")
+	}
+	for _, key := range rl.APIKeys {
+		if key == "" {
+			return fmt.Errorf("rate_limit.api_keys must not contain empty keys")
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, g := range rl.RouteGroups {
+		if seen[g.Name] {
+			return fmt.Errorf("rate_limit: route_group %q is declared twice", g.Name)
+		}
+		seen[g.Name] = true
+
+		switch g.Key {
+		case RateLimitKeyIP:
+		case RateLimitKeyAPIKey:
+			if len(rl.APIKeys) == 0 {
+				return fmt.Errorf("rate_limit: route_group %q: key api_key needs rate_limit.api_keys", g.Name)
+			}
+		case RateLimitKeyPrincipal:
+			if AppConfig.Auth == nil {
+				return fmt.Errorf("rate_limit: route_group %q: key principal needs an auth block", g.Name)
+			}
+		default:
+			return fmt.Errorf("rate_limit: route_group %q: unknown key %q (want ip, principal or api_key)", g.Name, g.Key)
+		}
+
+		if g.RequestsPerSecond <= 0 {
+			return fmt.Errorf("rate_limit: route_group %q: requests_per_second must be positive, got %v", g.Name, g.RequestsPerSecond)
+		}
+		if g.Burst < 1 {
+			return fmt.Errorf("rate_limit: route_group %q: burst must be positive, got %d", g.Name, g.Burst)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ScheduleTask schedules a periodic task. The label names a task registered in
+// code (overriding its schedule) or, with Job set, a task defined here that
+// enqueues a job on every run.
+type ScheduleTask struct {
+	Name string `hcl:"name,label" json:"name"`
+
+	// Cron is a five-field cron expression (minute hour day-of-month month
+	// day-of-week) or a macro such as @hourly, evaluated in local time
+	Cron string `hcl:"cron,optional" json:"cron,omitempty"`
+
+	// IntervalSeconds runs the task at a fixed interval instead of Cron
+	IntervalSeconds int `hcl:"interval_seconds,optional" json:"interval_seconds,omitempty"`
+
+	// JitterSeconds delays each run by a random amount up to this value so
+	// instances sharing a schedule do not run at the same moment (default: 0)
+	JitterSeconds int `hcl:"jitter_seconds,optional" json:"jitter_seconds,omitempty"`
+
+	// AllowOverlap starts a run even when the previous one is still going
+	// (default: false, the run is skipped)
+	AllowOverlap *bool `hcl:"allow_overlap,optional" json:"allow_overlap,omitempty"`
+
+	// Enabled turns the task off without removing it (default: true)
+	Enabled *bool `hcl:"enabled,optional" json:"enabled,omitempty"`
+
+	// Job enqueues a job of this type on every run
+	Job string `hcl:"job,optional" json:"job,omitempty"`
+
+	// Payload is the JSON payload of the enqueued job, e.g. jsonencode({ days = 30 })
+	Payload string `hcl:"payload,optional" json:"payload,omitempty"`
+}
+
+// applyScheduleDefaults turns snapshot.interval_seconds into the schedule of
+// the built-in "snapshot" task; a schedule "snapshot" block takes precedence
+func applyScheduleDefaults() {
+	s := AppConfig.Snapshot
+	if s == nil || s.IntervalSeconds <= 0 {
+		return
+	}
+
+	for _, task := range AppConfig.Schedule {
+		if task.Name == "snapshot" {
+			return
+		}
+	}
+	AppConfig.Schedule = append(AppConfig.Schedule, &ScheduleTask{
+		Name:            "snapshot",
+		IntervalSeconds: s.IntervalSeconds,
+	})
+}
+
+// validateSchedule checks schedule blocks. Cron expressions and task names are
+// checked by the scheduler, which owns the parser and the task registry.
+func validateSchedule() error {
+	seen := map[string]bool{}
+	for _, s := range AppConfig.Schedule {
+		if seen[s.Name] {
+			return fmt.Errorf("schedule %q is declared twice", s.Name)
+		}
+		seen[s.Name] = true
+
+		if s.Cron != "" && s.IntervalSeconds != 0 {
+			return fmt.Errorf("schedule %q: set either cron or interval_seconds, not both", s.Name)
+		}
+		if s.IntervalSeconds < 0 {
+			return fmt.Errorf("schedule %q: interval_seconds must be positive, got %d", s.Name, s.IntervalSeconds)
+		}
+		if s.JitterSeconds < 0 {
+			return fmt.Errorf("schedule %q: jitter_seconds must not be negative, got %d", s.Name, s.JitterSeconds)
+		}
+		if s.Job != "" && s.Cron == "" && s.IntervalSeconds == 0 {
+			return fmt.Errorf("schedule %q: cron or interval_seconds is required with job", s.Name)
+		}
+		if s.Payload != "" {
+			if s.Job == "" {
+				return fmt.Errorf("schedule %q: payload requires job", s.Name)
+			}
+			if !json.Valid([]byte(s.Payload)) {
+				return fmt.Errorf("schedule %q: payload must be valid JSON", s.Name)
+			}
+		}
+	}
+
+	return nil
+}
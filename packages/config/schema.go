@@ -0,0 +1,156 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONSchema returns a JSON Schema (draft 2020-12) for Configuration generated from
+// the hcl struct tags. It describes JSON/YAML configuration files and HCL files alike.
+func JSONSchema() map[string]interface{} {
+	schema := structSchema(reflect.TypeOf(Configuration{}), true)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "service-seed configuration"
+
+	props := schema["properties"].(map[string]interface{})
+
+	// Evaluation blocks (see eval.go and profiles.go)
+	props["variable"] = map[string]interface{}{
+		"type":        "object",
+		"description": "Input variables referenced as var.<name>; override with SS_VAR_<name>",
+		"additionalProperties": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"default":     map[string]interface{}{},
+				"description": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+	props["locals"] = map[string]interface{}{
+		"type":        "object",
+		"description": "Computed values referenced as local.<name>",
+	}
+	props["profile"] = map[string]interface{}{
+		"type":                 "object",
+		"description":          "Named profiles overlaid on the base settings; select with --profile or SS_PROFILE",
+		"additionalProperties": structSchema(reflect.TypeOf(Configuration{}), false),
+	}
+
+	return schema
+}
+
+// ConfigKeys lists every configuration setting as a dotted path (e.g. "server.port"),
+// including block names, in sorted order
+func ConfigKeys() []string {
+	var keys []string
+	var walk func(prefix string, schema map[string]interface{})
+	walk = func(prefix string, schema map[string]interface{}) {
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, prop := range props {
+			key := prefix + name
+			keys = append(keys, key)
+			if sub, ok := prop.(map[string]interface{}); ok && sub["type"] == "object" {
+				walk(key+".", sub)
+			}
+		}
+	}
+	walk("", structSchema(reflect.TypeOf(Configuration{}), false))
+
+	sort.Strings(keys)
+	return keys
+}
+
+// structSchema builds an object schema from a struct's hcl tags.
+// When requireFields is false every property is optional (used for profile overlays).
+func structSchema(t reflect.Type, requireFields bool) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("hcl")
+		if !ok {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name, kind := parts[0], ""
+		if len(parts) > 1 {
+			kind = parts[1]
+		}
+
+		switch kind {
+		case "label", "remain":
+			continue
+		case "block":
+			properties[name] = blockSchema(field.Type, requireFields)
+			// Non-pointer, non-slice blocks are mandatory in gohcl
+			if field.Type.Kind() == reflect.Struct && requireFields {
+				required = append(required, name)
+			}
+		case "optional":
+			properties[name] = typeSchema(field.Type)
+		default:
+			properties[name] = typeSchema(field.Type)
+			if requireFields {
+				required = append(required, name)
+			}
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// blockSchema describes a nested block (struct, pointer to struct, or slice of blocks)
+func blockSchema(t reflect.Type, requireFields bool) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return blockSchema(t.Elem(), requireFields)
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": blockSchema(t.Elem(), requireFields),
+		}
+	default:
+		return structSchema(t, requireFields)
+	}
+}
+
+// typeSchema maps a Go attribute type to its JSON Schema type
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": typeSchema(t.Elem()),
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(t.Elem()),
+		}
+	default:
+		return map[string]interface{}{}
+	}
+}
@@ -0,0 +1,390 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// RedactedValue replaces resolved secrets wherever configuration is logged or exposed
+const RedactedValue = "[REDACTED]"
+
+// MinRedactLength is the shortest secret RedactSecrets masks wherever
+// it appears. Shorter values, such as a PIN, are masked only where they stand
+// alone rather than inside a longer word or number, and RedactedConfig masks
+// fields holding exactly a secret whatever its length.
+const MinRedactLength = 8
+
+// SecretProvider resolves a secret reference (file path, env var name, vault path...)
+// to its plaintext value. Providers are registered by name and exposed to HCL
+// through the generic secret("<provider>", "<ref>") function.
+type SecretProvider interface {
+	// Name is the provider identifier used in secret("<name>", "<ref>")
+	Name() string
+
+	// Resolve returns the plaintext secret for the given reference
+	Resolve(ref string) (string, error)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{}
+
+	// resolvedSecrets holds the secret values to redact: those resolved with
+	// secret() or ResolveSecret, and env() and file() values that feed a
+	// redact:"true" field
+	resolvedSecretsMu sync.RWMutex
+	resolvedSecrets   = map[string]struct{}{}
+
+	// referencedValues holds every value read with env() or file(), which are
+	// only secrets once found in a redact:"true" field
+	referencedValues = map[string]struct{}{}
+)
+
+func init() {
+	RegisterSecretProvider(&fileSecretProvider{})
+	RegisterSecretProvider(&envSecretProvider{})
+}
+
+// RegisterSecretProvider adds (or replaces) a secret provider.
+// Must be called before LoadConfiguration() for the provider to be usable in HCL.
+func RegisterSecretProvider(p SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+	secretProviders[p.Name()] = p
+}
+
+// ResolveSecret resolves a reference through the named provider and records the
+// result for redaction
+func ResolveSecret(provider, ref string) (string, error) {
+	value, err := resolveReference(provider, ref)
+	if err != nil {
+		return "", err
+	}
+
+	recordSecret(value)
+	return value, nil
+}
+
+// resolveReference resolves a reference through the named provider without
+// recording it, for env() and file(): they also read plain settings such as
+// a port or log level, which must not be masked in logs
+func resolveReference(provider, ref string) (string, error) {
+	secretProvidersMu.RLock()
+	p, ok := secretProviders[provider]
+	secretProvidersMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q", provider)
+	}
+
+	value, err := p.Resolve(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s secret %q: %v", provider, ref, err)
+	}
+	return value, nil
+}
+
+func recordSecret(value string) {
+	if value == "" {
+		return
+	}
+	resolvedSecretsMu.Lock()
+	defer resolvedSecretsMu.Unlock()
+	resolvedSecrets[value] = struct{}{}
+}
+
+func recordReference(value string) {
+	if value == "" {
+		return
+	}
+	resolvedSecretsMu.Lock()
+	defer resolvedSecretsMu.Unlock()
+	referencedValues[value] = struct{}{}
+}
+
+// recordSensitiveReferences records as secrets the env() and file() values
+// used in fields tagged redact:"true" of a decoded configuration
+func recordSensitiveReferences(cfg *Configuration) {
+	recordSensitive(reflect.ValueOf(cfg).Elem(), false)
+}
+
+func recordSensitive(v reflect.Value, sensitive bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			recordSensitive(v.Elem(), sensitive)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				recordSensitive(v.Field(i), sensitive || v.Type().Field(i).Tag.Get("redact") == "true")
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			recordSensitive(v.Index(i), sensitive)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			recordSensitive(v.MapIndex(key), sensitive)
+		}
+	case reflect.String:
+		if !sensitive {
+			return
+		}
+		// Interpolated references count too, e.g. "Bearer ${file(...)}", when
+		// long enough not to match by chance
+		resolvedSecretsMu.Lock()
+		for value := range referencedValues {
+			if value == v.String() || len(value) >= MinRedactLength && strings.Contains(v.String(), value) {
+				resolvedSecrets[value] = struct{}{}
+			}
+		}
+		resolvedSecretsMu.Unlock()
+	}
+}
+
+// RedactSecrets replaces every resolved secret found in s with RedactedValue.
+// Secrets shorter than MinRedactLength are only replaced where they are not
+// part of a longer run of letters and digits.
+func RedactSecrets(s string) string {
+	resolvedSecretsMu.RLock()
+	defer resolvedSecretsMu.RUnlock()
+
+	if len(resolvedSecrets) == 0 {
+		return s
+	}
+
+	// Replace longest secrets first so a secret containing another is fully masked
+	values := make([]string, 0, len(resolvedSecrets))
+	for v := range resolvedSecrets {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+
+	for _, v := range values {
+		if len(v) >= MinRedactLength {
+			s = strings.ReplaceAll(s, v, RedactedValue)
+		} else {
+			s = replaceStandalone(s, v)
+		}
+	}
+	return s
+}
+
+// replaceStandalone replaces occurrences of v in s that are not preceded or
+// followed by a letter or digit
+func replaceStandalone(s, v string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, v)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(v)
+		if (i == 0 || !isAlphanumeric(s[i-1])) && (end == len(s) || !isAlphanumeric(s[end])) {
+			b.WriteString(s[:i])
+			b.WriteString(RedactedValue)
+		} else {
+			b.WriteString(s[:end])
+		}
+		s = s[end:]
+	}
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// isResolvedSecret reports whether s is exactly a recorded secret
+func isResolvedSecret(s string) bool {
+	resolvedSecretsMu.RLock()
+	defer resolvedSecretsMu.RUnlock()
+
+	_, ok := resolvedSecrets[s]
+	return ok
+}
+
+// redactString masks s entirely when it is a resolved secret, and any
+// resolved secrets within it otherwise
+func redactString(s string) string {
+	if isResolvedSecret(s) {
+		return RedactedValue
+	}
+	return RedactSecrets(s)
+}
+
+// RedactedConfig returns a deep copy of AppConfig with resolved secrets and
+// every field tagged redact:"true" masked, whether set literally or through a
+// secret function. Use it whenever the configuration is logged or served over
+// the API.
+func RedactedConfig() Configuration {
+	var redacted Configuration
+
+	// JSON round-trip gives a deep copy without sharing maps or pointers
+	data, err := json.Marshal(AppConfig)
+	if err != nil {
+		return redacted
+	}
+	if err := json.Unmarshal(data, &redacted); err != nil {
+		return Configuration{}
+	}
+
+	redactStrings(reflect.ValueOf(&redacted).Elem())
+	return redacted
+}
+
+// redactStrings walks a value and masks secrets in every string it contains
+func redactStrings(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			redactStrings(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Field(i).CanSet() {
+				continue
+			}
+			if v.Type().Field(i).Tag.Get("redact") == "true" {
+				maskField(v.Field(i))
+				continue
+			}
+			redactStrings(v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			redactStrings(v.Index(i))
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			v.SetMapIndex(key, reflect.ValueOf(redactString(v.MapIndex(key).String())).Convert(v.Type().Elem()))
+		}
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(redactString(v.String()))
+		}
+	}
+}
+
+// maskField replaces every non-empty string in a sensitive field: a string,
+// the values of a map, or the elements of a slice
+func maskField(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			maskField(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			maskField(v.Index(i))
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			v.SetMapIndex(key, reflect.ValueOf(RedactedValue).Convert(v.Type().Elem()))
+		}
+	case reflect.String:
+		if v.String() != "" {
+			v.SetString(RedactedValue)
+		}
+	}
+}
+
+// ============================================================================
+// BUILT-IN PROVIDERS
+// ============================================================================
+
+// fileSecretProvider reads secrets from files (e.g., Docker/Kubernetes secret mounts)
+type fileSecretProvider struct{}
+
+func (p *fileSecretProvider) Name() string { return "file" }
+
+func (p *fileSecretProvider) Resolve(ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	// Secret files commonly end with a newline that is not part of the value
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// envSecretProvider reads secrets from environment variables
+type envSecretProvider struct{}
+
+func (p *envSecretProvider) Name() string { return "env" }
+
+func (p *envSecretProvider) Resolve(ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable is not set")
+	}
+	return value, nil
+}
+
+// ============================================================================
+// HCL FUNCTIONS
+// ============================================================================
+
+// secretFunctions exposes secret providers to HCL expressions:
+//
+//	file("/run/secrets/otlp_token")
+//	env("OTLP_TOKEN")
+//	secret("vault", "kv/otlp#token")
+func secretFunctions() map[string]function.Function {
+	return map[string]function.Function{
+		"file":   providerFunction("file", "path"),
+		"env":    providerFunction("env", "name"),
+		"secret": genericSecretFunction(),
+	}
+}
+
+// providerFunction builds a single-argument HCL function bound to one provider.
+// Its values are secrets only when they feed a redact:"true" field.
+func providerFunction(provider, paramName string) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: paramName, Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			value, err := resolveReference(provider, args[0].AsString())
+			if err != nil {
+				return cty.NilVal, err
+			}
+			recordReference(value)
+			return cty.StringVal(value), nil
+		},
+	})
+}
+
+// genericSecretFunction builds secret(provider, ref) for pluggable providers
+func genericSecretFunction() function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "provider", Type: cty.String},
+			{Name: "ref", Type: cty.String},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			value, err := ResolveSecret(args[0].AsString(), args[1].AsString())
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(value), nil
+		},
+	})
+}
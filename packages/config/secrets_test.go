@@ -0,0 +1,157 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useSecrets records values as resolved for the duration of a test
+func useSecrets(t *testing.T, values ...string) {
+	t.Helper()
+
+	resolvedSecretsMu.Lock()
+	saved, savedReferences := resolvedSecrets, referencedValues
+	resolvedSecrets, referencedValues = map[string]struct{}{}, map[string]struct{}{}
+	for _, v := range values {
+		resolvedSecrets[v] = struct{}{}
+	}
+	resolvedSecretsMu.Unlock()
+
+	t.Cleanup(func() {
+		resolvedSecretsMu.Lock()
+		resolvedSecrets, referencedValues = saved, savedReferences
+		resolvedSecretsMu.Unlock()
+	})
+}
+
+// TestRedactSecrets checks that long secrets are masked anywhere and short
+// ones only where they stand alone
+func TestRedactSecrets(t *testing.T) {
+	useSecrets(t, "s3cr3t-token-value", "4821", "pw")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"long secret", "token=s3cr3t-token-value", "token=[REDACTED]"},
+		{"long secret inside a word", "Bearers3cr3t-token-valueX", "Bearer[REDACTED]X"},
+		{"short secret alone", "pin 4821 rejected", "pin [REDACTED] rejected"},
+		{"short secret in key/value", "pin=4821, user=pw", "pin=[REDACTED], user=[REDACTED]"},
+		{"short secret at both ends", "4821", "[REDACTED]"},
+		{"short secret repeated", "4821 4821", "[REDACTED] [REDACTED]"},
+		{"short secret inside a number", "took 148213 ms", "took 148213 ms"},
+		{"short secret inside a word", "upward trend", "upward trend"},
+		{"no secret", "nothing to hide", "nothing to hide"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactSecrets(tt.in); got != tt.want {
+				t.Errorf("RedactSecrets(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSecretReferences checks which resolved values are treated as secrets:
+// those of secret() and of env() or file() in redact:"true" fields, but not
+// plain settings read with env() or file()
+func TestSecretReferences(t *testing.T) {
+	useSecrets(t)
+	t.Cleanup(func() { AppConfig = Configuration{} })
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token-value\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SEED_TEST_PORT", "9090")
+	t.Setenv("SEED_TEST_LEVEL", "info")
+	t.Setenv("SEED_TEST_TOKEN", "tk42")
+	t.Setenv("SEED_TEST_PIN", "4821")
+
+	path := filepath.Join(dir, "config.hcl")
+	err := os.WriteFile(path, []byte(`
+log_dir   = "`+dir+`"
+data_dir  = "`+dir+`/${secret("env", "SEED_TEST_PIN")}"
+log_level = env("SEED_TEST_LEVEL")
+
+server {
+  address = "127.0.0.1"
+  port    = env("SEED_TEST_PORT")
+}
+
+client {
+  token = env("SEED_TEST_TOKEN")
+}
+
+telemetry {
+  headers = {
+    "Authorization" = "Bearer ${file("`+tokenFile+`")}"
+  }
+}
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfigurationFile(path); err != nil {
+		t.Fatalf("LoadConfigurationFile() failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"port from env()", "listening on 0.0.0.0:9090", "listening on 0.0.0.0:9090"},
+		{"log level from env()", "level info", "level info"},
+		{"short env() in a redacted field", "token tk42 rejected", "token [REDACTED] rejected"},
+		{"interpolated file() in a redacted field", "sent file-token-value", "sent [REDACTED]"},
+		{"short secret()", "pin=4821", "pin=[REDACTED]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactSecrets(tt.in); got != tt.want {
+				t.Errorf("RedactSecrets(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	redacted := RedactedConfig()
+	if redacted.Server.ServerPort != "9090" || redacted.LogLevel != "info" {
+		t.Errorf("config show masks plain settings: port %q, log_level %q", redacted.Server.ServerPort, redacted.LogLevel)
+	}
+}
+
+// TestRedactedConfig checks that fields holding exactly a resolved value are
+// masked whatever its length, and that AppConfig is left untouched
+func TestRedactedConfig(t *testing.T) {
+	useSecrets(t, "42", "a-long-secret-value")
+	t.Cleanup(func() { AppConfig = Configuration{} })
+
+	AppConfig = Configuration{
+		DataDir: "/data/a-long-secret-value/x",
+		LogDir:  "42",
+		Server:  Server{ServerAddress: "10.0.0.42", ServerPort: "3001"},
+	}
+
+	redacted := RedactedConfig()
+	if redacted.LogDir != RedactedValue {
+		t.Errorf("LogDir = %q, want it masked", redacted.LogDir)
+	}
+	if redacted.DataDir != "/data/[REDACTED]/x" {
+		t.Errorf("DataDir = %q, want the long secret masked", redacted.DataDir)
+	}
+	if redacted.Server.ServerAddress != "10.0.0.[REDACTED]" {
+		t.Errorf("ServerAddress = %q, want the standalone short secret masked", redacted.Server.ServerAddress)
+	}
+	if redacted.Server.ServerPort != "3001" {
+		t.Errorf("ServerPort = %q, want it untouched", redacted.Server.ServerPort)
+	}
+	if AppConfig.LogDir != "42" {
+		t.Errorf("RedactedConfig() changed AppConfig")
+	}
+}
@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CORS lets browser front-ends on other origins call the API. Without a cors
+// block no CORS headers are sent and browsers block cross-origin reads.
+type CORS struct {
+	// AllowedOrigins are origins such as "https://app.example.com"; a
+	// "https://*.example.com" entry matches every subdomain, and "*" any origin
+	AllowedOrigins []string `hcl:"allowed_origins" json:"allowed_origins"`
+
+	// AllowedMethods are the methods preflight requests may ask for
+	// (default: GET, HEAD, POST, PUT, PATCH, DELETE)
+	AllowedMethods []string `hcl:"allowed_methods,optional" json:"allowed_methods,omitempty"`
+
+	// AllowedHeaders are the request headers preflight requests may ask for;
+	// "*" allows any (default: Authorization, Content-Type)
+	AllowedHeaders []string `hcl:"allowed_headers,optional" json:"allowed_headers,omitempty"`
+
+	// ExposedHeaders are response headers scripts may read besides the
+	// CORS-safelisted ones (default: Location, Retry-After and RateLimit-*)
+	ExposedHeaders []string `hcl:"exposed_headers,optional" json:"exposed_headers,omitempty"`
+
+	// AllowCredentials lets requests carry cookies and Authorization headers
+	// (default: false)
+	AllowCredentials bool `hcl:"allow_credentials,optional" json:"allow_credentials,omitempty"`
+
+	// MaxAgeSeconds is how long browsers cache a preflight response
+	// (default: 600)
+	MaxAgeSeconds int `hcl:"max_age_seconds,optional" json:"max_age_seconds,omitempty"`
+}
+
+// SecurityHeaders are added to every API response. Without a security_headers
+// block none are sent. Set a string to "" to leave its header out.
+type SecurityHeaders struct {
+	// HSTSMaxAgeSeconds sends Strict-Transport-Security when positive; only
+	// enable it when the service is reached over HTTPS (default: 0)
+	HSTSMaxAgeSeconds int `hcl:"hsts_max_age_seconds,optional" json:"hsts_max_age_seconds,omitempty"`
+
+	// HSTSIncludeSubdomains adds includeSubDomains to the HSTS header
+	HSTSIncludeSubdomains bool `hcl:"hsts_include_subdomains,optional" json:"hsts_include_subdomains,omitempty"`
+
+	// HSTSPreload adds preload to the HSTS header
+	HSTSPreload bool `hcl:"hsts_preload,optional" json:"hsts_preload,omitempty"`
+
+	// ContentTypeNosniff sends X-Content-Type-Options: nosniff (default: true)
+	ContentTypeNosniff *bool `hcl:"content_type_nosniff,optional" json:"content_type_nosniff,omitempty"`
+
+	// ContentSecurityPolicy is sent as Content-Security-Policy
+	// (default: "default-src 'none'; frame-ancestors 'none'")
+	ContentSecurityPolicy *string `hcl:"content_security_policy,optional" json:"content_security_policy,omitempty"`
+
+	// FrameOptions is sent as X-Frame-Options: DENY or SAMEORIGIN
+	// (default: DENY)
+	FrameOptions *string `hcl:"frame_options,optional" json:"frame_options,omitempty"`
+
+	// ReferrerPolicy is sent as Referrer-Policy (default: no-referrer)
+	ReferrerPolicy *string `hcl:"referrer_policy,optional" json:"referrer_policy,omitempty"`
+}
+
+// applyServerDefaults fills in CORS and security header settings when their
+// blocks are present
+func applyServerDefaults() {
+	if c := AppConfig.Server.CORS; c != nil {
+		if c.AllowedMethods == nil {
+			c.AllowedMethods = []string{
+				http.MethodGet, http.MethodHead, http.MethodPost,
+				http.MethodPut, http.MethodPatch, http.MethodDelete,
+			}
+		}
+		if c.AllowedHeaders == nil {
+			c.AllowedHeaders = []string{"Authorization", "Content-Type"}
+		}
+		if c.ExposedHeaders == nil {
+			c.ExposedHeaders = []string{
+				"Location", "Retry-After",
+				"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+			}
+		}
+		if c.MaxAgeSeconds == 0 {
+			c.MaxAgeSeconds = 600
+		}
+	}
+
+	if s := AppConfig.Server.SecurityHeaders; s != nil {
+		if s.ContentTypeNosniff == nil {
+			nosniff := true
+			s.ContentTypeNosniff = &nosniff
+		}
+		if s.ContentSecurityPolicy == nil {
+			csp := "default-src 'none'; frame-ancestors 'none'"
+			s.ContentSecurityPolicy = &csp
+		}
+		if s.FrameOptions == nil {
+			frameOptions := "DENY"
+			s.FrameOptions = &frameOptions
+		}
+		if s.ReferrerPolicy == nil {
+			referrerPolicy := "no-referrer"
+			s.ReferrerPolicy = &referrerPolicy
+		}
+	}
+}
+
+// validateServerHeaders checks the cors and security_headers blocks after
+// defaults are applied
+func validateServerHeaders() error {
+	if c := AppConfig.Server.CORS; c != nil {
+		if len(c.AllowedOrigins) == 0 {
+			return fmt.Errorf("server.cors.allowed_origins must list at least one origin")
+		}
+		for _, origin := range c.AllowedOrigins {
+			if origin == "*" {
+				if c.AllowCredentials {
+					return fmt.Errorf("server.cors: allowed_origins \"*\" cannot be combined with allow_credentials; list the origins")
+				}
+				continue
+			}
+			if err := checkOrigin(origin); err != nil {
+				return fmt.Errorf("server.cors.allowed_origins: %v", err)
+			}
+		}
+
+		for _, method := range c.AllowedMethods {
+			if method == "*" || !contains(httpMethods, method) {
+				return fmt.Errorf("server.cors.allowed_methods: unknown method %q (want an uppercase HTTP method)", method)
+			}
+		}
+		for _, header := range c.AllowedHeaders {
+			if header != "*" && !validHeaderName(header) {
+				return fmt.Errorf("server.cors.allowed_headers: invalid header name %q", header)
+			}
+		}
+		for _, header := range c.ExposedHeaders {
+			if !validHeaderName(header) {
+				return fmt.Errorf("server.cors.exposed_headers: invalid header name %q", header)
+			}
+		}
+		if c.MaxAgeSeconds < 0 {
+			return fmt.Errorf("server.cors.max_age_seconds must not be negative, got %d", c.MaxAgeSeconds)
+		}
+	}
+
+	if s := AppConfig.Server.SecurityHeaders; s != nil {
+		if s.HSTSMaxAgeSeconds < 0 {
+			return fmt.Errorf("server.security_headers.hsts_max_age_seconds must not be negative, got %d", s.HSTSMaxAgeSeconds)
+		}
+		if (s.HSTSIncludeSubdomains || s.HSTSPreload) && s.HSTSMaxAgeSeconds == 0 {
+			return fmt.Errorf("server.security_headers: hsts_include_subdomains and hsts_preload need hsts_max_age_seconds")
+		}
+		switch *s.FrameOptions {
+		case "", "DENY", "SAMEORIGIN":
+		default:
+			return fmt.Errorf("server.security_headers.frame_options must be DENY, SAMEORIGIN or empty, got %q", *s.FrameOptions)
+		}
+	}
+
+	return nil
+}
+
+// checkOrigin checks an origin is a scheme and host, optionally with a port
+// and a "*." subdomain wildcard, and nothing else
+func checkOrigin(origin string) error {
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil || u.Path != "" ||
+		u.RawQuery != "" || u.Fragment != "" || strings.Contains(u.Host, "*") {
+		return fmt.Errorf("origin %q must be scheme://host[:port], optionally with a *. subdomain wildcard", origin)
+	}
+	if origin != strings.ToLower(origin) {
+		return fmt.Errorf("origin %q must be lowercase", origin)
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is an HTTP header field name
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r) {
+			return false
+		}
+	}
+	return true
+}
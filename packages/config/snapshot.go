@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Snapshot configures where data_dir snapshots are kept and how often they are taken
+type Snapshot struct {
+	// Dir holds snapshot archives, relative to data_dir unless absolute
+	// (default: "snapshots"). Its contents are never included in snapshots.
+	Dir string `hcl:"dir,optional" json:"dir,omitempty"`
+
+	// IntervalSeconds schedules snapshots while the agent runs (default: 0,
+	// disabled). Shorthand for schedule "snapshot" { interval_seconds = N }.
+	IntervalSeconds int `hcl:"interval_seconds,optional" json:"interval_seconds,omitempty"`
+
+	// Retain is the number of archives kept in Dir; older ones are removed
+	// after each scheduled snapshot (default: 7, 0 keeps all)
+	Retain *int `hcl:"retain,optional" json:"retain,omitempty"`
+}
+
+// SnapshotDir returns the resolved snapshot directory
+func SnapshotDir() string {
+	dir := AppConfig.Snapshot.Dir
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(ResolvePath(AppConfig.DataDir), dir)
+}
+
+// applySnapshotDefaults keeps a week of daily snapshots under data_dir when scheduled
+func applySnapshotDefaults() {
+	if AppConfig.Snapshot == nil {
+		AppConfig.Snapshot = &Snapshot{}
+	}
+
+	s := AppConfig.Snapshot
+
+	if s.Dir == "" {
+		s.Dir = "snapshots"
+	}
+	if s.Retain == nil {
+		retain := 7
+		s.Retain = &retain
+	}
+}
+
+// validateSnapshot checks snapshot settings after defaults are applied
+func validateSnapshot() error {
+	s := AppConfig.Snapshot
+	if s == nil {
+		return nil
+	}
+
+	if s.IntervalSeconds < 0 {
+		return fmt.Errorf("snapshot.interval_seconds must not be negative, got %d", s.IntervalSeconds)
+	}
+	if s.Retain != nil && *s.Retain < 0 {
+		return fmt.Errorf("snapshot.retain must not be negative, got %d", *s.Retain)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Storage selects and configures the embedded key-value store
+type Storage struct {
+	// Engine is the storage engine: "bolt" (on disk) or "memory" (default: "bolt")
+	Engine string `hcl:"engine,optional" json:"engine,omitempty"`
+
+	// Path is the bolt database file, relative to data_dir unless absolute
+	// (default: "store.db")
+	Path string `hcl:"path,optional" json:"path,omitempty"`
+
+	// TimeoutSeconds bounds the wait for the database file lock (default: 5)
+	TimeoutSeconds int `hcl:"timeout_seconds,optional" json:"timeout_seconds,omitempty"`
+}
+
+// StoragePath returns the resolved database file path
+func StoragePath() string {
+	path := AppConfig.Storage.Path
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(ResolvePath(AppConfig.DataDir), path)
+}
+
+// applyStorageDefaults selects the on-disk engine under data_dir
+func applyStorageDefaults() {
+	if AppConfig.Storage == nil {
+		AppConfig.Storage = &Storage{}
+	}
+
+	s := AppConfig.Storage
+
+	if s.Engine == "" {
+		s.Engine = "bolt"
+	}
+	if s.Path == "" {
+		s.Path = "store.db"
+	}
+
+	// Default lock timeout is 5 seconds
+	if s.TimeoutSeconds == 0 {
+		s.TimeoutSeconds = 5
+	}
+}
+
+// validateStorage checks storage settings after defaults are applied
+func validateStorage() error {
+	s := AppConfig.Storage
+	if s == nil {
+		return nil
+	}
+
+	switch s.Engine {
+	case "bolt", "memory":
+	default:
+		return fmt.Errorf("storage.engine must be one of bolt or memory, got %q", s.Engine)
+	}
+
+	if s.TimeoutSeconds < 0 {
+		return fmt.Errorf("storage.timeout_seconds must not be negative, got %d", s.TimeoutSeconds)
+	}
+
+	return nil
+}
@@ -1,71 +1,73 @@
 package config
 
+import "fmt"
+
 // Telemetry holds telemetry export configuration
 type Telemetry struct {
 	// Shared config (inherited by metrics, logs, traces)
-	Endpoint string            `hcl:"endpoint,optional"`
-	TLS      *OTLPTLSConfig    `hcl:"tls,block"`
-	Headers  map[string]string `hcl:"headers,optional"`
+	Endpoint string            `hcl:"endpoint,optional" json:"endpoint,omitempty"`
+	TLS      *OTLPTLSConfig    `hcl:"tls,block" json:"tls,omitempty"`
+	Headers  map[string]string `hcl:"headers,optional" json:"headers,omitempty" redact:"true"`
 
 	// Signal-specific config
-	Metrics *OTLPMetricsConfig `hcl:"metrics,block"`
-	Logs    *OTLPLogsConfig    `hcl:"logs,block"`
-	Traces  *OTLPTracesConfig  `hcl:"traces,block"`
+	Metrics *OTLPMetricsConfig `hcl:"metrics,block" json:"metrics,omitempty"`
+	Logs    *OTLPLogsConfig    `hcl:"logs,block" json:"logs,omitempty"`
+	Traces  *OTLPTracesConfig  `hcl:"traces,block" json:"traces,omitempty"`
 }
 
 // OTLPMetricsConfig holds OTLP metrics exporter configuration
 type OTLPMetricsConfig struct {
 	// Enabled controls whether OTLP metric export is active (default: false)
-	Enabled bool `hcl:"enabled"`
+	Enabled bool `hcl:"enabled" json:"enabled"`
 
 	// Endpoint overrides the shared telemetry endpoint for metrics
-	Endpoint string `hcl:"endpoint,optional"`
+	Endpoint string `hcl:"endpoint,optional" json:"endpoint,omitempty"`
 
 	// Protocol specifies the transport protocol: "grpc" (default) or "http"
-	Protocol string `hcl:"protocol,optional"`
+	Protocol string `hcl:"protocol,optional" json:"protocol,omitempty"`
 
 	// IntervalSeconds is the export interval in seconds (default: 60)
-	IntervalSeconds int `hcl:"interval_seconds,optional"`
+	IntervalSeconds int `hcl:"interval_seconds,optional" json:"interval_seconds,omitempty"`
 }
 
 // OTLPLogsConfig holds OTLP logs exporter configuration
 type OTLPLogsConfig struct {
 	// Enabled controls whether OTLP log export is active (default: false)
-	Enabled bool `hcl:"enabled"`
+	Enabled bool `hcl:"enabled" json:"enabled"`
 
 	// Endpoint overrides the shared telemetry endpoint for logs
-	Endpoint string `hcl:"endpoint,optional"`
+	Endpoint string `hcl:"endpoint,optional" json:"endpoint,omitempty"`
 }
 
 // OTLPTracesConfig holds OTLP traces exporter configuration
 type OTLPTracesConfig struct {
 	// Enabled controls whether OTLP trace export is active (default: false)
-	Enabled bool `hcl:"enabled"`
+	Enabled bool `hcl:"enabled" json:"enabled"`
 
 	// Endpoint overrides the shared telemetry endpoint for traces
-	Endpoint string `hcl:"endpoint,optional"`
+	Endpoint string `hcl:"endpoint,optional" json:"endpoint,omitempty"`
 
 	// SamplingRate controls trace sampling (0.0-1.0, where 1.0 = 100%)
 	// Default: 1.0 (sample all traces)
-	SamplingRate float64 `hcl:"sampling_rate,optional"`
+	SamplingRate float64 `hcl:"sampling_rate,optional" json:"sampling_rate,omitempty"`
 }
 
 // OTLPTLSConfig holds TLS settings for OTLP export
 type OTLPTLSConfig struct {
 	// Enabled enables TLS for the connection
-	Enabled bool `hcl:"enabled"`
+	Enabled bool `hcl:"enabled" json:"enabled"`
 
 	// Insecure skips certificate verification (not recommended for production)
-	Insecure bool `hcl:"insecure,optional"`
+	Insecure bool `hcl:"insecure,optional" json:"insecure,omitempty"`
 
 	// CAFile is the path to CA certificate for server verification
-	CAFile string `hcl:"ca_file,optional"`
+	CAFile string `hcl:"ca_file,optional" json:"ca_file,omitempty"`
 
 	// CertFile is the path to client certificate (for mutual TLS)
-	CertFile string `hcl:"cert_file,optional"`
+	CertFile string `hcl:"cert_file,optional" json:"cert_file,omitempty"`
 
 	// KeyFile is the path to client key (for mutual TLS)
-	KeyFile string `hcl:"key_file,optional"`
+	KeyFile string `hcl:"key_file,optional" json:"key_file,omitempty" redact:"true"`
 }
 
 // applyTelemetryDefaults sets default values for telemetry configuration
@@ -115,3 +117,39 @@ func applyTelemetryDefaults() {
 		}
 	}
 }
+
+// validateTelemetry checks telemetry values after defaults are applied
+func validateTelemetry() error {
+	if AppConfig.Telemetry == nil {
+		return nil
+	}
+
+	t := AppConfig.Telemetry
+
+	if t.Metrics != nil {
+		if t.Metrics.Protocol != "grpc" && t.Metrics.Protocol != "http" {
+			return fmt.Errorf("telemetry.metrics.protocol must be \"grpc\" or \"http\", got %q", t.Metrics.Protocol)
+		}
+		if t.Metrics.IntervalSeconds < 0 {
+			return fmt.Errorf("telemetry.metrics.interval_seconds must be positive, got %d", t.Metrics.IntervalSeconds)
+		}
+		if t.Metrics.Enabled && t.Metrics.Endpoint == "" {
+			return fmt.Errorf("telemetry.metrics is enabled but no endpoint is configured")
+		}
+	}
+
+	if t.Logs != nil && t.Logs.Enabled && t.Logs.Endpoint == "" {
+		return fmt.Errorf("telemetry.logs is enabled but no endpoint is configured")
+	}
+
+	if t.Traces != nil && t.Traces.Enabled {
+		if t.Traces.Endpoint == "" {
+			return fmt.Errorf("telemetry.traces is enabled but no endpoint is configured")
+		}
+		if t.Traces.SamplingRate < 0 || t.Traces.SamplingRate > 1 {
+			return fmt.Errorf("telemetry.traces.sampling_rate must be between 0.0 and 1.0, got %.2f", t.Traces.SamplingRate)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Workers sizes the background worker pool
+type Workers struct {
+	// MaxWorkers is the number of tasks run concurrently (default: 10)
+	MaxWorkers int `hcl:"max_workers,optional" json:"max_workers,omitempty"`
+
+	// QueueSize is the number of tasks waiting for a worker; submissions
+	// beyond it are rejected (default: 100)
+	QueueSize int `hcl:"queue_size,optional" json:"queue_size,omitempty"`
+
+	// DrainTimeoutSeconds bounds how long shutdown waits for queued and
+	// running tasks before cancelling them (default: 30)
+	DrainTimeoutSeconds int `hcl:"drain_timeout_seconds,optional" json:"drain_timeout_seconds,omitempty"`
+}
+
+// applyWorkersDefaults sizes the pool for light background work
+func applyWorkersDefaults() {
+	if AppConfig.Workers == nil {
+		AppConfig.Workers = &Workers{}
+	}
+
+	w := AppConfig.Workers
+
+	if w.MaxWorkers == 0 {
+		w.MaxWorkers = 10
+	}
+	if w.QueueSize == 0 {
+		w.QueueSize = 100
+	}
+	if w.DrainTimeoutSeconds == 0 {
+		w.DrainTimeoutSeconds = 30
+	}
+}
+
+// validateWorkers checks worker pool settings after defaults are applied
+func validateWorkers() error {
+	w := AppConfig.Workers
+	if w == nil {
+		return nil
+	}
+
+	if w.MaxWorkers < 1 {
+		return fmt.Errorf("workers.max_workers must be positive, got %d", w.MaxWorkers)
+	}
+	if w.QueueSize < 1 {
+		return fmt.Errorf("workers.queue_size must be positive, got %d", w.QueueSize)
+	}
+	if w.DrainTimeoutSeconds < 1 {
+		return fmt.Errorf("workers.drain_timeout_seconds must be positive, got %d", w.DrainTimeoutSeconds)
+	}
+
+	return nil
+}
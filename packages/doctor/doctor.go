@@ -0,0 +1,331 @@
+package doctor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/bootstrap"
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// Check outcomes
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// DialTimeout bounds connectivity checks such as OTLP reachability
+var DialTimeout = 3 * time.Second
+
+// Check is the result of a single preflight check
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Summary counts checks by status
+type Summary struct {
+	Pass int `json:"pass"`
+	Warn int `json:"warn"`
+	Fail int `json:"fail"`
+}
+
+// Report is the outcome of a doctor run
+type Report struct {
+	Checks  []Check `json:"checks"`
+	Summary Summary `json:"summary"`
+	OK      bool    `json:"ok"`
+}
+
+func (r *Report) add(name, status, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+
+	switch status {
+	case StatusPass:
+		r.Summary.Pass++
+	case StatusWarn:
+		r.Summary.Warn++
+	case StatusFail:
+		r.Summary.Fail++
+	}
+	r.OK = r.Summary.Fail == 0
+}
+
+// Run loads the configuration and runs every preflight check against it.
+// Checks that depend on the configuration are skipped when it fails to load.
+func Run() Report {
+	report := Report{OK: true}
+
+	if err := config.LoadConfiguration(); err != nil {
+		report.add("config", StatusFail, "%v", err)
+		return report
+	}
+	profile := ""
+	if config.ActiveProfile != "" {
+		profile = fmt.Sprintf(" (profile: %s)", config.ActiveProfile)
+	}
+	report.add("config", StatusPass, "%s is valid%s", config.ConfigPath, profile)
+
+	cfg := config.AppConfig
+	checkDirectory(&report, "log_dir", cfg.LogDir, cfg.Filesystem)
+	checkDirectory(&report, "data_dir", cfg.DataDir, cfg.Filesystem)
+	checkInstanceLock(&report, cfg.DataDir)
+	checkDataVersion(&report, cfg.DataDir)
+	checkPort(&report, cfg.Server)
+	checkTLSFiles(&report, cfg)
+	checkOTLP(&report, cfg.Telemetry)
+
+	return report
+}
+
+// checkDirectory verifies a directory is writable with the configured mode and
+// ownership, or that the bootstrap phase can create it
+func checkDirectory(report *Report, name, dir string, fs *config.Filesystem) {
+	path := config.ResolvePath(dir)
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		parent := existingParent(path)
+		if err := bootstrap.CheckWritable(parent); err != nil {
+			report.add(name, StatusFail, "%s does not exist and cannot be created in %s: %v", path, parent, err)
+			return
+		}
+		report.add(name, StatusWarn, "%s does not exist (created at startup)", path)
+		return
+	}
+	if err != nil {
+		report.add(name, StatusFail, "%s: %v", path, err)
+		return
+	}
+	if !info.IsDir() {
+		report.add(name, StatusFail, "%s is not a directory", path)
+		return
+	}
+
+	if err := bootstrap.CheckWritable(path); err != nil {
+		report.add(name, StatusFail, "%s is not writable: %v", path, err)
+		return
+	}
+	if err := bootstrap.CheckOwnership(path, fs); err != nil {
+		report.add(name, StatusFail, "%s: %v", path, err)
+		return
+	}
+	if perm := info.Mode().Perm(); perm != fs.Mode() {
+		report.add(name, StatusWarn, "%s has mode %#o, expected %#o", path, perm, fs.Mode())
+		return
+	}
+	report.add(name, StatusPass, "%s is writable", path)
+}
+
+// existingParent returns the closest ancestor of path that exists
+func existingParent(path string) string {
+	for {
+		parent := filepath.Dir(path)
+		if _, err := os.Stat(parent); err == nil || parent == path {
+			return parent
+		}
+		path = parent
+	}
+}
+
+// checkInstanceLock verifies no live instance holds the data_dir lock
+func checkInstanceLock(report *Report, dataDir string) {
+	holder, err := bootstrap.LockHolder(config.ResolvePath(dataDir))
+	if err != nil {
+		report.add("instance_lock", StatusFail, "cannot inspect lock file: %v", err)
+		return
+	}
+	if holder != nil {
+		report.add("instance_lock", StatusFail, "data_dir is in use by another instance (%s)", holder)
+		return
+	}
+	report.add("instance_lock", StatusPass, "data_dir is not in use")
+}
+
+// checkDataVersion compares the data_dir layout version with this binary
+func checkDataVersion(report *Report, dataDir string) {
+	version, found, err := bootstrap.ReadDataVersion(config.ResolvePath(dataDir))
+	target := bootstrap.DataVersion()
+	switch {
+	case err != nil:
+		report.add("data_version", StatusFail, "%v", err)
+	case !found:
+		report.add("data_version", StatusPass, "no layout version recorded yet; will be set to %d on start", target)
+	case version > target:
+		report.add("data_version", StatusFail, "data_dir has layout version %d, newer than this binary supports (%d)", version, target)
+	case version < target:
+		report.add("data_version", StatusWarn, "data_dir has layout version %d; %d migration(s) will run on start", version, target-version)
+	default:
+		report.add("data_version", StatusPass, "data_dir layout version %d is current", version)
+	}
+}
+
+// checkPort verifies the server address can be bound
+func checkPort(report *Report, server config.Server) {
+	address := net.JoinHostPort(server.ServerAddress, server.ServerPort)
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		report.add("server_port", StatusFail, "cannot listen on %s: %v", address, err)
+		return
+	}
+	listener.Close()
+	report.add("server_port", StatusPass, "%s is available", address)
+}
+
+// checkTLSFiles verifies configured certificates and keys are readable and valid
+func checkTLSFiles(report *Report, cfg config.Configuration) {
+	checked := false
+
+	if cfg.Telemetry != nil && cfg.Telemetry.TLS != nil && cfg.Telemetry.TLS.Enabled {
+		t := cfg.Telemetry.TLS
+		checkTLS(report, "telemetry_tls", t.CAFile, t.CertFile, t.KeyFile)
+		checked = true
+	}
+	if cfg.Client != nil && cfg.Client.TLS != nil {
+		t := cfg.Client.TLS
+		if t.CAFile != "" || t.CertFile != "" || t.KeyFile != "" {
+			checkTLS(report, "client_tls", t.CAFile, t.CertFile, t.KeyFile)
+			checked = true
+		}
+	}
+
+	if !checked {
+		report.add("tls", StatusPass, "no TLS files configured")
+	}
+}
+
+func checkTLS(report *Report, name, caFile, certFile, keyFile string) {
+	var loaded []string
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			report.add(name, StatusFail, "cannot read CA file: %v", err)
+			return
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			report.add(name, StatusFail, "CA file %s contains no PEM certificates", caFile)
+			return
+		}
+		loaded = append(loaded, caFile)
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		report.add(name, StatusFail, "cert_file and key_file must be set together")
+		return
+	}
+	if certFile != "" {
+		if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+			report.add(name, StatusFail, "cannot load client certificate: %v", err)
+			return
+		}
+		loaded = append(loaded, certFile, keyFile)
+	}
+
+	if len(loaded) == 0 {
+		report.add(name, StatusPass, "TLS enabled with system CA pool")
+		return
+	}
+	report.add(name, StatusPass, "loaded %s", strings.Join(loaded, ", "))
+}
+
+// checkOTLP dials every enabled OTLP endpoint. Unreachable collectors are warnings
+// because the agent still starts and exporters retry in the background.
+func checkOTLP(report *Report, t *config.Telemetry) {
+	if t == nil {
+		report.add("otlp", StatusPass, "telemetry export disabled")
+		return
+	}
+
+	type signal struct {
+		name     string
+		enabled  bool
+		endpoint string
+		protocol string
+	}
+	var signals []signal
+	if t.Metrics != nil {
+		signals = append(signals, signal{"metrics", t.Metrics.Enabled, t.Metrics.Endpoint, t.Metrics.Protocol})
+	}
+	if t.Logs != nil {
+		signals = append(signals, signal{"logs", t.Logs.Enabled, t.Logs.Endpoint, "grpc"})
+	}
+	if t.Traces != nil {
+		signals = append(signals, signal{"traces", t.Traces.Enabled, t.Traces.Endpoint, "grpc"})
+	}
+
+	dialed := map[string]error{}
+	enabled := false
+	for _, s := range signals {
+		if !s.enabled {
+			continue
+		}
+		enabled = true
+		name := "otlp_" + s.name
+
+		address, err := dialAddress(s.endpoint, s.protocol)
+		if err != nil {
+			report.add(name, StatusFail, "%v", err)
+			continue
+		}
+
+		err, seen := dialed[address]
+		if !seen {
+			err = dial(address)
+			dialed[address] = err
+		}
+		if err != nil {
+			report.add(name, StatusWarn, "%s is unreachable: %v", address, err)
+			continue
+		}
+		report.add(name, StatusPass, "%s is reachable", address)
+	}
+
+	if !enabled {
+		report.add("otlp", StatusPass, "no telemetry signals enabled")
+	}
+}
+
+func dial(address string) error {
+	conn, err := net.DialTimeout("tcp", address, DialTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// dialAddress turns an OTLP endpoint (host:port or URL) into host:port, using
+// the default OTLP port for the protocol when none is given
+func dialAddress(endpoint, protocol string) (string, error) {
+	if endpoint == "" {
+		return "", fmt.Errorf("no endpoint configured")
+	}
+
+	host := endpoint
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return "", fmt.Errorf("invalid endpoint %q: %v", endpoint, err)
+		}
+		host = u.Host
+	}
+
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host, nil
+	}
+	port := "4317"
+	if protocol == "http" {
+		port = "4318"
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,373 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/stats"
+	"github.com/cloudputation/service-seed/packages/storage"
+)
+
+var (
+	// ErrNotFound is returned for unknown job ids
+	ErrNotFound = errors.New("job not found")
+
+	// ErrUnknownType is returned when enqueuing a type with no registered handler
+	ErrUnknownType = errors.New("unknown job type")
+
+	// ErrNotDead is returned when retrying a job that is not dead-lettered
+	ErrNotDead = errors.New("job is not dead")
+
+	// ErrNotRunning is returned when storage is not open (outside the agent)
+	ErrNotRunning = errors.New("job queue is not running")
+)
+
+// Status is the lifecycle state of a job
+type Status string
+
+const (
+	// StatusPending jobs wait for RunAt (new, delayed or retrying)
+	StatusPending Status = "pending"
+	// StatusRunning jobs are executing
+	StatusRunning Status = "running"
+	// StatusSucceeded jobs completed; removed after jobs.retention_hours
+	StatusSucceeded Status = "succeeded"
+	// StatusDead jobs failed every attempt or failed permanently; kept until retried
+	StatusDead Status = "dead"
+)
+
+// Job is a persisted unit of background work
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Status      Status          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	RunAt       time.Time       `json:"run_at"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+}
+
+// Options adjusts a single enqueued job
+type Options struct {
+	// MaxAttempts overrides jobs.max_attempts when positive
+	MaxAttempts int
+
+	// Delay postpones the first run
+	Delay time.Duration
+}
+
+// Handler runs a job. Returning an error schedules a retry with exponential
+// backoff until MaxAttempts is reached; wrap it with Permanent to dead-letter
+// the job immediately. ctx is cancelled when shutdown runs out of drain time,
+// and a job interrupted that way is requeued without using up an attempt.
+type Handler func(ctx context.Context, job *Job) error
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = map[string]Handler{}
+)
+
+// Register adds the handler for jobType; call from init()
+func Register(jobType string, handler Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	if jobType == "" {
+		panic("jobs: empty job type")
+	}
+	if _, exists := handlers[jobType]; exists {
+		panic(fmt.Sprintf("jobs: handler for %q registered twice", jobType))
+	}
+	handlers[jobType] = handler
+}
+
+// Type is a registered job type with a typed payload
+type Type[T any] struct {
+	name string
+}
+
+// RegisterFunc registers fn for jobType, decoding each job's payload into T,
+// and returns a Type for enqueuing with a payload of the same type. A payload
+// that does not decode dead-letters the job.
+func RegisterFunc[T any](jobType string, fn func(ctx context.Context, payload T) error) *Type[T] {
+	Register(jobType, func(ctx context.Context, job *Job) error {
+		var payload T
+		if len(job.Payload) > 0 {
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return Permanent(fmt.Errorf("invalid payload: %v", err))
+			}
+		}
+		return fn(ctx, payload)
+	})
+	return &Type[T]{name: jobType}
+}
+
+// Name returns the job type
+func (t *Type[T]) Name() string {
+	return t.name
+}
+
+// Enqueue persists a job of this type; see Enqueue
+func (t *Type[T]) Enqueue(ctx context.Context, payload T, opts *Options) (*Job, error) {
+	return Enqueue(ctx, t.name, payload, opts)
+}
+
+// Types returns the registered job types, sorted
+func Types() []string {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+
+	types := make([]string, 0, len(handlers))
+	for name := range handlers {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func handlerFor(jobType string) (Handler, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+
+	handler, ok := handlers[jobType]
+	return handler, ok
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job is dead-lettered without further retries
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Storage layout: the job record, and a pending index ordered by due time
+// that the runner scans for work
+const (
+	jobPrefix     = "jobs/"
+	pendingPrefix = "jobs-pending/"
+)
+
+func jobKey(id string) string {
+	return jobPrefix + id
+}
+
+func pendingKey(job *Job) string {
+	return fmt.Sprintf("%s%020d/%s", pendingPrefix, job.RunAt.UnixNano(), job.ID)
+}
+
+// parsePendingKey returns the due time and job id of a pending index key
+func parsePendingKey(key string) (time.Time, string, bool) {
+	due, id, ok := strings.Cut(strings.TrimPrefix(key, pendingPrefix), "/")
+	if !ok {
+		return time.Time{}, "", false
+	}
+	nanos, err := strconv.ParseInt(due, 10, 64)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return time.Unix(0, nanos), id, true
+}
+
+func readJob(tx storage.Txn, id string) (*Job, error) {
+	data, err := tx.Get(jobKey(id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeJob(id, data)
+}
+
+func decodeJob(id string, data []byte) (*Job, error) {
+	job := &Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, fmt.Errorf("corrupt job %s: %v", id, err)
+	}
+	return job, nil
+}
+
+func writeJob(tx storage.Txn, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return tx.Put(jobKey(job.ID), data)
+}
+
+// schedule marks job pending at runAt and indexes it for the runner
+func schedule(tx storage.Txn, job *Job, runAt time.Time) error {
+	job.Status = StatusPending
+	job.RunAt = runAt
+	job.UpdatedAt = time.Now().UTC()
+	if err := writeJob(tx, job); err != nil {
+		return err
+	}
+	return tx.Put(pendingKey(job), []byte(job.ID))
+}
+
+func store() (storage.Store, error) {
+	if storage.DefaultStore == nil {
+		return nil, ErrNotRunning
+	}
+	return storage.DefaultStore, nil
+}
+
+// Enqueue persists a job for the handler registered as jobType. payload is
+// encoded as JSON (a json.RawMessage is stored as is). opts may be nil.
+func Enqueue(ctx context.Context, jobType string, payload interface{}, opts *Options) (*Job, error) {
+	if _, ok := handlerFor(jobType); !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownType, jobType)
+	}
+	s, err := store()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode payload: %v", err)
+	}
+
+	now := time.Now().UTC()
+	job := &Job{
+		ID:          newJobID(),
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: config.AppConfig.Jobs.MaxAttempts,
+		CreatedAt:   now,
+	}
+	runAt := now
+	if opts != nil {
+		if opts.MaxAttempts > 0 {
+			job.MaxAttempts = opts.MaxAttempts
+		}
+		if opts.Delay > 0 {
+			runAt = now.Add(opts.Delay)
+		}
+	}
+
+	err = s.Update(ctx, func(tx storage.Txn) error {
+		return schedule(tx, job, runAt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to persist job: %v", err)
+	}
+
+	stats.RecordJobEnqueued(ctx, jobType)
+	wake()
+	return job, nil
+}
+
+// Get returns the job with the given id
+func Get(ctx context.Context, id string) (*Job, error) {
+	s, err := store()
+	if err != nil {
+		return nil, err
+	}
+
+	var job *Job
+	err = s.View(ctx, func(tx storage.Txn) error {
+		job, err = readJob(tx, id)
+		return err
+	})
+	return job, err
+}
+
+// List returns jobs in the given status (all jobs when empty), oldest first.
+// List(ctx, StatusDead) is the dead-letter list.
+func List(ctx context.Context, status Status) ([]*Job, error) {
+	s, err := store()
+	if err != nil {
+		return nil, err
+	}
+
+	kvs, err := s.List(ctx, jobPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*Job{}
+	for _, kv := range kvs {
+		job, err := decodeJob(strings.TrimPrefix(kv.Key, jobPrefix), kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		if status == "" || job.Status == status {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// Retry moves a dead job back to the queue with a fresh set of attempts
+func Retry(ctx context.Context, id string) (*Job, error) {
+	s, err := store()
+	if err != nil {
+		return nil, err
+	}
+
+	var job *Job
+	err = s.Update(ctx, func(tx storage.Txn) error {
+		job, err = readJob(tx, id)
+		if err != nil {
+			return err
+		}
+		if job.Status != StatusDead {
+			return fmt.Errorf("%w: status is %s", ErrNotDead, job.Status)
+		}
+
+		job.Attempts = 0
+		job.FinishedAt = nil
+		return schedule(tx, job, time.Now().UTC())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	wake()
+	return job, nil
+}
+
+// ValidStatus reports whether s names a job status
+func ValidStatus(s Status) bool {
+	switch s {
+	case StatusPending, StatusRunning, StatusSucceeded, StatusDead:
+		return true
+	}
+	return false
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,315 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/storage"
+)
+
+// testType is the job type enqueued by the tests; claim and finish are driven
+// directly, so its handler never runs
+const testType = "test"
+
+func init() {
+	Register(testType, func(ctx context.Context, job *Job) error { return nil })
+}
+
+// setupQueue points the queue at an empty in-memory store
+func setupQueue(t *testing.T) *storage.Memory {
+	t.Helper()
+
+	savedStore, savedJobs := storage.DefaultStore, config.AppConfig.Jobs
+	t.Cleanup(func() {
+		storage.DefaultStore = savedStore
+		config.AppConfig.Jobs = savedJobs
+	})
+
+	s := storage.NewMemory()
+	storage.DefaultStore = s
+	config.AppConfig.Jobs = &config.Jobs{
+		Concurrency:       1,
+		MaxAttempts:       3,
+		BackoffSeconds:    1,
+		MaxBackoffSeconds: 10,
+		RetentionHours:    1,
+	}
+	return s
+}
+
+func enqueue(t *testing.T, opts *Options) *Job {
+	t.Helper()
+	job, err := Enqueue(context.Background(), testType, map[string]string{"k": "v"}, opts)
+	if err != nil {
+		t.Fatalf("Enqueue() failed: %v", err)
+	}
+	return job
+}
+
+func get(t *testing.T, id string) *Job {
+	t.Helper()
+	job, err := Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get(%s) failed: %v", id, err)
+	}
+	return job
+}
+
+// pendingIDs lists the job ids in the pending index, in index order
+func pendingIDs(t *testing.T, s storage.Store) []string {
+	t.Helper()
+	kvs, err := s.List(context.Background(), pendingPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, kv := range kvs {
+		ids = append(ids, string(kv.Value))
+	}
+	return ids
+}
+
+func claimIDs(t *testing.T, limit int) []string {
+	t.Helper()
+	claimed, err := claim(context.Background(), limit)
+	if err != nil {
+		t.Fatalf("claim() failed: %v", err)
+	}
+	var ids []string
+	for _, job := range claimed {
+		if job.Status != StatusRunning {
+			t.Errorf("claimed job %s is %s, want running", job.ID, job.Status)
+		}
+		ids = append(ids, job.ID)
+	}
+	return ids
+}
+
+// TestClaim checks that due jobs are claimed oldest due first up to the
+// limit, that jobs not yet due stop the scan, and that index entries without
+// a matching pending job are dropped
+func TestClaim(t *testing.T) {
+	s := setupQueue(t)
+	ctx := context.Background()
+
+	first := enqueue(t, nil)
+	delayed := enqueue(t, &Options{Delay: time.Hour})
+	second := enqueue(t, nil)
+
+	// A job rescheduled earlier after its first index entry was written, an
+	// entry for a job that no longer exists and an unparsable key
+	moved := enqueue(t, nil)
+	err := s.Update(ctx, func(tx storage.Txn) error {
+		job, err := readJob(tx, moved.ID)
+		if err != nil {
+			return err
+		}
+		if err := schedule(tx, job, job.RunAt.Add(-time.Hour)); err != nil {
+			return err
+		}
+		if err := tx.Put(pendingKey(&Job{ID: "missing", RunAt: job.RunAt}), []byte("missing")); err != nil {
+			return err
+		}
+		return tx.Put(pendingPrefix+"garbage", []byte("garbage"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := claimIDs(t, 1); len(got) != 1 || got[0] != moved.ID {
+		t.Fatalf("claim(1) = %v, want [%s]", got, moved.ID)
+	}
+	got := claimIDs(t, 10)
+	if len(got) != 2 || got[0] != first.ID || got[1] != second.ID {
+		t.Fatalf("claim(10) = %v, want [%s %s]", got, first.ID, second.ID)
+	}
+	if job := get(t, moved.ID); job.Attempts != 1 {
+		t.Errorf("rescheduled job has %d attempts, want 1: its stale index entry was claimed", job.Attempts)
+	}
+	if got := claimIDs(t, 10); len(got) != 0 {
+		t.Fatalf("claim() with nothing due = %v", got)
+	}
+
+	if ids := pendingIDs(t, s); len(ids) != 2 || ids[0] != delayed.ID || ids[1] != "garbage" {
+		t.Errorf("pending index = %v, want the delayed job and the garbage key past it", ids)
+	}
+	if job := get(t, delayed.ID); job.Status != StatusPending || job.Attempts != 0 {
+		t.Errorf("delayed job is %s with %d attempts, want pending with 0", job.Status, job.Attempts)
+	}
+}
+
+// TestFinish checks the transitions out of running
+func TestFinish(t *testing.T) {
+	tests := []struct {
+		name        string
+		attempts    int
+		runErr      error
+		interrupted bool
+		wantStatus  string
+		wantJob     Status
+		wantAtts    int
+		wantPending bool
+		wantError   string
+	}{
+		{"succeeded", 1, nil, false, "succeeded", StatusSucceeded, 1, false, ""},
+		{"retry", 1, errors.New("boom"), false, "retry", StatusPending, 1, true, "boom"},
+		{"last attempt", 3, errors.New("boom"), false, "dead", StatusDead, 3, false, "boom"},
+		{"permanent", 1, Permanent(errors.New("bad payload")), false, "dead", StatusDead, 1, false, "bad payload"},
+		{"interrupted", 3, context.Canceled, true, "canceled", StatusPending, 2, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := setupQueue(t)
+			enqueue(t, nil)
+
+			claimed, err := claim(context.Background(), 1)
+			if err != nil || len(claimed) != 1 {
+				t.Fatalf("claim() = %v, %v", claimed, err)
+			}
+			job := claimed[0]
+			job.Attempts = tt.attempts
+
+			start := time.Now()
+			status, err := finish(job, tt.runErr, tt.interrupted)
+			if err != nil {
+				t.Fatalf("finish() failed: %v", err)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("finish() = %s, want %s", status, tt.wantStatus)
+			}
+
+			stored := get(t, job.ID)
+			if stored.Status != tt.wantJob || stored.Attempts != tt.wantAtts || stored.LastError != tt.wantError {
+				t.Errorf("job is %s with %d attempts and error %q, want %s with %d and %q",
+					stored.Status, stored.Attempts, stored.LastError, tt.wantJob, tt.wantAtts, tt.wantError)
+			}
+			if pending := len(pendingIDs(t, s)) == 1; pending != tt.wantPending {
+				t.Errorf("job indexed as pending = %v, want %v", pending, tt.wantPending)
+			}
+			if (stored.FinishedAt != nil) != (stored.Status == StatusSucceeded || stored.Status == StatusDead) {
+				t.Errorf("finished_at = %v for a %s job", stored.FinishedAt, stored.Status)
+			}
+			if tt.wantStatus == "retry" && stored.RunAt.Before(start.Add(time.Second)) {
+				t.Errorf("retry scheduled at %s, want at least the base backoff after %s", stored.RunAt, start)
+			}
+		})
+	}
+}
+
+// TestBackoff checks doubling, the cap and the jitter bound
+func TestBackoff(t *testing.T) {
+	setupQueue(t)
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			got := backoff(tt.attempt)
+			if got < tt.base || got > tt.base+tt.base/10 {
+				t.Fatalf("backoff(%d) = %s, want %s plus up to 10%%", tt.attempt, got, tt.base)
+			}
+		}
+	}
+}
+
+// TestRecoverInterrupted checks that running jobs are requeued, or
+// dead-lettered when the interrupted run was their last attempt
+func TestRecoverInterrupted(t *testing.T) {
+	s := setupQueue(t)
+
+	requeue := enqueue(t, nil)
+	exhausted := enqueue(t, nil)
+	untouched := enqueue(t, &Options{Delay: time.Hour})
+	if got := claimIDs(t, 2); len(got) != 2 {
+		t.Fatalf("claim() = %v", got)
+	}
+	err := s.Update(context.Background(), func(tx storage.Txn) error {
+		job, err := readJob(tx, exhausted.ID)
+		if err != nil {
+			return err
+		}
+		job.Attempts = job.MaxAttempts
+		return writeJob(tx, job)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requeued, dead, err := recoverInterrupted(s)
+	if err != nil {
+		t.Fatalf("recoverInterrupted() failed: %v", err)
+	}
+	if requeued != 1 || dead != 1 {
+		t.Errorf("recoverInterrupted() = %d requeued, %d dead, want 1 and 1", requeued, dead)
+	}
+
+	if job := get(t, requeue.ID); job.Status != StatusPending || job.Attempts != 1 || job.LastError != "interrupted by shutdown" {
+		t.Errorf("interrupted job is %s with %d attempts and error %q, want pending with 1", job.Status, job.Attempts, job.LastError)
+	}
+	if job := get(t, exhausted.ID); job.Status != StatusDead || job.FinishedAt == nil {
+		t.Errorf("exhausted job is %s, want dead", job.Status)
+	}
+	if job := get(t, untouched.ID); job.Status != StatusPending || job.LastError != "" {
+		t.Errorf("pending job was changed: %s, %q", job.Status, job.LastError)
+	}
+	if got := claimIDs(t, 10); len(got) != 1 || got[0] != requeue.ID {
+		t.Errorf("claim() after recovery = %v, want [%s]", got, requeue.ID)
+	}
+}
+
+// TestRetry checks that only dead jobs are requeued, with fresh attempts
+func TestRetry(t *testing.T) {
+	setupQueue(t)
+	ctx := context.Background()
+
+	job := enqueue(t, nil)
+	if _, err := Retry(ctx, job.ID); !errors.Is(err, ErrNotDead) {
+		t.Fatalf("Retry() of a pending job error = %v, want ErrNotDead", err)
+	}
+	if _, err := Retry(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Retry() of an unknown job error = %v, want ErrNotFound", err)
+	}
+
+	claimed, err := claim(ctx, 1)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("claim() = %v, %v", claimed, err)
+	}
+	if _, err := finish(claimed[0], Permanent(errors.New("bad payload")), false); err != nil {
+		t.Fatal(err)
+	}
+
+	retried, err := Retry(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Retry() failed: %v", err)
+	}
+	if retried.Status != StatusPending || retried.Attempts != 0 || retried.FinishedAt != nil {
+		t.Errorf("retried job is %s with %d attempts, want pending with 0", retried.Status, retried.Attempts)
+	}
+	if got := claimIDs(t, 1); len(got) != 1 || got[0] != job.ID {
+		t.Errorf("claim() after retry = %v, want [%s]", got, job.ID)
+	}
+}
+
+// TestEnqueueUnknownType checks that jobs without a handler are refused
+func TestEnqueueUnknownType(t *testing.T) {
+	setupQueue(t)
+
+	_, err := Enqueue(context.Background(), "unknown", nil, nil)
+	if !errors.Is(err, ErrUnknownType) || !strings.Contains(err.Error(), "unknown") {
+		t.Fatalf("Enqueue() error = %v, want ErrUnknownType", err)
+	}
+}
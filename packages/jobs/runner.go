@@ -0,0 +1,416 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cloudputation/service-seed/packages/config"
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/stats"
+	"github.com/cloudputation/service-seed/packages/storage"
+	"github.com/cloudputation/service-seed/packages/workers"
+)
+
+// cleanupInterval is how often expired succeeded jobs are removed
+const cleanupInterval = time.Hour
+
+// runner claims due jobs and runs them on a dedicated worker pool
+type runner struct {
+	pool *workers.Pool
+
+	// slots holds one token per running job; only the loop acquires them
+	slots chan struct{}
+	wake  chan struct{}
+
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// current is the runner started by InitJobs
+var current atomic.Pointer[runner]
+
+// wake prompts the runner to look for due jobs without waiting for the poll
+func wake() {
+	if r := current.Load(); r != nil {
+		r.signal()
+	}
+}
+
+func (r *runner) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// InitJobs requeues jobs interrupted by the last shutdown and starts running
+// due jobs. Runs after storage is open.
+func InitJobs() error {
+	s, err := store()
+	if err != nil {
+		return err
+	}
+
+	requeued, dead, err := recoverInterrupted(s)
+	if err != nil {
+		return fmt.Errorf("Failed to recover interrupted jobs: %v", err)
+	}
+	if requeued > 0 || dead > 0 {
+		log.Warn(fmt.Sprintf("Recovered jobs interrupted by the last shutdown (requeued: %d, dead: %d)", requeued, dead))
+	}
+
+	cfg := config.AppConfig.Jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &runner{
+		pool:  workers.New("jobs", cfg.Concurrency, cfg.Concurrency),
+		slots: make(chan struct{}, cfg.Concurrency),
+		wake:  make(chan struct{}, 1),
+		stop:  cancel,
+		done:  make(chan struct{}),
+	}
+	current.Store(r)
+	go r.loop(ctx)
+
+	return nil
+}
+
+// ShutdownJobs stops claiming jobs and waits up to workers.drain_timeout_seconds
+// for running ones. Jobs still running are cancelled and requeued. Safe to
+// call when InitJobs never ran.
+func ShutdownJobs() error {
+	r := current.Swap(nil)
+	if r == nil {
+		return nil
+	}
+
+	r.stop()
+	<-r.done
+
+	timeout := time.Duration(config.AppConfig.Workers.DrainTimeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return r.pool.Shutdown(ctx)
+}
+
+func (r *runner) loop(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(time.Duration(config.AppConfig.Jobs.PollIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		r.dispatch(ctx)
+
+		if time.Since(lastCleanup) >= cleanupInterval {
+			if removed, err := cleanup(ctx); err != nil {
+				log.Error(fmt.Sprintf("Failed to remove expired jobs: %v", err))
+			} else if removed > 0 {
+				log.Info(fmt.Sprintf("Removed %d succeeded jobs past retention", removed))
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// dispatch claims as many due jobs as there are free slots and submits them
+func (r *runner) dispatch(ctx context.Context) {
+	free := cap(r.slots) - len(r.slots)
+	if free == 0 {
+		return
+	}
+
+	claimed, err := claim(ctx, free)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error(fmt.Sprintf("Failed to claim jobs: %v", err))
+		}
+		return
+	}
+
+	for _, job := range claimed {
+		r.slots <- struct{}{}
+		err := r.pool.Submit(context.Background(), job.Type, func(ctx context.Context) error {
+			defer r.release()
+			execute(ctx, job)
+			return nil
+		})
+		if err != nil {
+			// Left running; requeued by recoverInterrupted on the next start
+			log.Error(fmt.Sprintf("Failed to start job %s: %v", job.ID, err))
+			r.release()
+		}
+	}
+}
+
+// release frees a slot and looks for more work
+func (r *runner) release() {
+	<-r.slots
+	r.signal()
+}
+
+// claim marks up to limit due jobs running, oldest due first
+func claim(ctx context.Context, limit int) ([]*Job, error) {
+	s, err := store()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var claimed []*Job
+	err = s.Update(ctx, func(tx storage.Txn) error {
+		pending, err := tx.List(pendingPrefix)
+		if err != nil {
+			return err
+		}
+
+		for _, kv := range pending {
+			if len(claimed) == limit {
+				break
+			}
+			due, id, ok := parsePendingKey(kv.Key)
+			if ok && due.After(now) {
+				break
+			}
+			if err := tx.Delete(kv.Key); err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+
+			job, err := readJob(tx, id)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			// Skip index entries left behind by a reschedule
+			if job.Status != StatusPending || !job.RunAt.Equal(due) {
+				continue
+			}
+
+			job.Status = StatusRunning
+			job.Attempts++
+			job.UpdatedAt = now.UTC()
+			if err := writeJob(tx, job); err != nil {
+				return err
+			}
+			claimed = append(claimed, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// execute runs one claimed job and records its outcome
+func execute(ctx context.Context, job *Job) {
+	var span trace.Span
+	if stats.Tracer != nil {
+		ctx, span = stats.Tracer.Start(ctx, "job.execute", trace.WithAttributes(
+			attribute.String("job.id", job.ID),
+			attribute.String("job.type", job.Type),
+			attribute.Int("job.attempt", job.Attempts),
+		))
+		defer span.End()
+	}
+
+	delay := time.Since(job.RunAt)
+	timer := stats.NewTimer()
+	runErr := run(ctx, job)
+	duration := timer.Elapsed()
+
+	status, err := finish(job, runErr, ctx.Err() != nil)
+	if err != nil {
+		// Left running; requeued by recoverInterrupted on the next start
+		log.Error(fmt.Sprintf("Failed to record result of job %s: %v", job.ID, err))
+	}
+	stats.RecordJobExecution(ctx, job.Type, status, delay, duration)
+
+	switch status {
+	case "succeeded":
+		log.Debug(fmt.Sprintf("Job %s (%s) succeeded", job.ID, job.Type))
+	case "retry":
+		log.Warn(fmt.Sprintf("Job %s (%s) failed attempt %d/%d, retrying at %s: %v",
+			job.ID, job.Type, job.Attempts, job.MaxAttempts, job.RunAt.Format(time.RFC3339), runErr))
+	case "dead":
+		log.Error(fmt.Sprintf("Job %s (%s) moved to dead letters after %d attempts: %v", job.ID, job.Type, job.Attempts, runErr))
+	case "canceled":
+		log.Warn(fmt.Sprintf("Job %s (%s) interrupted by shutdown and requeued", job.ID, job.Type))
+	}
+
+	if span != nil {
+		span.SetAttributes(attribute.String("job.status", status))
+		if runErr != nil {
+			span.RecordError(runErr)
+			span.SetStatus(codes.Error, runErr.Error())
+		}
+	}
+}
+
+// run calls the job's handler, turning a panic into an error
+func run(ctx context.Context, job *Job) (err error) {
+	handler, ok := handlerFor(job.Type)
+	if !ok {
+		return Permanent(fmt.Errorf("no handler registered for job type %q", job.Type))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// finish persists the outcome of a run and returns the execution status
+func finish(job *Job, runErr error, interrupted bool) (string, error) {
+	s, err := store()
+	if err != nil {
+		return "", err
+	}
+
+	var permanent *permanentError
+	now := time.Now().UTC()
+	status := ""
+
+	err = s.Update(context.Background(), func(tx storage.Txn) error {
+		switch {
+		case runErr == nil:
+			status = "succeeded"
+			job.Status = StatusSucceeded
+			job.LastError = ""
+			job.FinishedAt = &now
+			job.UpdatedAt = now
+			return writeJob(tx, job)
+		case interrupted:
+			status = "canceled"
+			job.Attempts--
+			return schedule(tx, job, now)
+		case errors.As(runErr, &permanent) || job.Attempts >= job.MaxAttempts:
+			status = "dead"
+			job.Status = StatusDead
+			job.LastError = runErr.Error()
+			job.FinishedAt = &now
+			job.UpdatedAt = now
+			return writeJob(tx, job)
+		default:
+			status = "retry"
+			job.LastError = runErr.Error()
+			return schedule(tx, job, now.Add(backoff(job.Attempts)))
+		}
+	})
+	return status, err
+}
+
+// backoff returns the delay before the retry following attempt: the base
+// delay doubled per earlier attempt, capped, plus up to 10% jitter so jobs
+// that failed together do not retry together
+func backoff(attempt int) time.Duration {
+	cfg := config.AppConfig.Jobs
+	delay := time.Duration(cfg.BackoffSeconds) * time.Second
+	limit := time.Duration(cfg.MaxBackoffSeconds) * time.Second
+
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay + rand.N(delay/10+1)
+}
+
+// recoverInterrupted requeues jobs left running by a crash or a shutdown that
+// ran out of time. The interrupted run counts as an attempt, so a job that
+// keeps taking the process down ends up dead.
+func recoverInterrupted(s storage.Store) (int, int, error) {
+	requeued, dead := 0, 0
+	err := s.Update(context.Background(), func(tx storage.Txn) error {
+		kvs, err := tx.List(jobPrefix)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, kv := range kvs {
+			job, err := decodeJob(strings.TrimPrefix(kv.Key, jobPrefix), kv.Value)
+			if err != nil {
+				return err
+			}
+			if job.Status != StatusRunning {
+				continue
+			}
+
+			job.LastError = "interrupted by shutdown"
+			if job.Attempts >= job.MaxAttempts {
+				job.Status = StatusDead
+				job.FinishedAt = &now
+				job.UpdatedAt = now
+				if err := writeJob(tx, job); err != nil {
+					return err
+				}
+				dead++
+				continue
+			}
+			if err := schedule(tx, job, now); err != nil {
+				return err
+			}
+			requeued++
+		}
+		return nil
+	})
+	return requeued, dead, err
+}
+
+// cleanup removes succeeded jobs older than jobs.retention_hours
+func cleanup(ctx context.Context) (int, error) {
+	s, err := store()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-time.Duration(config.AppConfig.Jobs.RetentionHours) * time.Hour)
+	removed := 0
+	err = s.Update(ctx, func(tx storage.Txn) error {
+		kvs, err := tx.List(jobPrefix)
+		if err != nil {
+			return err
+		}
+
+		for _, kv := range kvs {
+			job, err := decodeJob(strings.TrimPrefix(kv.Key, jobPrefix), kv.Value)
+			if err != nil {
+				return err
+			}
+			if job.Status == StatusSucceeded && job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+				if err := tx.Delete(kv.Key); err != nil {
+					return err
+				}
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed, err
+}
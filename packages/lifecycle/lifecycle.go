@@ -0,0 +1,241 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Components registered by the agent runtime, for use as dependencies
+const (
+	Filesystem = "filesystem"
+	Logger     = "logger"
+	Audit      = "audit"
+	Metrics    = "metrics"
+	Traces     = "traces"
+	Storage    = "storage"
+	Workers    = "workers"
+	Jobs       = "jobs"
+	Scheduler  = "scheduler"
+)
+
+// Component is a subsystem with a start and stop step. A Start that fails
+// must release whatever it acquired; only started components are stopped.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Hook adapts a pair of functions to Component; either may be nil
+type Hook struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Start calls OnStart when set
+func (h Hook) Start(ctx context.Context) error {
+	if h.OnStart == nil {
+		return nil
+	}
+	return h.OnStart(ctx)
+}
+
+// Stop calls OnStop when set
+func (h Hook) Stop(ctx context.Context) error {
+	if h.OnStop == nil {
+		return nil
+	}
+	return h.OnStop(ctx)
+}
+
+type entry struct {
+	name      string
+	component Component
+	dependsOn []string
+}
+
+// Manager starts components after their dependencies and stops them in
+// reverse order
+type Manager struct {
+	// OnStopError is called for each component that fails to stop, during
+	// Stop or a rollback, before the next one is stopped. It must not call
+	// back into the Manager.
+	OnStopError func(name string, err error)
+
+	mu         sync.Mutex
+	components []*entry
+	byName     map[string]*entry
+	started    []*entry
+}
+
+// New returns an empty Manager
+func New() *Manager {
+	return &Manager{byName: map[string]*entry{}}
+}
+
+// Register adds a component that starts after the components it depends on.
+// Panics on a duplicate name or when the manager is running.
+func (m *Manager) Register(name string, c Component, dependsOn ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if name == "" {
+		panic("lifecycle: empty component name")
+	}
+	if _, exists := m.byName[name]; exists {
+		panic(fmt.Sprintf("lifecycle: component %q registered twice", name))
+	}
+	if len(m.started) > 0 {
+		panic(fmt.Sprintf("lifecycle: component %q registered after Start", name))
+	}
+
+	e := &entry{name: name, component: c, dependsOn: dependsOn}
+	m.components = append(m.components, e)
+	m.byName[name] = e
+}
+
+// Order returns component names in start order: dependencies first, then
+// registration order. Fails on unknown dependencies and cycles.
+func (m *Manager) Order() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, err := m.resolve()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(order))
+	for i, e := range order {
+		names[i] = e.name
+	}
+	return names, nil
+}
+
+// resolve sorts components topologically, taking the earliest registered
+// component whose dependencies have all been placed at each step
+func (m *Manager) resolve() ([]*entry, error) {
+	for _, e := range m.components {
+		for _, dep := range e.dependsOn {
+			if _, ok := m.byName[dep]; !ok {
+				return nil, fmt.Errorf("component %s depends on unknown component %s", e.name, dep)
+			}
+		}
+	}
+
+	placed := map[string]bool{}
+	order := make([]*entry, 0, len(m.components))
+	for len(order) < len(m.components) {
+		progress := false
+		for _, e := range m.components {
+			if placed[e.name] || !allPlaced(e.dependsOn, placed) {
+				continue
+			}
+			placed[e.name] = true
+			order = append(order, e)
+			progress = true
+			break
+		}
+
+		if !progress {
+			var remaining []string
+			for _, e := range m.components {
+				if !placed[e.name] {
+					remaining = append(remaining, e.name)
+				}
+			}
+			sort.Strings(remaining)
+			return nil, fmt.Errorf("dependency cycle among components %s", strings.Join(remaining, ", "))
+		}
+	}
+	return order, nil
+}
+
+func allPlaced(names []string, placed map[string]bool) bool {
+	for _, name := range names {
+		if !placed[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// Start starts every component in dependency order. When one fails, the
+// components already started are stopped in reverse order (failures go to
+// OnStopError) and the start error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.started) > 0 {
+		return errors.New("components are already started")
+	}
+
+	order, err := m.resolve()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range order {
+		if err := ctx.Err(); err != nil {
+			m.stop(context.WithoutCancel(ctx))
+			return err
+		}
+		if err := e.component.Start(ctx); err != nil {
+			m.stop(context.WithoutCancel(ctx))
+			return fmt.Errorf("Failed to start %s: %v", e.name, err)
+		}
+		m.started = append(m.started, e)
+	}
+	return nil
+}
+
+// Stop stops started components in reverse start order. Every component is
+// stopped even when some fail; the failures are returned together. Safe to
+// call when nothing was started.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stop(ctx)
+}
+
+func (m *Manager) stop(ctx context.Context) error {
+	var errs []error
+	for len(m.started) > 0 {
+		e := m.started[len(m.started)-1]
+		m.started = m.started[:len(m.started)-1]
+
+		if err := e.component.Stop(ctx); err != nil {
+			if m.OnStopError != nil {
+				m.OnStopError(e.name, err)
+			}
+			errs = append(errs, fmt.Errorf("Failed to stop %s: %v", e.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Running reports whether the named component is started
+func (m *Manager) Running(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.started {
+		if e.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Default holds the agent runtime's components. Services register their own
+// subsystems here from init(), declaring the runtime components they use.
+var Default = New()
+
+// Register adds a component to Default; see Manager.Register
+func Register(name string, c Component, dependsOn ...string) {
+	Default.Register(name, c, dependsOn...)
+}
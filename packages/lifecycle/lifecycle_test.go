@@ -0,0 +1,243 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recorder logs the Start and Stop calls of fake components in order
+type recorder struct {
+	calls []string
+}
+
+// component returns a fake component that records its calls and fails the
+// steps listed in fail ("start" or "stop")
+func (r *recorder) component(name string, fail ...string) Component {
+	failing := func(step string) error {
+		for _, f := range fail {
+			if f == step {
+				return errors.New(step + " failed")
+			}
+		}
+		return nil
+	}
+	return Hook{
+		OnStart: func(ctx context.Context) error {
+			r.calls = append(r.calls, "start "+name)
+			return failing("start")
+		},
+		OnStop: func(ctx context.Context) error {
+			r.calls = append(r.calls, "stop "+name)
+			return failing("stop")
+		},
+	}
+}
+
+// fake declares a component for a test table
+type fake struct {
+	name      string
+	dependsOn []string
+	fail      []string
+}
+
+// TestOrder checks dependency ordering and resolution errors
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []fake
+		want       string
+		wantErr    string
+	}{
+		{"registration order", []fake{{name: "a"}, {name: "b"}, {name: "c"}}, "a,b,c", ""},
+		{"dependencies first", []fake{{name: "a", dependsOn: []string{"c"}}, {name: "b"}, {name: "c"}}, "b,c,a", ""},
+		{"chain", []fake{{name: "a", dependsOn: []string{"b"}}, {name: "b", dependsOn: []string{"c"}}, {name: "c"}}, "c,b,a", ""},
+		{"shared dependency", []fake{{name: "x", dependsOn: []string{"s"}}, {name: "y", dependsOn: []string{"s"}}, {name: "s"}}, "s,x,y", ""},
+		{"unknown dependency", []fake{{name: "a", dependsOn: []string{"missing"}}}, "", "component a depends on unknown component missing"},
+		{"cycle", []fake{{name: "z"}, {name: "a", dependsOn: []string{"b"}}, {name: "b", dependsOn: []string{"a"}}}, "", "dependency cycle among components a, b"},
+		{"self dependency", []fake{{name: "a", dependsOn: []string{"a"}}}, "", "dependency cycle among components a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			r := &recorder{}
+			for _, c := range tt.components {
+				m.Register(c.name, r.component(c.name), c.dependsOn...)
+			}
+
+			order, err := m.Order()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Order() error = %v, want %q", err, tt.wantErr)
+				}
+				if err := m.Start(context.Background()); err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Start() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(r.calls) != 0 {
+					t.Errorf("Start() with an invalid graph called %v", r.calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Order() failed: %v", err)
+			}
+			if got := strings.Join(order, ","); got != tt.want {
+				t.Errorf("Order() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestStartStop checks that components start in dependency order, stop in
+// reverse, and that a failed start rolls back the components already started
+func TestStartStop(t *testing.T) {
+	tests := []struct {
+		name         string
+		components   []fake
+		wantStartErr string
+		wantStopErr  string
+		wantStopped  []string
+		wantCalls    string
+	}{
+		{
+			name:       "start and stop",
+			components: []fake{{name: "jobs", dependsOn: []string{"storage"}}, {name: "storage"}, {name: "scheduler", dependsOn: []string{"jobs"}}},
+			wantCalls:  "start storage,start jobs,start scheduler,stop scheduler,stop jobs,stop storage",
+		},
+		{
+			name:         "rollback on start failure",
+			components:   []fake{{name: "a"}, {name: "b"}, {name: "c", fail: []string{"start"}}, {name: "d"}},
+			wantStartErr: "Failed to start c: start failed",
+			wantCalls:    "start a,start b,start c,stop b,stop a",
+		},
+		{
+			name:         "rollback continues past stop failures",
+			components:   []fake{{name: "a"}, {name: "b", fail: []string{"stop"}}, {name: "c", fail: []string{"start"}}},
+			wantStartErr: "Failed to start c: start failed",
+			wantStopped:  []string{"b"},
+			wantCalls:    "start a,start b,start c,stop b,stop a",
+		},
+		{
+			name:        "stop failures are joined",
+			components:  []fake{{name: "a", fail: []string{"stop"}}, {name: "b"}, {name: "c", fail: []string{"stop"}}},
+			wantStopErr: "Failed to stop c: stop failed\nFailed to stop a: stop failed",
+			wantStopped: []string{"c", "a"},
+			wantCalls:   "start a,start b,start c,stop c,stop b,stop a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			var stopErrors []string
+			m := New()
+			m.OnStopError = func(name string, err error) { stopErrors = append(stopErrors, name) }
+			for _, c := range tt.components {
+				m.Register(c.name, r.component(c.name, c.fail...), c.dependsOn...)
+			}
+
+			err := m.Start(context.Background())
+			if tt.wantStartErr != "" {
+				if err == nil || err.Error() != tt.wantStartErr {
+					t.Fatalf("Start() error = %v, want %q", err, tt.wantStartErr)
+				}
+				for _, c := range tt.components {
+					if m.Running(c.name) {
+						t.Errorf("%s is running after a failed Start()", c.name)
+					}
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Start() failed: %v", err)
+				}
+				for _, c := range tt.components {
+					if !m.Running(c.name) {
+						t.Errorf("%s is not running after Start()", c.name)
+					}
+				}
+				err = m.Stop(context.Background())
+				if tt.wantStopErr == "" && err != nil {
+					t.Errorf("Stop() failed: %v", err)
+				}
+				if tt.wantStopErr != "" && (err == nil || err.Error() != tt.wantStopErr) {
+					t.Errorf("Stop() error = %v, want %q", err, tt.wantStopErr)
+				}
+			}
+
+			if got := strings.Join(r.calls, ","); got != tt.wantCalls {
+				t.Errorf("calls = %s, want %s", got, tt.wantCalls)
+			}
+			if strings.Join(stopErrors, ",") != strings.Join(tt.wantStopped, ",") {
+				t.Errorf("OnStopError called for %v, want %v", stopErrors, tt.wantStopped)
+			}
+
+			// Nothing is left to stop
+			r.calls = nil
+			if err := m.Stop(context.Background()); err != nil || len(r.calls) != 0 {
+				t.Errorf("second Stop() = %v and called %v, want a no-op", err, r.calls)
+			}
+		})
+	}
+}
+
+// TestStartTwice checks that a running manager refuses to start again or
+// take new components
+func TestStartTwice(t *testing.T) {
+	r := &recorder{}
+	m := New()
+	m.Register("a", r.component("a"))
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if err := m.Start(context.Background()); err == nil {
+		t.Errorf("second Start() succeeded")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() after Start() did not panic")
+		}
+	}()
+	m.Register("b", r.component("b"))
+}
+
+// TestStartCancelled checks that a cancelled context stops the start
+// sequence and rolls back
+func TestStartCancelled(t *testing.T) {
+	r := &recorder{}
+	m := New()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m.Register("a", Hook{OnStart: func(context.Context) error {
+		r.calls = append(r.calls, "start a")
+		cancel()
+		return nil
+	}, OnStop: func(context.Context) error {
+		r.calls = append(r.calls, "stop a")
+		return nil
+	}})
+	m.Register("b", r.component("b"))
+
+	if err := m.Start(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Start() error = %v, want context.Canceled", err)
+	}
+	if got := strings.Join(r.calls, ","); got != "start a,stop a" {
+		t.Errorf("calls = %s, want start a,stop a", got)
+	}
+}
+
+// TestRegisterDuplicate checks that a name can only be registered once
+func TestRegisterDuplicate(t *testing.T) {
+	m := New()
+	m.Register("a", Hook{})
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("duplicate Register() did not panic")
+		}
+	}()
+	m.Register("a", Hook{})
+}
@@ -4,10 +4,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/go-hclog"
+
+	"github.com/cloudputation/service-seed/packages/buildinfo"
 )
 
+// loggerName follows the service name so renamed services log under their own name
+var loggerName = strings.ToUpper(buildinfo.ServiceName)
+
 // Logger interface abstracts logging operations
 // This allows us to decouple from hclog and makes testing easier
 type Logger interface {
@@ -75,6 +82,10 @@ type LoggerOptions struct {
 	// ExtraWriter is an additional io.Writer to send logs to (e.g., OTLP adapter)
 	// If set, a separate JSON-formatted logger will write to this destination
 	ExtraWriter io.Writer
+
+	// Redactor, if set, is applied to every log line before it is written
+	// (e.g., config.RedactSecrets to mask resolved secrets)
+	Redactor func(string) string
 }
 
 func InitLogger(logDirPath, logLevelController string) error {
@@ -83,9 +94,10 @@ func InitLogger(logDirPath, logLevelController string) error {
 
 func InitLoggerWithOptions(logDirPath, logLevelController string, opts *LoggerOptions) error {
 	logFileName := "service-seed.log"
-	logFilePath := logDirPath + "/" + logFileName
+	logFilePath := filepath.Join(logDirPath, logFileName)
 
-	logFile, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	var err error
+	logFile, err = os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		return fmt.Errorf("Failed to open log file at path %s: %v", logFilePath, err)
 	}
@@ -106,9 +118,12 @@ func InitLoggerWithOptions(logDirPath, logLevelController string, opts *LoggerOp
 	}
 
 	// Human-readable logger for stdout + file
-	consoleWriter := io.MultiWriter(os.Stdout, logFile)
+	var consoleWriter io.Writer = io.MultiWriter(os.Stdout, logFile)
+	if opts != nil && opts.Redactor != nil {
+		consoleWriter = &redactingWriter{out: consoleWriter, redact: opts.Redactor}
+	}
 	logger = hclog.New(&hclog.LoggerOptions{
-		Name:   "SERVICE-SEED",
+		Name:   loggerName,
 		Level:  logLevel,
 		Output: consoleWriter,
 		// JSONFormat: false (default, human-readable)
@@ -116,10 +131,14 @@ func InitLoggerWithOptions(logDirPath, logLevelController string, opts *LoggerOp
 
 	// JSON logger for OTLP only (if configured)
 	if opts != nil && opts.ExtraWriter != nil {
+		extraWriter := opts.ExtraWriter
+		if opts.Redactor != nil {
+			extraWriter = &redactingWriter{out: extraWriter, redact: opts.Redactor}
+		}
 		jsonLogger = hclog.New(&hclog.LoggerOptions{
-			Name:       "SERVICE-SEED",
+			Name:       loggerName,
 			Level:      logLevel,
-			Output:     opts.ExtraWriter,
+			Output:     extraWriter,
 			JSONFormat: true,
 		})
 	}
@@ -127,6 +146,19 @@ func InitLoggerWithOptions(logDirPath, logLevelController string, opts *LoggerOp
 	return nil
 }
 
+// redactingWriter masks sensitive values before forwarding log output
+type redactingWriter struct {
+	out    io.Writer
+	redact func(string) string
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := w.out.Write([]byte(w.redact(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 func CloseLogger() {
 	if logFile != nil {
 		logFile.Close()
@@ -14,15 +14,9 @@ import (
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	"go.opentelemetry.io/otel/log"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
-	"go.opentelemetry.io/otel/sdk/resource"
-	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"google.golang.org/grpc/credentials"
-)
 
-// Build-time variables (shared with stats package)
-var (
-	Version     = "dev"
-	Environment = "development"
+	"github.com/cloudputation/service-seed/packages/buildinfo"
 )
 
 // loggerProvider holds the OTEL logger provider for graceful shutdown
@@ -189,13 +183,8 @@ func InitOTLPLogs(opts *OTLPLogsOptions) (*otlpLogWriter, error) {
 		return nil, fmt.Errorf("failed to create OTLP log exporter: %v", err)
 	}
 
-	// Create resource with service attributes
-	res := resource.NewWithAttributes(
-		semconv.SchemaURL,
-		semconv.ServiceName("service-seed"),
-		semconv.ServiceVersion(Version),
-		semconv.DeploymentEnvironment(Environment),
-	)
+	// Create resource with service and build attributes (same as metrics and traces)
+	res := buildinfo.Resource()
 
 	// Create logger provider with batch processor
 	loggerProvider = sdklog.NewLoggerProvider(
@@ -204,7 +193,7 @@ func InitOTLPLogs(opts *OTLPLogsOptions) (*otlpLogWriter, error) {
 	)
 
 	// Get a logger from the provider
-	otelLogger := loggerProvider.Logger("service-seed")
+	otelLogger := loggerProvider.Logger(buildinfo.ServiceName)
 
 	fmt.Fprintf(os.Stderr, "[OTLP] Log exporter initialized: endpoint=%s\n", opts.Endpoint)
 
@@ -0,0 +1,350 @@
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/endpoint/*.tmpl
+var endpointTemplates embed.FS
+
+// EndpointOptions describe an API resource generated by `scaffold endpoint`
+type EndpointOptions struct {
+	// Name is the singular resource name in kebab-case (e.g. "todo", "work-item")
+	Name string
+
+	// Plural is used for the route and list handler (default: English plural of Name)
+	Plural string
+
+	// Fields are "name:type" pairs; see FieldTypes
+	Fields []string
+
+	// Dir is the service root containing go.mod (default: ".")
+	Dir string
+
+	// Force overwrites previously generated files
+	Force bool
+}
+
+// FieldTypes maps the types accepted in --field to Go types
+var FieldTypes = map[string]string{
+	"string":  "string",
+	"int":     "int",
+	"int64":   "int64",
+	"float":   "float64",
+	"float64": "float64",
+	"bool":    "bool",
+	"time":    "time.Time",
+}
+
+// fieldExamples are JSON values used by the generated tests
+var fieldExamples = map[string]string{
+	"string":    `"example"`,
+	"int":       `42`,
+	"int64":     `42`,
+	"float64":   `1.5`,
+	"bool":      `true`,
+	"time.Time": `"2024-01-01T00:00:00Z"`,
+}
+
+// endpointOperations are the CRUD operations with a counter each
+var endpointOperations = []string{"Create", "List", "Get", "Update", "Delete"}
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// endpointData is passed to the endpoint templates
+type endpointData struct {
+	Module      string
+	Name        string // Go type name, e.g. WorkItem
+	Plural      string // e.g. WorkItems
+	Var         string // unexported identifier, e.g. workItem
+	Label       string // human-readable, e.g. "work item"
+	Metric      string // metric name prefix, e.g. work_item
+	Path        string // route, e.g. /v1/work-items
+	Key         string // storage key prefix, e.g. work-items
+	Fields      []endpointField
+	Operations  []string
+	ExampleJSON string
+}
+
+type endpointField struct {
+	Name string
+	JSON string
+	Type string
+}
+
+// endpointFile maps a template to its output path
+type endpointFile struct {
+	template string
+	path     string
+
+	// keep leaves an existing file untouched (shared by all resources)
+	keep bool
+}
+
+// RenderEndpoint writes the model, storage, handlers, tests and metrics for a resource
+// and registers its routes in packages/api/server.go. It returns the written paths.
+func RenderEndpoint(opts EndpointOptions) ([]string, error) {
+	if opts.Dir == "" {
+		opts.Dir = "."
+	}
+
+	data, err := newEndpointData(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	serverPath := filepath.Join(opts.Dir, "packages", "api", "server.go")
+	server, err := os.ReadFile(serverPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s (run from the service root or use --dir): %v", serverPath, err)
+	}
+	if bytes.Contains(server, []byte(`"`+data.Path+`"`)) && !opts.Force {
+		return nil, fmt.Errorf("route %s is already registered in %s", data.Path, serverPath)
+	}
+	if err := checkDeclarations(filepath.Join(opts.Dir, "packages", "api", "v1"), data, opts.Force); err != nil {
+		return nil, err
+	}
+
+	files := []endpointFile{
+		{template: "handlers.go.tmpl", path: "packages/api/v1/" + data.Metric + ".go"},
+		{template: "storage.go.tmpl", path: "packages/api/v1/" + data.Metric + "_storage.go"},
+		{template: "handlers_test.go.tmpl", path: "packages/api/v1/" + data.Metric + "_test.go"},
+		{template: "main_test.go.tmpl", path: "packages/api/v1/main_test.go", keep: true},
+		{template: "metrics.go.tmpl", path: "packages/stats/" + data.Metric + "_metrics.go"},
+	}
+
+	// Render everything before writing so a template error leaves the tree untouched
+	rendered := map[string][]byte{}
+	for _, file := range files {
+		target := filepath.Join(opts.Dir, filepath.FromSlash(file.path))
+		if _, err := os.Stat(target); err == nil {
+			if file.keep {
+				continue
+			}
+			if !opts.Force {
+				return nil, fmt.Errorf("%s already exists (use --force to overwrite)", target)
+			}
+		}
+
+		src, err := renderEndpointTemplate(file.template, data)
+		if err != nil {
+			return nil, err
+		}
+		rendered[file.path] = src
+	}
+
+	var written []string
+	for _, file := range files {
+		src, ok := rendered[file.path]
+		if !ok {
+			continue
+		}
+		target := filepath.Join(opts.Dir, filepath.FromSlash(file.path))
+		if err := os.WriteFile(target, src, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", target, err)
+		}
+		written = append(written, file.path)
+	}
+
+	updated, err := registerRoutes(server, data)
+	if err != nil {
+		return written, fmt.Errorf("%v; register the routes manually in %s", err, serverPath)
+	}
+	if updated != nil {
+		if err := os.WriteFile(serverPath, updated, 0644); err != nil {
+			return written, fmt.Errorf("failed to write %s: %v", serverPath, err)
+		}
+		written = append(written, "packages/api/server.go")
+	}
+
+	return written, nil
+}
+
+// newEndpointData validates the options and derives identifiers
+func newEndpointData(opts EndpointOptions) (*endpointData, error) {
+	if !namePattern.MatchString(opts.Name) {
+		return nil, fmt.Errorf("resource name must be lowercase kebab-case (e.g. work-item), got %q", opts.Name)
+	}
+	plural := opts.Plural
+	if plural == "" {
+		plural = pluralize(opts.Name)
+	}
+	if !namePattern.MatchString(plural) {
+		return nil, fmt.Errorf("plural must be lowercase kebab-case, got %q", plural)
+	}
+	if plural == opts.Name {
+		return nil, fmt.Errorf("plural %q must differ from the resource name", plural)
+	}
+
+	module, err := readModulePath(filepath.Join(opts.Dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	name := pascalCase(opts.Name)
+	data := &endpointData{
+		Module:     module,
+		Name:       name,
+		Plural:     pascalCase(plural),
+		Var:        strings.ToLower(name[:1]) + name[1:],
+		Label:      strings.ReplaceAll(opts.Name, "-", " "),
+		Metric:     strings.ReplaceAll(opts.Name, "-", "_"),
+		Path:       "/v1/" + plural,
+		Key:        plural,
+		Operations: endpointOperations,
+	}
+
+	seen := map[string]bool{"id": true, "created_at": true, "updated_at": true}
+	var examples []string
+	for _, spec := range opts.Fields {
+		fieldName, fieldType, ok := strings.Cut(spec, ":")
+		if !ok {
+			return nil, fmt.Errorf("field %q must be written as name:type", spec)
+		}
+		if !fieldNamePattern.MatchString(fieldName) {
+			return nil, fmt.Errorf("field name must be lowercase snake_case, got %q", fieldName)
+		}
+		if seen[fieldName] {
+			return nil, fmt.Errorf("field %q is reserved or declared twice", fieldName)
+		}
+		seen[fieldName] = true
+
+		goType, ok := FieldTypes[fieldType]
+		if !ok {
+			return nil, fmt.Errorf("field %q has unsupported type %q (supported: string, int, int64, float, float64, bool, time)", fieldName, fieldType)
+		}
+
+		data.Fields = append(data.Fields, endpointField{
+			Name: pascalCase(strings.ReplaceAll(fieldName, "_", "-")),
+			JSON: fieldName,
+			Type: goType,
+		})
+		examples = append(examples, fmt.Sprintf("%q:%s", fieldName, fieldExamples[goType]))
+	}
+	data.ExampleJSON = "{" + strings.Join(examples, ",") + "}"
+
+	return data, nil
+}
+
+func renderEndpointTemplate(name string, data *endpointData) ([]byte, error) {
+	tmpl, err := template.New(name).
+		Funcs(template.FuncMap{"lower": strings.ToLower}).
+		ParseFS(endpointTemplates, "templates/endpoint/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %v", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %v", name, err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format %s: %v", name, err)
+	}
+	return src, nil
+}
+
+// checkDeclarations refuses to generate identifiers that already exist in the v1 package
+func checkDeclarations(dir string, data *endpointData, force bool) error {
+	if force {
+		return nil
+	}
+
+	sources, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+
+	declarations := []string{
+		"type " + data.Name + " ",
+		"func " + data.Name + "Handler(",
+		"func " + data.Plural + "Handler(",
+	}
+	for _, source := range sources {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return err
+		}
+		for _, declaration := range declarations {
+			if bytes.Contains(content, []byte(declaration)) {
+				return fmt.Errorf("%s already declares %q; choose another resource name", source, strings.TrimSpace(declaration))
+			}
+		}
+	}
+	return nil
+}
+
+var routePattern = regexp.MustCompile(`(?m)^([ \t]*)\{Group: \w+, Pattern: "/v1/.*$`)
+
+// registerRoutes inserts the resource routes, in the api route group, after
+// the last /v1/ route in server.go. It returns nil when the routes are already
+// present.
+func registerRoutes(server []byte, data *endpointData) ([]byte, error) {
+	collection := fmt.Sprintf(`{Group: GroupAPI, Pattern: "%s", Handler: v1.%sHandler},`, data.Path, data.Plural)
+	item := fmt.Sprintf(`{Group: GroupAPI, Pattern: "%s/", Handler: v1.%sHandler},`, data.Path, data.Name)
+	if bytes.Contains(server, []byte(fmt.Sprintf(`Pattern: "%s",`, data.Path))) {
+		return nil, nil
+	}
+
+	matches := routePattern.FindAllSubmatchIndex(server, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no /v1/ route registration found")
+	}
+	last := matches[len(matches)-1]
+	indent := string(server[last[2]:last[3]])
+
+	var out bytes.Buffer
+	out.Write(server[:last[1]])
+	out.WriteString("\n" + indent + collection)
+	out.WriteString("\n" + indent + item)
+	out.Write(server[last[1]:])
+	return out.Bytes(), nil
+}
+
+// readModulePath returns the module path declared in go.mod
+func readModulePath(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s (run from the service root or use --dir): %v", path, err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("no module directive in %s", path)
+}
+
+// pascalCase converts kebab-case to PascalCase ("work-item" -> "WorkItem")
+func pascalCase(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(s, "-") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// pluralize applies basic English plural rules to the last word
+func pluralize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	default:
+		return s + "s"
+	}
+}
@@ -0,0 +1,75 @@
+package scaffold
+
+import (
+	"fmt"
+)
+
+func init() {
+	RegisterFeature(Feature{
+		Name:        "telemetry",
+		Description: "Enable OTLP export of metrics, logs and traces in config.hcl",
+		Apply:       enableTelemetry,
+	})
+	RegisterFeature(Feature{
+		Name:        "storage",
+		Description: "Persist data in a bolt database with hourly snapshots in config.hcl",
+		Apply:       enableStorage,
+	})
+}
+
+// telemetryBlock is appended to the generated config.hcl by the telemetry feature
+const telemetryBlock = `
+# Telemetry export (enabled by "init --with telemetry")
+telemetry {
+  endpoint = "localhost:4317"
+
+  metrics {
+    enabled          = true
+    protocol         = "grpc"
+    interval_seconds = 60
+  }
+
+  logs {
+    enabled = true
+  }
+
+  traces {
+    enabled       = true
+    sampling_rate = 1.0
+  }
+}
+`
+
+// storageBlock is appended to the generated config.hcl by the storage feature
+const storageBlock = `
+# Persistent storage (enabled by "init --with storage")
+storage {
+  engine          = "bolt"
+  path            = "store.db"
+  timeout_seconds = 5
+}
+
+snapshot {
+  dir              = "snapshots"
+  interval_seconds = 3600
+  retain           = 7
+}
+`
+
+func enableTelemetry(files map[string][]byte, opts Options) error {
+	data, ok := files["config.hcl"]
+	if !ok {
+		return fmt.Errorf("config.hcl not found in template")
+	}
+	files["config.hcl"] = append(data, []byte(telemetryBlock)...)
+	return nil
+}
+
+func enableStorage(files map[string][]byte, opts Options) error {
+	data, ok := files["config.hcl"]
+	if !ok {
+		return fmt.Errorf("config.hcl not found in template")
+	}
+	files["config.hcl"] = append(data, []byte(storageBlock)...)
+	return nil
+}
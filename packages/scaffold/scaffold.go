@@ -0,0 +1,314 @@
+package scaffold
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudputation/service-seed/packages/buildinfo"
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// Seed identifiers rewritten when rendering a new service. They are read from
+// the running binary, so a service generated from a generated service has its
+// own name, module path and prefix rewritten.
+var (
+	SeedName   = buildinfo.ServiceName
+	SeedModule = strings.TrimSuffix(reflect.TypeOf(Options{}).PkgPath(), "/packages/scaffold")
+	SeedPrefix = strings.TrimSuffix(config.EnvPrefix, "_")
+)
+
+// seedPortPatterns find the template's default ports: the server port in
+// config.hcl and the container port in the Dockerfile
+var seedPortPatterns = map[string]*regexp.Regexp{
+	"config.hcl": regexp.MustCompile(`(?m)^\s*port\s*=\s*"(\d+)"`),
+	"Dockerfile": regexp.MustCompile(`(?m)^ARG SERVICE_PORT=(\d+)`),
+}
+
+// portFiles are the deploy files and docs, besides those in seedPortPatterns,
+// whose default ports follow --port
+var portFiles = map[string]bool{
+	"GNUmakefile": true,
+	"README.md":   true,
+}
+
+// portFile reports whether the default ports are rewritten in the template
+// file at p. Go sources never are: the ports they mention are test fixtures
+// and flag defaults, not the port the new service listens on.
+func portFile(p string) bool {
+	if _, ok := seedPortPatterns[p]; ok {
+		return true
+	}
+	return portFiles[p] || (strings.HasPrefix(p, "helpers/") && path.Ext(p) == ".sh")
+}
+
+// Template is the seed source tree rendered by Render. It is set by main from
+// the files embedded in the binary.
+var Template fs.FS
+
+// Options describe the service to generate
+type Options struct {
+	// Name is the new service name in kebab-case (e.g. "billing-api")
+	Name string
+
+	// Module is the Go module path (e.g. "github.com/acme/billing-api")
+	Module string
+
+	// EnvPrefix replaces the SS_ environment variable prefix (default: initials of Name)
+	EnvPrefix string
+
+	// Port is the HTTP port written to config.hcl and the Dockerfile
+	Port int
+
+	// OutputDir is where the tree is written (default: ./<Name>)
+	OutputDir string
+
+	// Features lists optional features to enable (see Features)
+	Features []string
+
+	// Force allows writing into a non-empty directory
+	Force bool
+}
+
+// Feature is an optional capability selected with `init --with <name>`
+type Feature struct {
+	Name        string
+	Description string
+
+	// Apply edits rendered files (keyed by slash-separated path) in place
+	Apply func(files map[string][]byte, opts Options) error
+}
+
+// Features lists the optional features known to the generator, by name
+var Features = map[string]Feature{}
+
+// RegisterFeature makes an optional feature selectable from the init command
+func RegisterFeature(feature Feature) {
+	Features[feature.Name] = feature
+}
+
+// FeatureNames returns the registered feature names in sorted order
+func FeatureNames() []string {
+	names := make([]string, 0, len(Features))
+	for name := range Features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+var prefixPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*$`)
+
+// ApplyDefaults fills in derived options
+func (o *Options) ApplyDefaults() {
+	if o.EnvPrefix == "" {
+		for _, part := range strings.Split(o.Name, "-") {
+			if part != "" {
+				o.EnvPrefix += strings.ToUpper(part[:1])
+			}
+		}
+	}
+	if o.Port == 0 {
+		o.Port = 8080
+	}
+	if o.OutputDir == "" {
+		o.OutputDir = o.Name
+	}
+}
+
+// Validate checks options before anything is written
+func (o *Options) Validate() error {
+	if !namePattern.MatchString(o.Name) {
+		return fmt.Errorf("service name must be lowercase kebab-case (e.g. billing-api), got %q", o.Name)
+	}
+	if o.Module == "" || strings.ContainsAny(o.Module, " \t\\") || strings.HasPrefix(o.Module, "/") || strings.HasSuffix(o.Module, "/") {
+		return fmt.Errorf("module must be a Go module path (e.g. github.com/acme/%s), got %q", o.Name, o.Module)
+	}
+	if !prefixPattern.MatchString(o.EnvPrefix) {
+		return fmt.Errorf("env prefix must be uppercase letters and digits, got %q", o.EnvPrefix)
+	}
+	if o.Port < 1 || o.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", o.Port)
+	}
+	for _, name := range o.Features {
+		if _, ok := Features[name]; !ok {
+			return fmt.Errorf("unknown feature %q (available: %s)", name, strings.Join(FeatureNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// Render writes the template tree to opts.OutputDir with the seed's name, module
+// path, environment prefix and ports replaced. It returns the written paths.
+func Render(template fs.FS, opts Options) ([]string, error) {
+	opts.ApplyDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, fmt.Errorf("no template available")
+	}
+
+	if err := checkOutputDir(opts.OutputDir, opts.Force); err != nil {
+		return nil, err
+	}
+
+	replacer := newReplacer(opts, seedPorts(template))
+
+	// Render every file in memory first so features can edit them before writing
+	files := map[string][]byte{}
+	err := fs.WalkDir(template, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		data, err := fs.ReadFile(template, p)
+		if err != nil {
+			return err
+		}
+		// go.sum only holds dependency hashes, which a short name could match
+		if utf8.Valid(data) && path.Base(p) != "go.sum" {
+			text := replacer.replace(string(data))
+			if portFile(p) {
+				text = replacer.replacePorts(text)
+			}
+			data = []byte(text)
+		}
+		files[replacer.replace(p)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %v", err)
+	}
+
+	for _, name := range opts.Features {
+		if err := Features[name].Apply(files, opts); err != nil {
+			return nil, fmt.Errorf("failed to apply feature %q: %v", name, err)
+		}
+	}
+
+	written := make([]string, 0, len(files))
+	for p := range files {
+		written = append(written, p)
+	}
+	sort.Strings(written)
+
+	for _, p := range written {
+		target := filepath.Join(opts.OutputDir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %v", err)
+		}
+
+		// Embedded files lose their mode; scripts must stay executable
+		mode := os.FileMode(0644)
+		if path.Ext(p) == ".sh" {
+			mode = 0755
+		}
+		if err := os.WriteFile(target, files[p], mode); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", target, err)
+		}
+	}
+
+	return written, nil
+}
+
+// checkOutputDir refuses to write into a non-empty directory unless forced
+func checkOutputDir(dir string, force bool) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read output directory: %v", err)
+	}
+	if len(entries) > 0 && !force {
+		return fmt.Errorf("output directory %s is not empty (use --force to overwrite)", dir)
+	}
+	return nil
+}
+
+// replacer rewrites seed identifiers in paths and file contents
+type replacer struct {
+	strings *strings.Replacer
+	prefix  *regexp.Regexp
+	ports   *regexp.Regexp
+	opts    Options
+}
+
+// seedPorts returns the default ports found in the template, so ports changed
+// by a previous init are rewritten as well
+func seedPorts(template fs.FS) []string {
+	seen := map[string]bool{}
+	var ports []string
+	for name, pattern := range seedPortPatterns {
+		data, err := fs.ReadFile(template, name)
+		if err != nil {
+			continue
+		}
+		if m := pattern.FindSubmatch(data); m != nil && !seen[string(m[1])] {
+			seen[string(m[1])] = true
+			ports = append(ports, string(m[1]))
+		}
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+func newReplacer(opts Options, ports []string) *replacer {
+	seedSnake, seedTitle := nameVariants(SeedName)
+	snake, title := nameVariants(opts.Name)
+
+	r := &replacer{
+		// Module path first so the name inside it is not rewritten separately.
+		// "iterator" is the seed's legacy binary name in the Makefile and helpers.
+		strings: strings.NewReplacer(
+			SeedModule, opts.Module,
+			SeedName, opts.Name,
+			seedTitle, title,
+			strings.ToUpper(seedSnake), strings.ToUpper(snake),
+			seedSnake, snake,
+			"ITERATOR", strings.ToUpper(snake),
+			"iterator", opts.Name,
+		),
+		prefix: regexp.MustCompile(`\b` + SeedPrefix + `_`),
+		opts:   opts,
+	}
+	if len(ports) > 0 {
+		r.ports = regexp.MustCompile(`\b(` + strings.Join(ports, "|") + `)\b`)
+	}
+	return r
+}
+
+// nameVariants returns the snake_case and Title Case forms of a kebab-case name
+func nameVariants(name string) (snake, title string) {
+	words := strings.Split(name, "-")
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.ReplaceAll(name, "-", "_"), strings.Join(words, " ")
+}
+
+func (r *replacer) replace(s string) string {
+	s = r.strings.Replace(s)
+	return r.prefix.ReplaceAllString(s, r.opts.EnvPrefix+"_")
+}
+
+// replacePorts rewrites the seed's default ports to the new service's port
+func (r *replacer) replacePorts(s string) string {
+	if r.ports == nil {
+		return s
+	}
+	return r.ports.ReplaceAllString(s, strconv.Itoa(r.opts.Port))
+}
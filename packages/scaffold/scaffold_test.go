@@ -0,0 +1,151 @@
+package scaffold
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// repoRoot is the seed's source tree, relative to this package
+const repoRoot = "../.."
+
+// seedTree returns the files listed in the //go:embed directive of seed.go,
+// read from the working tree, as main embeds them into scaffold.Template
+func seedTree(t *testing.T) fs.FS {
+	t.Helper()
+
+	src, err := os.ReadFile(filepath.Join(repoRoot, "seed.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`(?m)^//go:embed (.+)$`).FindSubmatch(src)
+	if m == nil {
+		t.Fatal("no //go:embed directive in seed.go")
+	}
+
+	tree := fstest.MapFS{}
+	root := os.DirFS(repoRoot)
+	for _, pattern := range strings.Fields(string(m[1])) {
+		err := fs.WalkDir(root, pattern, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Like go:embed, skip hidden and underscore-prefixed entries of directories
+			if p != pattern && (strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_")) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			data, err := fs.ReadFile(root, p)
+			if err != nil {
+				return err
+			}
+			tree[p] = &fstest.MapFile{Data: data}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tree
+}
+
+// TestRenderPorts checks that --port reaches the deploy files and docs only
+func TestRenderPorts(t *testing.T) {
+	out := t.TempDir()
+	_, err := Render(seedTree(t), Options{
+		Name:      "billing-api",
+		Module:    "github.com/acme/billing-api",
+		Port:      9090,
+		OutputDir: out,
+		Force:     true,
+	})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+
+	tests := []struct {
+		file    string
+		want    string
+		notWant string
+	}{
+		{"config.hcl", `port = "9090"`, `port = "8080"`},
+		{"Dockerfile", "ARG SERVICE_PORT=9090", "9595"},
+		{"GNUmakefile", "LOCAL_PORT ?= 9090", "8080"},
+		{"README.md", `port = "9090"`, "8080"},
+		{"helpers/docker_rebuild.sh", `SERVICE_PORT="9090"`, "9595"},
+		{"packages/cli/init.go", `"port", 8080,`, ""},
+		{"packages/api/headers/cors_test.go", "api.local.test:9090", ""},
+		{"packages/api/headers/cors_test.go", "*.local.test:8080", ""},
+		{"packages/scaffold/CLAUDELET.md", "`8080` and `9595`", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(tt.file)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("%s does not contain %q", tt.file, tt.want)
+			}
+			if tt.notWant != "" && strings.Contains(string(data), tt.notWant) {
+				t.Errorf("%s still contains %q", tt.file, tt.notWant)
+			}
+		})
+	}
+}
+
+// TestRenderBuilds renders a service with a non-default port and every
+// feature, then builds and tests it
+func TestRenderBuilds(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and tests a generated service")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found")
+	}
+
+	out := t.TempDir()
+	written, err := Render(seedTree(t), Options{
+		Name:      "billing-api",
+		Module:    "github.com/acme/billing-api",
+		Port:      9090,
+		OutputDir: out,
+		Features:  FeatureNames(),
+		Force:     true,
+	})
+	if err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	for _, p := range written {
+		if strings.Contains(p, "service-seed") || path.Base(p) == "service_seed" {
+			t.Errorf("rendered path %s still holds the seed name", p)
+		}
+	}
+
+	// -short keeps the generated service's copy of this test from rendering again
+	for _, args := range [][]string{
+		{"build", "./..."},
+		{"vet", "./..."},
+		{"test", "-short", "./..."},
+	} {
+		cmd := exec.Command(goBin, args...)
+		cmd.Dir = out
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s in the generated service failed: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+}
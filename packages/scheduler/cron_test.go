@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseCronNext checks run times computed from cron expressions, in UTC.
+// 2026-10-18 is a Sunday.
+func TestParseCronNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from string
+		want string
+	}{
+		{"step within the hour", "*/15 * * * *", "2026-10-18T10:07:00Z", "2026-10-18T10:15:00Z"},
+		{"step on a match moves to the next", "*/15 * * * *", "2026-10-18T10:15:30Z", "2026-10-18T10:30:00Z"},
+		{"step wraps to the next hour", "*/15 * * * *", "2026-10-18T10:45:00Z", "2026-10-18T11:00:00Z"},
+		{"step from a start value", "5/15 * * * *", "2026-10-18T10:50:00Z", "2026-10-18T11:05:00Z"},
+		{"range with step", "0-30/10 9 * * *", "2026-10-18T09:25:00Z", "2026-10-18T09:30:00Z"},
+		{"list", "0 6,18 * * *", "2026-10-18T07:00:00Z", "2026-10-18T18:00:00Z"},
+		{"day of week only", "0 9 * * mon", "2026-10-18T10:00:00Z", "2026-10-19T09:00:00Z"},
+		{"day of month only", "0 9 1 * *", "2026-10-18T10:00:00Z", "2026-11-01T09:00:00Z"},
+		{"day of month or day of week, day of week first", "0 9 1 * mon", "2026-10-18T10:00:00Z", "2026-10-19T09:00:00Z"},
+		{"day of month or day of week, day of month first", "0 9 1 * mon", "2026-10-26T10:00:00Z", "2026-11-01T09:00:00Z"},
+		{"stepped day of month and day of week must both match", "0 0 */2 * mon", "2026-10-19T01:00:00Z", "2026-11-09T00:00:00Z"},
+		{"sunday as 7", "0 12 * * 7", "2026-10-18T13:00:00Z", "2026-10-25T12:00:00Z"},
+		{"month names", "0 0 1 jan,jul *", "2026-10-18T00:00:00Z", "2027-01-01T00:00:00Z"},
+		{"leap day", "0 0 29 2 *", "2026-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"hourly macro", "@hourly", "2026-10-18T10:59:59Z", "2026-10-18T11:00:00Z"},
+		{"weekly macro", "@weekly", "2026-10-18T00:00:00Z", "2026-10-25T00:00:00Z"},
+		{"never matches", "0 0 30 2 *", "2026-10-18T00:00:00Z", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) failed: %v", tt.expr, err)
+			}
+
+			from, _ := time.Parse(time.RFC3339, tt.from)
+			got := schedule.Next(from)
+
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("Next(%s) = %s, want no run", tt.from, got.Format(time.RFC3339))
+				}
+				return
+			}
+			want, _ := time.Parse(time.RFC3339, tt.want)
+			if !got.Equal(want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got.Format(time.RFC3339), tt.want)
+			}
+		})
+	}
+}
+
+// TestParseCronErrors checks invalid expressions are rejected
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"too few fields", "* * * *"},
+		{"too many fields", "* * * * * *"},
+		{"minute out of range", "60 * * * *"},
+		{"day of month zero", "0 0 0 * *"},
+		{"zero step", "*/0 * * * *"},
+		{"reversed range", "30-10 * * * *"},
+		{"unknown name", "0 0 * foo *"},
+		{"unknown macro", "@every"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCron(tt.expr); err == nil {
+				t.Errorf("ParseCron(%q) succeeded, want an error", tt.expr)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package scheduler
+
+import (
+	"os"
+	"testing"
+
+	log "github.com/cloudputation/service-seed/packages/logger"
+)
+
+// TestMain initializes the logger used to report runs
+func TestMain(m *testing.M) {
+	logDir, err := os.MkdirTemp("", "scheduler-test")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := log.InitLogger(logDir, "error"); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	log.CloseLogger()
+	os.RemoveAll(logDir)
+	os.Exit(code)
+}
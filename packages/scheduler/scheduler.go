@@ -0,0 +1,453 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/jobs"
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/stats"
+)
+
+// Func is the body of a scheduled task. ctx is cancelled when shutdown runs
+// out of drain time.
+type Func func(ctx context.Context) error
+
+// Options adjusts a task registered in code. A schedule block with the same
+// name overrides them.
+type Options struct {
+	// Jitter delays each run by a random amount up to this value
+	Jitter time.Duration
+
+	// AllowOverlap starts a run even when the previous one is still going;
+	// by default the run is skipped
+	AllowOverlap bool
+}
+
+type registration struct {
+	schedule Schedule
+	fn       Func
+	opts     Options
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*registration{}
+	started    bool
+)
+
+// Register adds a periodic task; call from init(). schedule may be nil for a
+// task that only runs once a schedule block with the same name sets cron or
+// interval_seconds. Use Every or MustParseCron for schedules fixed in code.
+func Register(name string, schedule Schedule, fn Func, opts *Options) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("scheduler: empty task name")
+	}
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("scheduler: task %q registered twice", name))
+	}
+	if started {
+		panic(fmt.Sprintf("scheduler: task %q registered after InitScheduler", name))
+	}
+
+	r := &registration{schedule: schedule, fn: fn}
+	if opts != nil {
+		r.opts = *opts
+	}
+	registry[name] = r
+}
+
+// task is a registered or configured task and its run history
+type task struct {
+	name         string
+	schedule     Schedule
+	fn           Func
+	jitter       time.Duration
+	allowOverlap bool
+	enabled      bool
+
+	mu           sync.Mutex
+	running      int
+	nextRun      *time.Time
+	lastRun      *time.Time
+	lastDuration time.Duration
+	lastStatus   string
+	lastError    string
+	runs         int64
+	skipped      int64
+}
+
+// TaskStatus is a task's schedule and run history, reported in system status
+type TaskStatus struct {
+	Name                string     `json:"name"`
+	Schedule            string     `json:"schedule,omitempty"`
+	Enabled             bool       `json:"enabled"`
+	Running             bool       `json:"running"`
+	NextRun             *time.Time `json:"next_run,omitempty"`
+	LastRun             *time.Time `json:"last_run,omitempty"`
+	LastStatus          string     `json:"last_status,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastDurationSeconds float64    `json:"last_duration_seconds,omitempty"`
+	Runs                int64      `json:"runs"`
+	Skipped             int64      `json:"skipped"`
+}
+
+// resolveTasks merges code registrations with schedule blocks
+func resolveTasks() ([]*task, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	tasks := map[string]*task{}
+	for name, r := range registry {
+		tasks[name] = &task{
+			name:         name,
+			schedule:     r.schedule,
+			fn:           r.fn,
+			jitter:       r.opts.Jitter,
+			allowOverlap: r.opts.AllowOverlap,
+			enabled:      true,
+		}
+	}
+
+	for _, block := range config.AppConfig.Schedule {
+		t, registered := tasks[block.Name]
+		switch {
+		case block.Job != "" && registered:
+			return nil, fmt.Errorf("schedule %q: job is set but a task with this name is registered in code", block.Name)
+		case block.Job != "":
+			if !slices.Contains(jobs.Types(), block.Job) {
+				return nil, fmt.Errorf("schedule %q: %w %q", block.Name, jobs.ErrUnknownType, block.Job)
+			}
+			t = &task{name: block.Name, fn: enqueueJob(block), enabled: true}
+			tasks[block.Name] = t
+		case !registered:
+			return nil, fmt.Errorf("schedule %q: no task is registered with this name; set job to enqueue a job instead", block.Name)
+		}
+
+		switch {
+		case block.Cron != "":
+			schedule, err := ParseCron(block.Cron)
+			if err != nil {
+				return nil, fmt.Errorf("schedule %q: %v", block.Name, err)
+			}
+			if schedule.Next(time.Now()).IsZero() {
+				return nil, fmt.Errorf("schedule %q: cron expression %q never matches", block.Name, block.Cron)
+			}
+			t.schedule = schedule
+		case block.IntervalSeconds > 0:
+			t.schedule = Every(time.Duration(block.IntervalSeconds) * time.Second)
+		}
+		if block.JitterSeconds > 0 {
+			t.jitter = time.Duration(block.JitterSeconds) * time.Second
+		}
+		if block.AllowOverlap != nil {
+			t.allowOverlap = *block.AllowOverlap
+		}
+		if block.Enabled != nil {
+			t.enabled = *block.Enabled
+		}
+	}
+
+	list := make([]*task, 0, len(tasks))
+	for _, t := range tasks {
+		// Tasks without a schedule wait for a schedule block
+		if t.schedule == nil {
+			t.enabled = false
+		}
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name < list[j].name
+	})
+	return list, nil
+}
+
+// enqueueJob returns the body of a schedule block that sets job
+func enqueueJob(block *config.ScheduleTask) Func {
+	var payload interface{}
+	if block.Payload != "" {
+		payload = json.RawMessage(block.Payload)
+	}
+
+	return func(ctx context.Context) error {
+		job, err := jobs.Enqueue(ctx, block.Job, payload, nil)
+		if err != nil {
+			return err
+		}
+		log.Debug(fmt.Sprintf("Scheduled task %s enqueued job %s (%s)", block.Name, job.ID, job.Type))
+		return nil
+	}
+}
+
+// CheckConfig resolves schedule blocks against registered tasks and job types
+// and parses cron expressions without starting anything
+func CheckConfig() error {
+	_, err := resolveTasks()
+	return err
+}
+
+// scheduler runs the enabled tasks until ShutdownScheduler
+type scheduler struct {
+	tasks []*task
+
+	stop  context.CancelFunc
+	loops sync.WaitGroup
+
+	// runCtx outlives the loops so runs can finish during the drain
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+	runs       sync.WaitGroup
+}
+
+// current is the scheduler started by InitScheduler
+var current atomic.Pointer[scheduler]
+
+// InitScheduler resolves schedule blocks against registered tasks and starts
+// them. Runs after the job queue so tasks can enqueue jobs.
+func InitScheduler() error {
+	tasks, err := resolveTasks()
+	if err != nil {
+		return err
+	}
+
+	registryMu.Lock()
+	started = true
+	registryMu.Unlock()
+
+	loopCtx, stop := context.WithCancel(context.Background())
+	runCtx, cancelRuns := context.WithCancel(context.Background())
+	s := &scheduler{
+		tasks:      tasks,
+		stop:       stop,
+		runCtx:     runCtx,
+		cancelRuns: cancelRuns,
+	}
+
+	for _, t := range tasks {
+		if !t.enabled {
+			continue
+		}
+		log.Info(fmt.Sprintf("Scheduled task %s (%s, jitter: %s)", t.name, t.schedule, t.jitter))
+		s.loops.Add(1)
+		go s.loop(loopCtx, t)
+	}
+	current.Store(s)
+
+	return nil
+}
+
+// ShutdownScheduler stops starting runs and waits up to
+// workers.drain_timeout_seconds for running ones, then cancels them. Safe to
+// call when InitScheduler never ran.
+func ShutdownScheduler() error {
+	s := current.Swap(nil)
+	if s == nil {
+		return nil
+	}
+
+	s.stop()
+	s.loops.Wait()
+
+	drained := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(drained)
+	}()
+
+	timeout := time.Duration(config.AppConfig.Workers.DrainTimeoutSeconds) * time.Second
+	select {
+	case <-drained:
+		s.cancelRuns()
+		return nil
+	case <-time.After(timeout):
+	}
+
+	s.cancelRuns()
+
+	// Give cancelled runs a moment to return before reporting
+	select {
+	case <-drained:
+		return fmt.Errorf("scheduled tasks did not finish in time; cancelled running tasks")
+	case <-time.After(time.Second):
+		return fmt.Errorf("scheduled tasks did not finish in time; cancelled tasks are still running")
+	}
+}
+
+// loop waits for each run time of t and starts the run
+func (s *scheduler) loop(ctx context.Context, t *task) {
+	defer s.loops.Done()
+
+	slot := time.Now()
+	for {
+		// Runs missed while the previous wait overran are not made up
+		next := t.schedule.Next(slot)
+		if now := time.Now(); !next.IsZero() && next.Before(now) {
+			next = t.schedule.Next(now)
+		}
+		if next.IsZero() {
+			log.Warn(fmt.Sprintf("Scheduled task %s has no future runs", t.name))
+			t.setNextRun(nil)
+			return
+		}
+		slot = next
+
+		at := next
+		if t.jitter > 0 {
+			at = at.Add(rand.N(t.jitter))
+		}
+		t.setNextRun(&at)
+
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			t.setNextRun(nil)
+			return
+		case <-timer.C:
+		}
+
+		s.start(t)
+	}
+}
+
+// start runs t in the background unless its previous run is still going and
+// overlap is not allowed
+func (s *scheduler) start(t *task) {
+	t.mu.Lock()
+	if t.running > 0 && !t.allowOverlap {
+		t.skipped++
+		t.mu.Unlock()
+
+		log.Warn(fmt.Sprintf("Skipped scheduled run of %s: previous run still in progress", t.name))
+		stats.RecordSchedulerSkip(s.runCtx, t.name)
+		return
+	}
+	t.running++
+	t.mu.Unlock()
+
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		s.run(t)
+	}()
+}
+
+// run executes one run of t and records its outcome
+func (s *scheduler) run(t *task) {
+	ctx := s.runCtx
+	var span trace.Span
+	if stats.Tracer != nil {
+		ctx, span = stats.Tracer.Start(ctx, "scheduler.run", trace.WithAttributes(
+			attribute.String("scheduler.task", t.name),
+			attribute.String("scheduler.schedule", t.schedule.String()),
+		))
+		defer span.End()
+	}
+
+	startedAt := time.Now().UTC()
+	timer := stats.NewTimer()
+	panicked, err := call(ctx, t)
+	duration := timer.Elapsed()
+
+	status := "ok"
+	switch {
+	case panicked:
+		status = "panic"
+	case err != nil && ctx.Err() != nil:
+		status = "canceled"
+		log.Warn(fmt.Sprintf("Scheduled task %s cancelled by shutdown: %v", t.name, err))
+	case err != nil:
+		status = "error"
+		log.Error(fmt.Sprintf("Scheduled task %s failed: %v", t.name, err))
+	default:
+		log.Debug(fmt.Sprintf("Scheduled task %s completed in %s", t.name, duration))
+	}
+	stats.RecordSchedulerRun(ctx, t.name, status, duration)
+
+	if span != nil {
+		span.SetAttributes(attribute.String("scheduler.status", status))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running--
+	t.runs++
+	t.lastRun = &startedAt
+	t.lastDuration = duration
+	t.lastStatus = status
+	t.lastError = ""
+	if err != nil {
+		t.lastError = err.Error()
+	}
+}
+
+// call runs t's body, turning a panic into an error
+func call(ctx context.Context, t *task) (panicked bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error(fmt.Sprintf("Scheduled task %s panicked: %v\n%s", t.name, r, string(debug.Stack())))
+			panicked = true
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return false, t.fn(ctx)
+}
+
+func (t *task) setNextRun(at *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextRun = at
+}
+
+// Status returns every task's schedule and last and next run, sorted by
+// name. Returns nil before InitScheduler.
+func Status() []TaskStatus {
+	s := current.Load()
+	if s == nil {
+		return nil
+	}
+
+	list := make([]TaskStatus, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		t.mu.Lock()
+		status := TaskStatus{
+			Name:                t.name,
+			Enabled:             t.enabled,
+			Running:             t.running > 0,
+			NextRun:             t.nextRun,
+			LastRun:             t.lastRun,
+			LastStatus:          t.lastStatus,
+			LastError:           t.lastError,
+			LastDurationSeconds: t.lastDuration.Seconds(),
+			Runs:                t.runs,
+			Skipped:             t.skipped,
+		}
+		t.mu.Unlock()
+
+		if t.schedule != nil {
+			status.Schedule = t.schedule.String()
+		}
+		list = append(list, status)
+	}
+	return list
+}
@@ -0,0 +1,355 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/jobs"
+	"github.com/cloudputation/service-seed/packages/storage"
+)
+
+// testJobType is the job type enqueued by schedule blocks in the tests
+const testJobType = "scheduler-test"
+
+func init() {
+	jobs.Register(testJobType, func(ctx context.Context, job *jobs.Job) error { return nil })
+}
+
+// setupScheduler starts each test with an empty registry and no schedule
+// blocks, and stops the scheduler when the test ends
+func setupScheduler(t *testing.T) {
+	t.Helper()
+
+	registryMu.Lock()
+	savedRegistry, savedStarted := registry, started
+	registry, started = map[string]*registration{}, false
+	registryMu.Unlock()
+
+	savedSchedule, savedWorkers := config.AppConfig.Schedule, config.AppConfig.Workers
+	config.AppConfig.Schedule = nil
+	config.AppConfig.Workers = &config.Workers{DrainTimeoutSeconds: 1}
+
+	t.Cleanup(func() {
+		if err := ShutdownScheduler(); err != nil {
+			t.Errorf("ShutdownScheduler() failed: %v", err)
+		}
+		registryMu.Lock()
+		registry, started = savedRegistry, savedStarted
+		registryMu.Unlock()
+		config.AppConfig.Schedule, config.AppConfig.Workers = savedSchedule, savedWorkers
+	})
+}
+
+// status returns the status of the named task
+func status(t *testing.T, name string) TaskStatus {
+	t.Helper()
+	for _, s := range Status() {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("no status for task %s", name)
+	return TaskStatus{}
+}
+
+// waitUntil polls cond for up to two seconds
+func waitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestOverlap checks that ticks during a run are skipped unless overlap is
+// allowed, in which case runs start alongside each other
+func TestOverlap(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowOverlap bool
+	}{
+		{"skipped", false},
+		{"allowed", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupScheduler(t)
+
+			var active atomic.Int32
+			release := make(chan struct{})
+			Register("blocking", Every(10*time.Millisecond), func(ctx context.Context) error {
+				active.Add(1)
+				defer active.Add(-1)
+				<-release
+				return nil
+			}, &Options{AllowOverlap: tt.allowOverlap})
+
+			if err := InitScheduler(); err != nil {
+				t.Fatalf("InitScheduler() failed: %v", err)
+			}
+
+			if tt.allowOverlap {
+				waitUntil(t, "overlapping runs", func() bool { return active.Load() >= 3 })
+				if s := status(t, "blocking"); s.Skipped != 0 || !s.Running {
+					t.Errorf("status = %+v, want running with no skips", s)
+				}
+			} else {
+				waitUntil(t, "skipped runs", func() bool { return status(t, "blocking").Skipped >= 3 })
+				if got := active.Load(); got != 1 {
+					t.Errorf("%d runs in progress, want 1", got)
+				}
+				if s := status(t, "blocking"); !s.Running || s.Runs != 0 || s.LastRun != nil {
+					t.Errorf("status = %+v, want one run in progress and none finished", s)
+				}
+			}
+
+			close(release)
+			waitUntil(t, "a finished run", func() bool { return status(t, "blocking").Runs > 0 })
+		})
+	}
+}
+
+// TestPanicRecovered checks that a panicking run is recorded and the task
+// keeps being scheduled
+func TestPanicRecovered(t *testing.T) {
+	setupScheduler(t)
+
+	var runs atomic.Int32
+	Register("panics", Every(10*time.Millisecond), func(ctx context.Context) error {
+		runs.Add(1)
+		panic("boom")
+	}, nil)
+
+	if err := InitScheduler(); err != nil {
+		t.Fatalf("InitScheduler() failed: %v", err)
+	}
+	waitUntil(t, "several runs", func() bool { return status(t, "panics").Runs >= 3 })
+
+	s := status(t, "panics")
+	if s.LastStatus != "panic" || s.LastError != "panic: boom" {
+		t.Errorf("last run is %s with error %q, want panic with \"panic: boom\"", s.LastStatus, s.LastError)
+	}
+}
+
+// TestErrorRecorded checks that a failed run records its error and a later
+// successful run clears it
+func TestErrorRecorded(t *testing.T) {
+	setupScheduler(t)
+
+	var runs atomic.Int32
+	Register("flaky", Every(10*time.Millisecond), func(ctx context.Context) error {
+		if runs.Add(1) == 1 {
+			return errors.New("first run fails")
+		}
+		return nil
+	}, nil)
+
+	if err := InitScheduler(); err != nil {
+		t.Fatalf("InitScheduler() failed: %v", err)
+	}
+	waitUntil(t, "the failed run", func() bool { return status(t, "flaky").Runs >= 1 })
+	if s := status(t, "flaky"); s.Runs == 1 && (s.LastStatus != "error" || s.LastError != "first run fails") {
+		t.Errorf("after the first run: %s with error %q, want error", s.LastStatus, s.LastError)
+	}
+	waitUntil(t, "a later run", func() bool { return status(t, "flaky").Runs >= 2 })
+	if s := status(t, "flaky"); s.LastStatus != "ok" || s.LastError != "" {
+		t.Errorf("after a successful run: %s with error %q, want ok and no error", s.LastStatus, s.LastError)
+	}
+}
+
+// TestJitter checks that the next run falls between the scheduled time and
+// the scheduled time plus the jitter, whether set in code or by a block
+func TestJitter(t *testing.T) {
+	setupScheduler(t)
+
+	const tasks = 20
+	interval, jitter := time.Hour, 10*time.Minute
+	noop := func(ctx context.Context) error { return nil }
+	for i := 0; i < tasks; i++ {
+		Register(string(rune('a'+i)), Every(interval), noop, &Options{Jitter: jitter})
+	}
+	Register("configured", Every(interval), noop, nil)
+	config.AppConfig.Schedule = []*config.ScheduleTask{{Name: "configured", JitterSeconds: 600}}
+
+	before := time.Now()
+	if err := InitScheduler(); err != nil {
+		t.Fatalf("InitScheduler() failed: %v", err)
+	}
+	waitUntil(t, "next runs", func() bool {
+		for _, s := range Status() {
+			if s.NextRun == nil {
+				return false
+			}
+		}
+		return true
+	})
+	after := time.Now()
+
+	jittered := false
+	for _, s := range Status() {
+		earliest, latest := before.Add(interval), after.Add(interval+jitter)
+		if s.NextRun.Before(earliest) || !s.NextRun.Before(latest) {
+			t.Errorf("%s next run %s, want between %s and %s", s.Name, s.NextRun, earliest, latest)
+		}
+		if s.NextRun.Sub(after.Add(interval)) > 0 {
+			jittered = true
+		}
+	}
+	if !jittered {
+		t.Errorf("no next run was delayed by jitter")
+	}
+}
+
+// TestEnqueueJob checks that a schedule block with job enqueues a job of
+// that type carrying the block's payload
+func TestEnqueueJob(t *testing.T) {
+	setupScheduler(t)
+
+	savedStore, savedJobs := storage.DefaultStore, config.AppConfig.Jobs
+	t.Cleanup(func() {
+		storage.DefaultStore, config.AppConfig.Jobs = savedStore, savedJobs
+	})
+	storage.DefaultStore = storage.NewMemory()
+	config.AppConfig.Jobs = &config.Jobs{MaxAttempts: 3, BackoffSeconds: 1, MaxBackoffSeconds: 10, RetentionHours: 1}
+
+	config.AppConfig.Schedule = []*config.ScheduleTask{
+		{Name: "cleanup", Job: testJobType, IntervalSeconds: 3600, Payload: `{"days":30}`},
+	}
+	tasks, err := resolveTasks()
+	if err != nil {
+		t.Fatalf("resolveTasks() failed: %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].name != "cleanup" || !tasks[0].enabled || tasks[0].schedule.String() != "every 1h0m0s" {
+		t.Fatalf("resolveTasks() = %+v, want the enabled cleanup task", tasks)
+	}
+
+	if err := tasks[0].fn(context.Background()); err != nil {
+		t.Fatalf("running the task failed: %v", err)
+	}
+	pending, err := jobs.List(context.Background(), jobs.StatusPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Type != testJobType || string(pending[0].Payload) != `{"days":30}` {
+		t.Errorf("pending jobs = %+v, want one %s job with the block's payload", pending, testJobType)
+	}
+
+	// Without a payload the job carries a JSON null
+	config.AppConfig.Schedule[0].Payload = ""
+	tasks, err = resolveTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tasks[0].fn(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pending, _ = jobs.List(context.Background(), jobs.StatusPending)
+	payloads := map[string]bool{}
+	for _, job := range pending {
+		payloads[string(job.Payload)] = true
+	}
+	if len(pending) != 2 || !payloads[`{"days":30}`] || !payloads["null"] {
+		t.Errorf("pending jobs = %+v, want one with the payload and one with null", pending)
+	}
+}
+
+// TestResolveErrors checks that schedule blocks are checked against
+// registered tasks and job types
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		block   *config.ScheduleTask
+		wantErr string
+	}{
+		{"unknown task", &config.ScheduleTask{Name: "missing", IntervalSeconds: 60}, "no task is registered"},
+		{"unknown job type", &config.ScheduleTask{Name: "cleanup", Job: "missing", IntervalSeconds: 60}, "unknown job type"},
+		{"job on a registered task", &config.ScheduleTask{Name: "registered", Job: testJobType}, "registered in code"},
+		{"bad cron", &config.ScheduleTask{Name: "registered", Cron: "61 * * * *"}, "schedule \"registered\""},
+		{"cron never matches", &config.ScheduleTask{Name: "registered", Cron: "0 0 30 2 *"}, "never matches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupScheduler(t)
+			Register("registered", nil, func(ctx context.Context) error { return nil }, nil)
+			config.AppConfig.Schedule = []*config.ScheduleTask{tt.block}
+
+			err := CheckConfig()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckConfig() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestStatus checks the reported schedule, last run and next run of enabled,
+// disabled and unscheduled tasks
+func TestStatus(t *testing.T) {
+	setupScheduler(t)
+
+	if Status() != nil {
+		t.Fatalf("Status() before InitScheduler() is not nil")
+	}
+
+	noop := func(ctx context.Context) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	disabled := false
+	Register("frequent", Every(20*time.Millisecond), noop, nil)
+	Register("disabled", Every(20*time.Millisecond), noop, nil)
+	Register("unscheduled", nil, noop, nil)
+	config.AppConfig.Schedule = []*config.ScheduleTask{{Name: "disabled", Enabled: &disabled}}
+
+	before := time.Now()
+	if err := InitScheduler(); err != nil {
+		t.Fatalf("InitScheduler() failed: %v", err)
+	}
+	waitUntil(t, "a finished run", func() bool { return status(t, "frequent").Runs > 0 })
+
+	list := Status()
+	if len(list) != 3 || list[0].Name != "disabled" || list[1].Name != "frequent" || list[2].Name != "unscheduled" {
+		t.Fatalf("Status() = %+v, want the three tasks by name", list)
+	}
+
+	s := status(t, "frequent")
+	if !s.Enabled || s.Schedule != "every 20ms" || s.LastStatus != "ok" || s.LastDurationSeconds <= 0 {
+		t.Errorf("frequent = %+v, want enabled with a successful run", s)
+	}
+	if s.LastRun == nil || s.LastRun.Before(before.Add(20*time.Millisecond).Truncate(time.Millisecond)) || s.LastRun.After(time.Now()) {
+		t.Errorf("frequent last run = %v, want after the first interval", s.LastRun)
+	}
+	if s.NextRun == nil || !s.NextRun.After(*s.LastRun) {
+		t.Errorf("frequent next run = %v, want after the last run %v", s.NextRun, s.LastRun)
+	}
+
+	for _, name := range []string{"disabled", "unscheduled"} {
+		s := status(t, name)
+		if s.Enabled || s.NextRun != nil || s.LastRun != nil || s.Runs != 0 {
+			t.Errorf("%s = %+v, want disabled and never run", name, s)
+		}
+	}
+	if s := status(t, "unscheduled"); s.Schedule != "" {
+		t.Errorf("unscheduled schedule = %q, want none", s.Schedule)
+	}
+
+	// Next runs are cleared once the scheduler stops
+	current := current.Load()
+	if err := ShutdownScheduler(); err != nil {
+		t.Fatalf("ShutdownScheduler() failed: %v", err)
+	}
+	for _, task := range current.tasks {
+		if task.nextRun != nil {
+			t.Errorf("%s still has a next run after shutdown", task.name)
+		}
+	}
+}
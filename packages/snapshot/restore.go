@@ -0,0 +1,260 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/bootstrap"
+	"github.com/cloudputation/service-seed/packages/buildinfo"
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// Extract reads a snapshot archive and checks every file against the manifest.
+// Files are written under dir; with an empty dir the archive is only verified.
+func Extract(ctx context.Context, r io.Reader, dir string) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	files := map[string]File{}
+	var manifest *Manifest
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("corrupt snapshot archive: %v", err)
+		}
+
+		if hdr.Name == ManifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("invalid snapshot manifest: %v", err)
+			}
+			continue
+		}
+
+		rel, err := entryPath(hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if dir != "" {
+				if err := os.MkdirAll(filepath.Join(dir, rel), os.FileMode(hdr.Mode).Perm()|0700); err != nil {
+					return nil, err
+				}
+			}
+		case tar.TypeReg:
+			file, err := extractFile(tr, hdr, dir, rel)
+			if err != nil {
+				return nil, err
+			}
+			files[file.Path] = file
+		default:
+			return nil, fmt.Errorf("unsupported entry %s in snapshot archive", hdr.Name)
+		}
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("snapshot archive has no %s", ManifestName)
+	}
+	if err := verify(manifest, files); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// entryPath validates an archive entry name and returns its data_dir relative path
+func entryPath(name string) (string, error) {
+	if !strings.HasPrefix(name, dataPrefix) {
+		return "", fmt.Errorf("unexpected entry %s in snapshot archive", name)
+	}
+	rel := path.Clean(strings.TrimPrefix(name, dataPrefix))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") || path.IsAbs(rel) {
+		return "", fmt.Errorf("unsafe entry %s in snapshot archive", name)
+	}
+	return filepath.FromSlash(rel), nil
+}
+
+func extractFile(tr *tar.Reader, hdr *tar.Header, dir, rel string) (File, error) {
+	h := sha256.New()
+	var w io.Writer = h
+
+	if dir != "" {
+		target := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return File{}, err
+		}
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(hdr.Mode).Perm())
+		if err != nil {
+			return File{}, err
+		}
+		defer out.Close()
+		w = io.MultiWriter(out, h)
+	}
+
+	size, err := io.Copy(w, tr)
+	if err != nil {
+		return File{}, fmt.Errorf("corrupt snapshot archive: %v", err)
+	}
+
+	return File{
+		Path:   filepath.ToSlash(rel),
+		Size:   size,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// verify compares extracted files with the manifest
+func verify(manifest *Manifest, files map[string]File) error {
+	if manifest.Checksum != manifest.computeChecksum() {
+		return fmt.Errorf("snapshot manifest checksum mismatch")
+	}
+	for _, want := range manifest.Files {
+		got, ok := files[want.Path]
+		if !ok {
+			return fmt.Errorf("snapshot is missing %s", want.Path)
+		}
+		if got != want {
+			return fmt.Errorf("snapshot checksum mismatch for %s", want.Path)
+		}
+		delete(files, want.Path)
+	}
+	for extra := range files {
+		return fmt.Errorf("snapshot contains %s, which is not in the manifest", extra)
+	}
+	return nil
+}
+
+// Inspect verifies the snapshot archive at path and returns its manifest
+func Inspect(ctx context.Context, path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Extract(ctx, f, "")
+}
+
+// Restore replaces the contents of data_dir with the snapshot at in. The
+// caller must hold the instance lock. The archive is extracted and verified in
+// a staging directory first, so a bad archive leaves data_dir untouched. A
+// data_dir that already holds data is only replaced with force, after its
+// contents are saved as a pre-restore snapshot, whose path is returned.
+func Restore(ctx context.Context, in string, force bool) (*Manifest, string, error) {
+	dataDir := config.ResolvePath(config.AppConfig.DataDir)
+
+	f, err := os.Open(in)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	staging, err := os.MkdirTemp(dataDir, tempRestorePrefix+"*")
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to create staging directory in %s: %v", dataDir, err)
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := Extract(ctx, f, staging)
+	if err != nil {
+		return nil, "", err
+	}
+	if manifest.Service != buildinfo.ServiceName {
+		return nil, "", fmt.Errorf("snapshot belongs to service %q, not %q", manifest.Service, buildinfo.ServiceName)
+	}
+	if manifest.DataVersion > bootstrap.DataVersion() {
+		return nil, "", fmt.Errorf("snapshot has data_dir layout version %d, newer than this binary supports (%d)", manifest.DataVersion, bootstrap.DataVersion())
+	}
+
+	existing, err := restorableEntries(dataDir, staging)
+	if err != nil {
+		return nil, "", err
+	}
+
+	preRestore := ""
+	if len(existing) > 0 {
+		if !force {
+			return nil, "", fmt.Errorf("data_dir %s already holds data; use --force to replace it (its contents are snapshotted first)", dataDir)
+		}
+
+		preRestore, err = savePreRestore(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to snapshot data_dir before restoring: %v", err)
+		}
+		for _, name := range existing {
+			if err := os.RemoveAll(filepath.Join(dataDir, name)); err != nil {
+				return nil, preRestore, err
+			}
+		}
+	}
+
+	// Same filesystem, so each top-level entry moves into place atomically
+	staged, err := os.ReadDir(staging)
+	if err != nil {
+		return nil, preRestore, err
+	}
+	for _, entry := range staged {
+		if err := os.Rename(filepath.Join(staging, entry.Name()), filepath.Join(dataDir, entry.Name())); err != nil {
+			return nil, preRestore, err
+		}
+	}
+
+	return manifest, preRestore, nil
+}
+
+// restorableEntries lists data_dir entries a restore replaces
+func restorableEntries(dataDir, staging string) ([]string, error) {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotDir := config.SnapshotDir()
+	var names []string
+	for _, entry := range entries {
+		path := filepath.Join(dataDir, entry.Name())
+		if path == staging || excluded(entry.Name(), path, snapshotDir) {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+// savePreRestore snapshots data_dir into the snapshot directory under a name
+// Prune never removes
+func savePreRestore(ctx context.Context) (string, error) {
+	dir := config.SnapshotDir()
+	if err := os.MkdirAll(dir, config.AppConfig.Filesystem.Mode()); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "pre-restore-"+NewName(time.Now()))
+	_, _, err := WriteFile(ctx, path)
+	return path, err
+}
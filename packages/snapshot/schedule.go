@@ -0,0 +1,34 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudputation/service-seed/packages/config"
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/scheduler"
+)
+
+// Scheduled snapshots run as the "snapshot" task, scheduled by
+// snapshot.interval_seconds or a schedule "snapshot" block
+func init() {
+	scheduler.Register("snapshot", nil, runScheduled, nil)
+}
+
+// runScheduled takes a snapshot and prunes old ones beyond snapshot.retain
+func runScheduled(ctx context.Context) error {
+	path, manifest, err := Take(ctx, "schedule")
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Snapshot written: %s (%d files)", path, len(manifest.Files)))
+
+	removed, err := Prune(config.SnapshotDir(), *config.AppConfig.Snapshot.Retain)
+	for _, old := range removed {
+		log.Info(fmt.Sprintf("Removed old snapshot: %s", old))
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to prune snapshots: %v", err))
+	}
+	return nil
+}
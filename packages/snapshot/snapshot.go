@@ -0,0 +1,413 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cloudputation/service-seed/packages/bootstrap"
+	"github.com/cloudputation/service-seed/packages/buildinfo"
+	"github.com/cloudputation/service-seed/packages/config"
+	"github.com/cloudputation/service-seed/packages/stats"
+	"github.com/cloudputation/service-seed/packages/storage"
+)
+
+// ManifestName is the archive entry holding the Manifest, written last
+const ManifestName = "manifest.json"
+
+// Extension is the file extension of snapshot archives
+const Extension = ".tar.gz"
+
+// dataPrefix holds data_dir contents inside the archive
+const dataPrefix = "data/"
+
+// Temporary entries created in data_dir while snapshotting or restoring
+const (
+	tempSnapshotPrefix = ".snapshot-"
+	tempRestorePrefix  = ".restore-"
+)
+
+// Manifest describes a snapshot archive
+type Manifest struct {
+	Service     string    `json:"service"`
+	Version     string    `json:"version"`
+	DataVersion int       `json:"data_version"`
+	CreatedAt   time.Time `json:"created_at"`
+	Files       []File    `json:"files"`
+
+	// Checksum covers the file list (path, size and SHA-256 of every file)
+	Checksum string `json:"checksum"`
+}
+
+// File is a regular file in the snapshot, relative to data_dir
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Size returns the total size of the files in the snapshot
+func (m *Manifest) Size() int64 {
+	var size int64
+	for _, f := range m.Files {
+		size += f.Size
+	}
+	return size
+}
+
+func (m *Manifest) computeChecksum() string {
+	h := sha256.New()
+	for _, f := range m.Files {
+		fmt.Fprintf(h, "%s %d %s\n", f.Path, f.Size, f.SHA256)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Write streams a tar.gz snapshot of data_dir to w. The open storage database
+// is copied from a read transaction, so the agent keeps serving writes; other
+// files are copied as they are on disk. Instance lock, migration backups, the
+// snapshot directory and temporary files are skipped.
+func Write(ctx context.Context, w io.Writer) (*Manifest, error) {
+	dataDir := config.ResolvePath(config.AppConfig.DataDir)
+
+	dataVersion, found, err := bootstrap.ReadDataVersion(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		dataVersion = bootstrap.DataVersion()
+	}
+
+	manifest := &Manifest{
+		Service:     buildinfo.ServiceName,
+		Version:     buildinfo.Version,
+		DataVersion: dataVersion,
+		CreatedAt:   time.Now().UTC(),
+		Files:       []File{},
+	}
+
+	gz := gzip.NewWriter(w)
+	a := &archiver{tw: tar.NewWriter(gz), manifest: manifest}
+
+	// A running store is read through a transaction instead of from disk
+	storePath := config.StoragePath()
+	snapshotter, live := storage.DefaultStore.(storage.Snapshotter)
+	snapshotDir := config.SnapshotDir()
+
+	err = filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dataDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if excluded(rel, path, snapshotDir) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return a.addDir(rel, info)
+		case path == storePath && live:
+			return a.addStore(ctx, rel, info, snapshotter)
+		case info.Mode().IsRegular():
+			return a.addFile(path, rel, info)
+		default:
+			// Symlinks, sockets and devices are not data
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to snapshot data_dir %s: %v", dataDir, err)
+	}
+
+	manifest.Checksum = manifest.computeChecksum()
+	if err := a.addManifest(); err != nil {
+		return nil, err
+	}
+	if err := a.tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// excluded reports whether a data_dir entry is left out of snapshots
+func excluded(rel, path, snapshotDir string) bool {
+	if rel == bootstrap.LockFileName || rel == bootstrap.BackupDirName || path == snapshotDir {
+		return true
+	}
+	base := filepath.Base(rel)
+	for _, prefix := range []string{tempSnapshotPrefix, tempRestorePrefix, ".bootstrap-", ".VERSION-"} {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// archiver writes data entries and records them in the manifest
+type archiver struct {
+	tw       *tar.Writer
+	manifest *Manifest
+}
+
+func (a *archiver) header(rel string, info os.FileInfo) (*tar.Header, error) {
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return nil, err
+	}
+	hdr.Name = dataPrefix + filepath.ToSlash(rel)
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	// Ownership is applied by the restoring process, not copied
+	hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+	return hdr, nil
+}
+
+func (a *archiver) addDir(rel string, info os.FileInfo) error {
+	hdr, err := a.header(rel, info)
+	if err != nil {
+		return err
+	}
+	return a.tw.WriteHeader(hdr)
+}
+
+func (a *archiver) addFile(path, rel string, info os.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hdr, err := a.header(rel, info)
+	if err != nil {
+		return err
+	}
+	return a.addContent(hdr, func(w io.Writer) error {
+		// Copy exactly the size in the header even if the file grows meanwhile
+		_, err := io.CopyN(w, f, hdr.Size)
+		return err
+	})
+}
+
+func (a *archiver) addStore(ctx context.Context, rel string, info os.FileInfo, snapshotter storage.Snapshotter) error {
+	return snapshotter.Snapshot(ctx, func(size int64, data io.WriterTo) error {
+		hdr, err := a.header(rel, info)
+		if err != nil {
+			return err
+		}
+		hdr.Size = size
+		return a.addContent(hdr, func(w io.Writer) error {
+			_, err := data.WriteTo(w)
+			return err
+		})
+	})
+}
+
+// addContent writes a regular file entry, hashing it for the manifest
+func (a *archiver) addContent(hdr *tar.Header, write func(w io.Writer) error) error {
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	if err := write(io.MultiWriter(a.tw, h)); err != nil {
+		return err
+	}
+
+	a.manifest.Files = append(a.manifest.Files, File{
+		Path:   strings.TrimPrefix(hdr.Name, dataPrefix),
+		Size:   hdr.Size,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	})
+	return nil
+}
+
+func (a *archiver) addManifest() error {
+	data, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Name:    ManifestName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: a.manifest.CreatedAt,
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = a.tw.Write(data)
+	return err
+}
+
+// WriteFile writes a snapshot to path atomically and returns its manifest and
+// archive size
+func WriteFile(ctx context.Context, path string) (*Manifest, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempSnapshotPrefix+"*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("Failed to create %s: %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	manifest, err := Write(ctx, tmp)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return nil, 0, err
+	}
+	if err := MoveNew(tmp.Name(), path); err != nil {
+		return nil, 0, fmt.Errorf("Failed to write %s: %v", path, err)
+	}
+
+	return manifest, info.Size(), nil
+}
+
+// MoveNew moves the file at from to to, failing when to already exists
+// instead of replacing it. Both paths must be on the same filesystem.
+func MoveNew(from, to string) error {
+	if err := os.Link(from, to); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s already exists", to)
+		}
+		return err
+	}
+	return os.Remove(from)
+}
+
+// NewName returns the archive name for a snapshot taken at t. Milliseconds
+// keep snapshots taken within the same second apart.
+func NewName(t time.Time) string {
+	return buildinfo.ServiceName + "-" + t.UTC().Format("20060102T150405.000Z") + Extension
+}
+
+// ManualName returns the archive name for a backup taken by hand at t, which
+// Prune never removes
+func ManualName(t time.Time) string {
+	return "manual-" + NewName(t)
+}
+
+// Take writes a snapshot into the configured snapshot directory, recording
+// metrics and a trace span labelled with trigger ("api" or "schedule")
+func Take(ctx context.Context, trigger string) (string, *Manifest, error) {
+	var span trace.Span
+	if stats.Tracer != nil {
+		ctx, span = stats.Tracer.Start(ctx, "snapshot.create", trace.WithAttributes(
+			attribute.String("snapshot.trigger", trigger),
+		))
+		defer span.End()
+	}
+
+	timer := stats.NewTimer()
+	path, manifest, size, err := take(ctx)
+	stats.RecordSnapshot(ctx, trigger, size, timer.Elapsed(), err)
+
+	if span != nil {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(
+				attribute.String("snapshot.path", path),
+				attribute.Int("snapshot.files", len(manifest.Files)),
+				attribute.Int64("snapshot.size_bytes", size),
+			)
+		}
+	}
+	return path, manifest, err
+}
+
+func take(ctx context.Context) (string, *Manifest, int64, error) {
+	dir := config.SnapshotDir()
+	if err := os.MkdirAll(dir, config.AppConfig.Filesystem.Mode()); err != nil {
+		return "", nil, 0, fmt.Errorf("Failed to create snapshot directory %s: %v", dir, err)
+	}
+
+	path := filepath.Join(dir, NewName(time.Now()))
+	manifest, size, err := WriteFile(ctx, path)
+	return path, manifest, size, err
+}
+
+// Prune removes the oldest snapshots in dir beyond the newest retain (0 keeps
+// all) and returns the removed paths. Only archives named by NewName are
+// considered, so manual and pre-restore archives are kept.
+func Prune(dir string, retain int) ([]string, error) {
+	if retain <= 0 {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, buildinfo.ServiceName+"-") && strings.HasSuffix(name, Extension) {
+			names = append(names, name)
+		}
+	}
+	if len(names) <= retain {
+		return nil, nil
+	}
+
+	// Timestamps in the names sort chronologically
+	sort.Strings(names)
+
+	var removed []string
+	for _, name := range names[:len(names)-retain] {
+		path := filepath.Join(dir, name)
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
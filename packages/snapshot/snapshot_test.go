@@ -0,0 +1,389 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/bootstrap"
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+// setupDataDir points the configuration at a data_dir holding two data files
+// and the entries snapshots leave out: the instance lock, migration backups,
+// the snapshot directory and a symlink
+func setupDataDir(t *testing.T) string {
+	t.Helper()
+
+	saved := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = saved })
+
+	dataDir := t.TempDir()
+	config.AppConfig = config.Configuration{
+		DataDir:    dataDir,
+		Filesystem: &config.Filesystem{DirMode: "0755"},
+		Snapshot:   &config.Snapshot{Dir: "snapshots"},
+		Storage:    &config.Storage{Path: "storage.db"},
+	}
+
+	files := map[string]string{
+		"notes.txt":            "hello",
+		"sub/item.json":        `{"id": 1}`,
+		bootstrap.LockFileName: "123",
+		filepath.Join(bootstrap.BackupDirName, "old"): "backup",
+		filepath.Join("snapshots", "old"+Extension):   "archive",
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dataDir, name), content)
+	}
+	if err := os.Symlink("notes.txt", filepath.Join(dataDir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	return dataDir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestWriteExtract checks that a snapshot round-trips the data files and
+// leaves out everything else
+func TestWriteExtract(t *testing.T) {
+	setupDataDir(t)
+
+	var buf bytes.Buffer
+	written, err := Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	var paths []string
+	for _, f := range written.Files {
+		paths = append(paths, f.Path)
+	}
+	sort.Strings(paths)
+	if got, want := strings.Join(paths, ","), "notes.txt,sub/item.json"; got != want {
+		t.Errorf("manifest files = %s, want %s", got, want)
+	}
+
+	out := t.TempDir()
+	extracted, err := Extract(context.Background(), &buf, out)
+	if err != nil {
+		t.Fatalf("Extract() failed: %v", err)
+	}
+	if extracted.Checksum != written.Checksum {
+		t.Errorf("Extract() checksum = %s, want %s", extracted.Checksum, written.Checksum)
+	}
+
+	if got := readTestFile(t, filepath.Join(out, "notes.txt")); got != "hello" {
+		t.Errorf("notes.txt = %q, want %q", got, "hello")
+	}
+	if got := readTestFile(t, filepath.Join(out, "sub", "item.json")); got != `{"id": 1}` {
+		t.Errorf("sub/item.json = %q", got)
+	}
+	for _, name := range []string{bootstrap.LockFileName, bootstrap.BackupDirName, "snapshots", "link"} {
+		if _, err := os.Lstat(filepath.Join(out, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was extracted, want it left out of the snapshot", name)
+		}
+	}
+}
+
+// testEntry is an archive entry for buildArchive
+type testEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+// buildArchive writes a snapshot archive by hand. Regular files are listed in
+// the manifest under listed, so the archive passes verification unless an
+// entry itself is rejected.
+func buildArchive(t *testing.T, entries []testEntry, listed map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	a := &archiver{tw: tw, manifest: &Manifest{Service: "test", Files: []File{}}}
+
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0644, Linkname: e.linkname}
+		if e.typeflag != tar.TypeReg {
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		hdr.Size = int64(len(e.content))
+		content := e.content
+		if err := a.addContent(hdr, func(w io.Writer) error {
+			_, err := w.Write([]byte(content))
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a.manifest.Files = a.manifest.Files[:0]
+	for path, content := range listed {
+		a.manifest.Files = append(a.manifest.Files, File{Path: path, Size: int64(len(content)), SHA256: sha256Hex(content)})
+	}
+	a.manifest.Checksum = a.manifest.computeChecksum()
+	if err := a.addManifest(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// TestExtractRejects checks that unsafe entries and archives that do not
+// match their manifest are rejected without writing outside the target
+func TestExtractRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+		listed  map[string]string
+		wantErr string
+	}{
+		{"parent traversal", []testEntry{{name: "data/../x", typeflag: tar.TypeReg, content: "x"}}, nil, "unsafe entry"},
+		{"nested traversal", []testEntry{{name: "data/a/../../x", typeflag: tar.TypeReg, content: "x"}}, nil, "unsafe entry"},
+		{"absolute path", []testEntry{{name: "/x", typeflag: tar.TypeReg, content: "x"}}, nil, "unexpected entry"},
+		{"absolute path under data", []testEntry{{name: "data//x", typeflag: tar.TypeReg, content: "x"}}, nil, "unsafe entry"},
+		{"outside data", []testEntry{{name: "x", typeflag: tar.TypeReg, content: "x"}}, nil, "unexpected entry"},
+		{"symlink", []testEntry{{name: "data/link", typeflag: tar.TypeSymlink, linkname: "../../x"}}, nil, "unsupported entry"},
+		{"hard link", []testEntry{{name: "data/link", typeflag: tar.TypeLink, linkname: "/x"}}, nil, "unsupported entry"},
+		{"file not in manifest", []testEntry{{name: "data/a", typeflag: tar.TypeReg, content: "a"}}, nil, "not in the manifest"},
+		{"missing file", nil, map[string]string{"a": "a"}, "missing a"},
+		{"modified file", []testEntry{{name: "data/a", typeflag: tar.TypeReg, content: "b"}}, map[string]string{"a": "a"}, "checksum mismatch for a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := buildArchive(t, tt.entries, tt.listed)
+
+			parent := t.TempDir()
+			out := filepath.Join(parent, "out")
+			if err := os.Mkdir(out, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Extract(context.Background(), bytes.NewReader(archive), out)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Extract() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(parent, "x")); !os.IsNotExist(err) {
+				t.Errorf("Extract() wrote outside the target directory")
+			}
+		})
+	}
+}
+
+// TestExtractManifestTampered checks that a manifest whose checksum does not
+// cover its file list is rejected
+func TestExtractManifestTampered(t *testing.T) {
+	setupDataDir(t)
+
+	var buf bytes.Buffer
+	if _, err := Write(context.Background(), &buf); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	original, err := Extract(context.Background(), bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		t.Fatalf("Extract() failed: %v", err)
+	}
+
+	// Same files, forged checksum
+	original.Checksum = "sha256:forged"
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := replaceManifest(t, buf.Bytes(), data)
+
+	_, err = Extract(context.Background(), bytes.NewReader(archive), "")
+	if err == nil || !strings.Contains(err.Error(), "manifest checksum mismatch") {
+		t.Fatalf("Extract() error = %v, want a manifest checksum mismatch", err)
+	}
+}
+
+// replaceManifest rewrites the manifest entry of an archive
+func replaceManifest(t *testing.T, archive, manifest []byte) []byte {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	var buf bytes.Buffer
+	out := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name == ManifestName {
+			content = manifest
+			hdr.Size = int64(len(content))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestRestore checks that restore refuses to replace data without force, and
+// with force saves a pre-restore snapshot and swaps in the archive's contents
+// while keeping the lock, migration backups and snapshot directory
+func TestRestore(t *testing.T) {
+	dataDir := setupDataDir(t)
+	ctx := context.Background()
+
+	archive := filepath.Join(t.TempDir(), "backup"+Extension)
+	if _, _, err := WriteFile(ctx, archive); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	writeTestFile(t, filepath.Join(dataDir, "notes.txt"), "changed")
+	writeTestFile(t, filepath.Join(dataDir, "new.txt"), "new")
+
+	if _, _, err := Restore(ctx, archive, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("Restore() without force error = %v, want a request for --force", err)
+	}
+	if got := readTestFile(t, filepath.Join(dataDir, "notes.txt")); got != "changed" {
+		t.Fatalf("Restore() without force changed notes.txt to %q", got)
+	}
+
+	manifest, preRestore, err := Restore(ctx, archive, true)
+	if err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Errorf("Restore() manifest has %d files, want 2", len(manifest.Files))
+	}
+
+	if got := readTestFile(t, filepath.Join(dataDir, "notes.txt")); got != "hello" {
+		t.Errorf("notes.txt = %q after restore, want %q", got, "hello")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("new.txt survived the restore")
+	}
+	for _, name := range []string{bootstrap.LockFileName, filepath.Join(bootstrap.BackupDirName, "old"), filepath.Join("snapshots", "old"+Extension)} {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); err != nil {
+			t.Errorf("%s was not kept: %v", name, err)
+		}
+	}
+
+	if filepath.Dir(preRestore) != config.SnapshotDir() || !strings.HasPrefix(filepath.Base(preRestore), "pre-restore-") {
+		t.Fatalf("pre-restore snapshot = %s, want pre-restore-* in %s", preRestore, config.SnapshotDir())
+	}
+	saved, err := Inspect(ctx, preRestore)
+	if err != nil {
+		t.Fatalf("Inspect(pre-restore) failed: %v", err)
+	}
+	var paths []string
+	for _, f := range saved.Files {
+		paths = append(paths, f.Path)
+	}
+	sort.Strings(paths)
+	if got, want := strings.Join(paths, ","), "new.txt,notes.txt,sub/item.json"; got != want {
+		t.Errorf("pre-restore files = %s, want %s", got, want)
+	}
+
+	// Staging directories are cleaned up
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), tempRestorePrefix) || strings.HasPrefix(entry.Name(), tempSnapshotPrefix) {
+			t.Errorf("temporary entry %s left in data_dir", entry.Name())
+		}
+	}
+}
+
+// TestPrune checks that retention only removes the oldest scheduled snapshots
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var scheduled []string
+	for i := 0; i < 3; i++ {
+		name := NewName(start.Add(time.Duration(i) * time.Hour))
+		scheduled = append(scheduled, name)
+		writeTestFile(t, filepath.Join(dir, name), "")
+	}
+	kept := []string{ManualName(start), "pre-restore-" + NewName(start), "other" + Extension}
+	for _, name := range kept {
+		writeTestFile(t, filepath.Join(dir, name), "")
+	}
+
+	removed, err := Prune(dir, 1)
+	if err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	if len(removed) != 2 || filepath.Base(removed[0]) != scheduled[0] || filepath.Base(removed[1]) != scheduled[1] {
+		t.Errorf("Prune() removed %v, want the two oldest of %v", removed, scheduled)
+	}
+	for _, name := range append(kept, scheduled[2]) {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was removed: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// ============================================================================
+// AUTHENTICATION AND AUTHORIZATION METRICS
+// ============================================================================
+
+var (
+	// AuthRequestsTotal counts authentication outcomes with group, method and
+	// result labels
+	AuthRequestsTotal api.Int64Counter
+
+	// AuthzDecisionsTotal counts authorization decisions with group,
+	// decision and policy labels
+	AuthzDecisionsTotal api.Int64Counter
+)
+
+func init() {
+	RegisterMetrics(initAuthMetrics)
+}
+
+func initAuthMetrics() error {
+	var err error
+
+	AuthRequestsTotal, err = Meter.Int64Counter(
+		"service_auth_requests_total",
+		api.WithDescription("API authentication outcomes by route group, method and result"),
+		api.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_auth_requests_total: %v", err)
+	}
+
+	AuthzDecisionsTotal, err = Meter.Int64Counter(
+		"service_authz_decisions_total",
+		api.WithDescription("API authorization decisions by route group, decision and policy"),
+		api.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_authz_decisions_total: %v", err)
+	}
+
+	return nil
+}
+
+// RecordAuth counts an authentication outcome. method is "token", "hmac",
+// "jwt" or "none"; result is "ok", "anonymous" or "denied". No-op until
+// InitMetrics has run.
+func RecordAuth(ctx context.Context, group, method, result string) {
+	if AuthRequestsTotal == nil {
+		return
+	}
+	AuthRequestsTotal.Add(ctx, 1, api.WithAttributes(
+		attribute.String("group", group),
+		attribute.String("method", method),
+		attribute.String("result", result),
+	))
+}
+
+// RecordAuthz counts an authorization decision. decision is "allow" or
+// "deny"; policy is the allowing policy, or "none". No-op until InitMetrics
+// has run.
+func RecordAuthz(ctx context.Context, group, decision, policy string) {
+	if AuthzDecisionsTotal == nil {
+		return
+	}
+	AuthzDecisionsTotal.Add(ctx, 1, api.WithAttributes(
+		attribute.String("group", group),
+		attribute.String("decision", decision),
+		attribute.String("policy", policy),
+	))
+}
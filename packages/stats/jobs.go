@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// ============================================================================
+// JOB QUEUE METRICS
+// ============================================================================
+
+var (
+	// JobsEnqueuedTotal counts enqueued jobs with a type label
+	JobsEnqueuedTotal api.Int64Counter
+
+	// JobExecutionsTotal counts job runs with type, status labels
+	JobExecutionsTotal api.Int64Counter
+
+	// JobExecutionDuration measures job run time with type, status labels
+	JobExecutionDuration api.Float64Histogram
+
+	// JobStartDelay measures how late jobs start after they are due, with a type label
+	JobStartDelay api.Float64Histogram
+)
+
+func init() {
+	RegisterMetrics(initJobMetrics)
+}
+
+func initJobMetrics() error {
+	var err error
+
+	JobsEnqueuedTotal, err = Meter.Int64Counter(
+		"service_jobs_enqueued_total",
+		api.WithDescription("Background jobs enqueued by type"),
+		api.WithUnit("{job}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_jobs_enqueued_total: %v", err)
+	}
+
+	JobExecutionsTotal, err = Meter.Int64Counter(
+		"service_jobs_executions_total",
+		api.WithDescription("Background job runs by type and status"),
+		api.WithUnit("{execution}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_jobs_executions_total: %v", err)
+	}
+
+	JobExecutionDuration, err = Meter.Float64Histogram(
+		"service_jobs_execution_duration_seconds",
+		api.WithDescription("Background job run time by type and status"),
+		api.WithUnit("s"),
+		api.WithExplicitBucketBoundaries(0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_jobs_execution_duration_seconds: %v", err)
+	}
+
+	JobStartDelay, err = Meter.Float64Histogram(
+		"service_jobs_start_delay_seconds",
+		api.WithDescription("Time between a job becoming due and starting"),
+		api.WithUnit("s"),
+		api.WithExplicitBucketBoundaries(0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_jobs_start_delay_seconds: %v", err)
+	}
+
+	return nil
+}
+
+// RecordJobEnqueued counts an enqueued job. No-op until InitMetrics has run.
+func RecordJobEnqueued(ctx context.Context, jobType string) {
+	if JobsEnqueuedTotal == nil {
+		return
+	}
+	JobsEnqueuedTotal.Add(ctx, 1, api.WithAttributes(attribute.String("type", jobType)))
+}
+
+// RecordJobExecution records a job run. status is "succeeded", "retry",
+// "dead" or "canceled" (interrupted by shutdown and requeued); retries and
+// dead jobs are also counted by RecordError. No-op until InitMetrics has run.
+func RecordJobExecution(ctx context.Context, jobType, status string, delay, duration time.Duration) {
+	if JobExecutionsTotal == nil {
+		return
+	}
+
+	JobExecutionsTotal.Add(ctx, 1, api.WithAttributes(
+		attribute.String("type", jobType),
+		attribute.String("status", status),
+	))
+	JobExecutionDuration.Record(ctx, duration.Seconds(), api.WithAttributes(
+		attribute.String("type", jobType),
+		attribute.String("status", status),
+	))
+	JobStartDelay.Record(ctx, delay.Seconds(), api.WithAttributes(attribute.String("type", jobType)))
+
+	if status == "retry" || status == "dead" {
+		RecordError(ctx, "jobs", status)
+	}
+}
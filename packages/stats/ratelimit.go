@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// ============================================================================
+// RATE LIMITING METRICS
+// ============================================================================
+
+var (
+	// RateLimitThrottledTotal counts requests rejected with 429, with group
+	// and reason labels
+	RateLimitThrottledTotal api.Int64Counter
+
+	// RateLimitInFlight tracks requests being served under the in-flight cap
+	RateLimitInFlight api.Int64UpDownCounter
+)
+
+func init() {
+	RegisterMetrics(initRateLimitMetrics)
+}
+
+func initRateLimitMetrics() error {
+	var err error
+
+	RateLimitThrottledTotal, err = Meter.Int64Counter(
+		"service_ratelimit_throttled_total",
+		api.WithDescription("API requests rejected by rate limiting, by route group and reason"),
+		api.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_ratelimit_throttled_total: %v", err)
+	}
+
+	RateLimitInFlight, err = Meter.Int64UpDownCounter(
+		"service_ratelimit_in_flight",
+		api.WithDescription("API requests being served under the in-flight cap"),
+		api.WithUnit("{request}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_ratelimit_in_flight: %v", err)
+	}
+
+	return nil
+}
+
+// RecordThrottled counts a request rejected with 429. reason is "rate" for
+// a client over its route group's limit or "in_flight" for the global cap.
+// No-op until InitMetrics has run.
+func RecordThrottled(ctx context.Context, group, reason string) {
+	if RateLimitThrottledTotal == nil {
+		return
+	}
+	RateLimitThrottledTotal.Add(ctx, 1, api.WithAttributes(
+		attribute.String("group", group),
+		attribute.String("reason", reason),
+	))
+}
+
+// RecordInFlight adds delta (1 or -1) to the requests in flight. No-op until
+// InitMetrics has run.
+func RecordInFlight(ctx context.Context, delta int64) {
+	if RateLimitInFlight == nil {
+		return
+	}
+	RateLimitInFlight.Add(ctx, delta)
+}
@@ -0,0 +1,79 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// ============================================================================
+// SCHEDULER METRICS
+// ============================================================================
+
+var (
+	// SchedulerRunDuration measures scheduled task run time with task, status labels
+	SchedulerRunDuration api.Float64Histogram
+
+	// SchedulerSkippedTotal counts runs skipped because the previous run was
+	// still going, with a task label
+	SchedulerSkippedTotal api.Int64Counter
+)
+
+func init() {
+	RegisterMetrics(initSchedulerMetrics)
+}
+
+func initSchedulerMetrics() error {
+	var err error
+
+	SchedulerRunDuration, err = Meter.Float64Histogram(
+		"service_scheduler_run_duration_seconds",
+		api.WithDescription("Scheduled task run time by task and status"),
+		api.WithUnit("s"),
+		api.WithExplicitBucketBoundaries(0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_scheduler_run_duration_seconds: %v", err)
+	}
+
+	SchedulerSkippedTotal, err = Meter.Int64Counter(
+		"service_scheduler_skipped_total",
+		api.WithDescription("Scheduled runs skipped because the previous run was still going"),
+		api.WithUnit("{run}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_scheduler_skipped_total: %v", err)
+	}
+
+	return nil
+}
+
+// RecordSchedulerRun records a scheduled task run. status is "ok", "error",
+// "panic" or "canceled"; errors and panics are also counted by RecordError.
+// No-op until InitMetrics has run.
+func RecordSchedulerRun(ctx context.Context, task, status string, duration time.Duration) {
+	if SchedulerRunDuration == nil {
+		return
+	}
+
+	SchedulerRunDuration.Record(ctx, duration.Seconds(), api.WithAttributes(
+		attribute.String("task", task),
+		attribute.String("status", status),
+	))
+
+	if status == "error" || status == "panic" {
+		RecordError(ctx, "scheduler", status)
+	}
+}
+
+// RecordSchedulerSkip counts a run skipped to prevent overlap. No-op until
+// InitMetrics has run.
+func RecordSchedulerSkip(ctx context.Context, task string) {
+	if SchedulerSkippedTotal == nil {
+		return
+	}
+	SchedulerSkippedTotal.Add(ctx, 1, api.WithAttributes(attribute.String("task", task)))
+}
@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// ============================================================================
+// SNAPSHOT METRICS
+// ============================================================================
+
+var (
+	// SnapshotsTotal counts data_dir snapshots with trigger, status labels
+	SnapshotsTotal api.Int64Counter
+
+	// SnapshotDuration measures snapshot creation time with a trigger label
+	SnapshotDuration api.Float64Histogram
+
+	// SnapshotSize records archive sizes with a trigger label
+	SnapshotSize api.Int64Histogram
+)
+
+func init() {
+	RegisterMetrics(initSnapshotMetrics)
+}
+
+func initSnapshotMetrics() error {
+	var err error
+
+	SnapshotsTotal, err = Meter.Int64Counter(
+		"service_snapshots_total",
+		api.WithDescription("Data directory snapshots by trigger and status"),
+		api.WithUnit("{snapshot}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_snapshots_total: %v", err)
+	}
+
+	SnapshotDuration, err = Meter.Float64Histogram(
+		"service_snapshot_duration_seconds",
+		api.WithDescription("Data directory snapshot creation time"),
+		api.WithUnit("s"),
+		api.WithExplicitBucketBoundaries(0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_snapshot_duration_seconds: %v", err)
+	}
+
+	SnapshotSize, err = Meter.Int64Histogram(
+		"service_snapshot_size_bytes",
+		api.WithDescription("Compressed size of data directory snapshots"),
+		api.WithUnit("By"),
+		api.WithExplicitBucketBoundaries(1<<10, 1<<15, 1<<20, 1<<25, 1<<30, 1<<35),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_snapshot_size_bytes: %v", err)
+	}
+
+	return nil
+}
+
+// RecordSnapshot records a snapshot. trigger is "api", "schedule" or "restore";
+// size is ignored when err is set. No-op until InitMetrics has run (e.g. the
+// offline backup command).
+func RecordSnapshot(ctx context.Context, trigger string, size int64, duration time.Duration, err error) {
+	if SnapshotsTotal == nil {
+		return
+	}
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+		RecordError(ctx, "snapshot", trigger)
+	}
+
+	SnapshotsTotal.Add(ctx, 1, api.WithAttributes(
+		attribute.String("trigger", trigger),
+		attribute.String("status", status),
+	))
+	SnapshotDuration.Record(ctx, duration.Seconds(), api.WithAttributes(attribute.String("trigger", trigger)))
+	if err == nil {
+		SnapshotSize.Record(ctx, size, api.WithAttributes(attribute.String("trigger", trigger)))
+	}
+}
@@ -13,20 +13,14 @@ import (
 	"go.opentelemetry.io/otel/exporters/prometheus"
 	api "go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/sdk/metric"
-	"go.opentelemetry.io/otel/sdk/resource"
-	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"google.golang.org/grpc/credentials"
 
+	"github.com/cloudputation/service-seed/packages/buildinfo"
 	"github.com/cloudputation/service-seed/packages/config"
 )
 
-const meterName = "service-seed"
-
-// Build-time variables injected via ldflags
-var (
-	Version     = "dev"
-	Environment = "development"
-)
+// meterName follows the service name so renamed services report their own scope
+const meterName = buildinfo.ServiceName
 
 // Meter is exported for use by helper functions
 var Meter api.Meter
@@ -43,6 +37,7 @@ var (
 	ErrorCounter                 api.Int64Counter
 	HealthEndpointCounter        api.Int64Counter
 	SystemMetricsEndpointCounter api.Int64Counter
+	SystemStatusEndpointCounter  api.Int64Counter
 )
 
 // ============================================================================
@@ -66,20 +61,31 @@ var (
 	ErrorsTotal api.Int64Counter
 )
 
+// ============================================================================
+// GAUGE METRICS
+// ============================================================================
+
+var (
+	// BuildInfoGauge is always 1, labelled with version, revision and go_version
+	BuildInfoGauge api.Int64ObservableGauge
+)
+
 // ============================================================================
 // INITIALIZATION
 // ============================================================================
 
+// metricInitializers create metrics declared in other files of this package
+// (e.g. counters generated by `service-seed scaffold endpoint`)
+var metricInitializers []func() error
+
+// RegisterMetrics adds an initializer that InitMetrics runs once Meter is ready
+func RegisterMetrics(init func() error) {
+	metricInitializers = append(metricInitializers, init)
+}
+
 func InitMetrics() error {
-	// Create resource with service attributes
-	// Note: Using NewWithAttributes directly to avoid schema version conflicts
-	// between resource.Default() (v1.37.0) and semconv (v1.24.0)
-	res := resource.NewWithAttributes(
-		semconv.SchemaURL,
-		semconv.ServiceName("service-seed"),
-		semconv.ServiceVersion(Version),
-		semconv.DeploymentEnvironment(Environment),
-	)
+	// Create resource with service and build attributes
+	res := buildinfo.Resource()
 
 	// Always create Prometheus exporter (backward compatibility)
 	prometheusExporter, err := prometheus.New()
@@ -126,6 +132,11 @@ func InitMetrics() error {
 	if err := initGaugeMetrics(); err != nil {
 		return err
 	}
+	for _, init := range metricInitializers {
+		if err := init(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -157,6 +168,14 @@ func initLegacyMetrics() error {
 		return fmt.Errorf("failed to initialize system metrics endpoint counter: %v", err)
 	}
 
+	SystemStatusEndpointCounter, err = Meter.Int64Counter(
+		"system_status_endpoint_hits",
+		api.WithDescription("Counts the number of hits to the /system/status endpoint"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize system status endpoint counter: %v", err)
+	}
+
 	return nil
 }
 
@@ -201,7 +220,20 @@ func initErrorMetrics() error {
 }
 
 func initGaugeMetrics() error {
-	// Placeholder for future gauge metrics
+	var err error
+
+	BuildInfoGauge, err = Meter.Int64ObservableGauge(
+		"service_build_info",
+		api.WithDescription("Build information (version, revision, go_version); value is always 1"),
+		api.WithInt64Callback(func(ctx context.Context, o api.Int64Observer) error {
+			o.Observe(1, api.WithAttributes(buildinfo.Attributes()...))
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_build_info: %v", err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// ============================================================================
+// STORAGE METRICS
+// ============================================================================
+
+var (
+	// StorageOperationsTotal counts store operations with engine, operation, status labels
+	StorageOperationsTotal api.Int64Counter
+
+	// StorageOperationDuration measures store operation latency with engine, operation labels
+	StorageOperationDuration api.Float64Histogram
+)
+
+func init() {
+	RegisterMetrics(initStorageMetrics)
+}
+
+func initStorageMetrics() error {
+	var err error
+
+	StorageOperationsTotal, err = Meter.Int64Counter(
+		"service_storage_operations_total",
+		api.WithDescription("Storage operations by engine, operation, and status"),
+		api.WithUnit("{operation}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_storage_operations_total: %v", err)
+	}
+
+	StorageOperationDuration, err = Meter.Float64Histogram(
+		"service_storage_operation_duration_seconds",
+		api.WithDescription("Storage operation latency"),
+		api.WithUnit("s"),
+		api.WithExplicitBucketBoundaries(0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_storage_operation_duration_seconds: %v", err)
+	}
+
+	return nil
+}
+
+// RecordStorageOperation records a store operation. status is "ok", "not_found"
+// or "error"; errors are also counted by RecordError. No-op until InitMetrics
+// has run (e.g. stores opened in tests).
+func RecordStorageOperation(ctx context.Context, engine, operation, status string, duration time.Duration) {
+	if StorageOperationsTotal == nil {
+		return
+	}
+
+	StorageOperationsTotal.Add(ctx, 1, api.WithAttributes(
+		attribute.String("engine", engine),
+		attribute.String("operation", operation),
+		attribute.String("status", status),
+	))
+	StorageOperationDuration.Record(ctx, duration.Seconds(), api.WithAttributes(
+		attribute.String("engine", engine),
+		attribute.String("operation", operation),
+	))
+
+	if status == "error" {
+		RecordError(ctx, "storage", operation)
+	}
+}
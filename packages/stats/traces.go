@@ -8,12 +8,11 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
-	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/credentials"
 
+	"github.com/cloudputation/service-seed/packages/buildinfo"
 	"github.com/cloudputation/service-seed/packages/config"
 )
 
@@ -56,12 +55,7 @@ func InitTraces(t *config.Telemetry) error {
 	}
 
 	// Create resource (same as metrics)
-	res := resource.NewWithAttributes(
-		semconv.SchemaURL,
-		semconv.ServiceName("service-seed"),
-		semconv.ServiceVersion(Version),
-		semconv.DeploymentEnvironment(Environment),
-	)
+	res := buildinfo.Resource()
 
 	// Create sampler based on config
 	var sampler sdktrace.Sampler
@@ -80,7 +74,7 @@ func InitTraces(t *config.Telemetry) error {
 
 	// Set global tracer provider
 	otel.SetTracerProvider(tracerProvider)
-	Tracer = tracerProvider.Tracer("service-seed")
+	Tracer = tracerProvider.Tracer(buildinfo.ServiceName)
 
 	return nil
 }
@@ -0,0 +1,142 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	api "go.opentelemetry.io/otel/metric"
+)
+
+// ============================================================================
+// WORKER POOL METRICS
+// ============================================================================
+
+var (
+	// WorkerQueueDepth tracks tasks waiting for a worker with a pool label
+	WorkerQueueDepth api.Int64UpDownCounter
+
+	// WorkerActive tracks tasks currently running with a pool label
+	WorkerActive api.Int64UpDownCounter
+
+	// WorkerTaskDuration measures task run time with pool, task, status labels
+	WorkerTaskDuration api.Float64Histogram
+
+	// WorkerTaskWait measures time spent queued with pool, task labels
+	WorkerTaskWait api.Float64Histogram
+
+	// WorkerRejectedTotal counts refused submissions with pool, task, reason labels
+	WorkerRejectedTotal api.Int64Counter
+)
+
+func init() {
+	RegisterMetrics(initWorkerMetrics)
+}
+
+func initWorkerMetrics() error {
+	var err error
+
+	WorkerQueueDepth, err = Meter.Int64UpDownCounter(
+		"service_workers_queue_depth",
+		api.WithDescription("Tasks waiting for a worker"),
+		api.WithUnit("{task}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_workers_queue_depth: %v", err)
+	}
+
+	WorkerActive, err = Meter.Int64UpDownCounter(
+		"service_workers_active",
+		api.WithDescription("Workers currently running a task"),
+		api.WithUnit("{worker}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_workers_active: %v", err)
+	}
+
+	WorkerTaskDuration, err = Meter.Float64Histogram(
+		"service_workers_task_duration_seconds",
+		api.WithDescription("Worker task run time by pool, task, and status"),
+		api.WithUnit("s"),
+		api.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_workers_task_duration_seconds: %v", err)
+	}
+
+	WorkerTaskWait, err = Meter.Float64Histogram(
+		"service_workers_task_wait_seconds",
+		api.WithDescription("Time worker tasks spend queued"),
+		api.WithUnit("s"),
+		api.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_workers_task_wait_seconds: %v", err)
+	}
+
+	WorkerRejectedTotal, err = Meter.Int64Counter(
+		"service_workers_rejected_total",
+		api.WithDescription("Worker task submissions refused by pool, task, and reason"),
+		api.WithUnit("{task}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize service_workers_rejected_total: %v", err)
+	}
+
+	return nil
+}
+
+// AddWorkerQueueDepth adjusts the queue depth of a pool. No-op until
+// InitMetrics has run.
+func AddWorkerQueueDepth(ctx context.Context, pool string, delta int64) {
+	if WorkerQueueDepth == nil {
+		return
+	}
+	WorkerQueueDepth.Add(ctx, delta, api.WithAttributes(attribute.String("pool", pool)))
+}
+
+// AddWorkerActive adjusts the running task count of a pool. No-op until
+// InitMetrics has run.
+func AddWorkerActive(ctx context.Context, pool string, delta int64) {
+	if WorkerActive == nil {
+		return
+	}
+	WorkerActive.Add(ctx, delta, api.WithAttributes(attribute.String("pool", pool)))
+}
+
+// RecordWorkerTask records a finished task. status is "ok", "error", "panic"
+// or "canceled" (skipped or cut short by shutdown or the submitter); errors
+// and panics are also counted by RecordError. No-op until InitMetrics has run.
+func RecordWorkerTask(ctx context.Context, pool, task, status string, wait, duration time.Duration) {
+	if WorkerTaskDuration == nil {
+		return
+	}
+
+	WorkerTaskWait.Record(ctx, wait.Seconds(), api.WithAttributes(
+		attribute.String("pool", pool),
+		attribute.String("task", task),
+	))
+	WorkerTaskDuration.Record(ctx, duration.Seconds(), api.WithAttributes(
+		attribute.String("pool", pool),
+		attribute.String("task", task),
+		attribute.String("status", status),
+	))
+
+	if status == "error" || status == "panic" {
+		RecordError(ctx, "workers", status)
+	}
+}
+
+// RecordWorkerRejection counts a refused submission. reason is "queue_full"
+// or "stopped". No-op until InitMetrics has run.
+func RecordWorkerRejection(ctx context.Context, pool, task, reason string) {
+	if WorkerRejectedTotal == nil {
+		return
+	}
+	WorkerRejectedTotal.Add(ctx, 1, api.WithAttributes(
+		attribute.String("pool", pool),
+		attribute.String("task", task),
+		attribute.String("reason", reason),
+	))
+}
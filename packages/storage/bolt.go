@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	berrors "go.etcd.io/bbolt/errors"
+)
+
+// boltBucket holds every key; prefixes provide namespacing
+var boltBucket = []byte("kv")
+
+// Bolt is a Store backed by a single bbolt database file
+type Bolt struct {
+	db       *bolt.DB
+	watchers watchers
+
+	// writeMu orders commits with their Watch events
+	writeMu sync.Mutex
+}
+
+// OpenBolt opens or creates the database file at path, waiting up to timeout
+// for its file lock
+func OpenBolt(path string, timeout time.Duration) (*Bolt, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: timeout})
+	if errors.Is(err, berrors.ErrTimeout) {
+		return nil, fmt.Errorf("Failed to open storage %s: database is locked by another process", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open storage %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Failed to initialize storage %s: %v", path, err)
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// Path returns the database file path
+func (b *Bolt) Path() string {
+	return b.db.Path()
+}
+
+func (b *Bolt) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := b.View(ctx, func(tx Txn) error {
+		var err error
+		value, err = tx.Get(key)
+		return err
+	})
+	return value, err
+}
+
+func (b *Bolt) Put(ctx context.Context, key string, value []byte) error {
+	return b.Update(ctx, func(tx Txn) error {
+		return tx.Put(key, value)
+	})
+}
+
+func (b *Bolt) Delete(ctx context.Context, key string) error {
+	return b.Update(ctx, func(tx Txn) error {
+		return tx.Delete(key)
+	})
+}
+
+func (b *Bolt) List(ctx context.Context, prefix string) ([]KV, error) {
+	var kvs []KV
+	err := b.View(ctx, func(tx Txn) error {
+		var err error
+		kvs, err = tx.List(prefix)
+		return err
+	})
+	return kvs, err
+}
+
+func (b *Bolt) View(ctx context.Context, fn func(tx Txn) error) error {
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return fn(&boltTxn{bucket: tx.Bucket(boltBucket)})
+	})
+	return boltError(err)
+}
+
+func (b *Bolt) Update(ctx context.Context, fn func(tx Txn) error) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	txn := &boltTxn{writable: true}
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		txn.bucket = tx.Bucket(boltBucket)
+		return fn(txn)
+	})
+	if err != nil {
+		return boltError(err)
+	}
+
+	b.watchers.publish(txn.events)
+	return nil
+}
+
+func (b *Bolt) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	return b.watchers.add(ctx, prefix)
+}
+
+// Snapshot streams the database file as of a read transaction
+func (b *Bolt) Snapshot(ctx context.Context, fn func(size int64, data io.WriterTo) error) error {
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Size(), tx)
+	})
+	return boltError(err)
+}
+
+// Close waits for open transactions and closes the database file
+func (b *Bolt) Close() error {
+	b.watchers.close()
+	return b.db.Close()
+}
+
+// boltError maps bbolt errors to the package errors
+func boltError(err error) error {
+	switch {
+	case errors.Is(err, berrors.ErrDatabaseNotOpen):
+		return ErrClosed
+	case errors.Is(err, berrors.ErrTxNotWritable):
+		return ErrReadOnly
+	case errors.Is(err, berrors.ErrKeyRequired):
+		return ErrEmptyKey
+	}
+	return err
+}
+
+// boltTxn copies values out of the transaction, since bbolt memory is only
+// valid until it ends
+type boltTxn struct {
+	bucket   *bolt.Bucket
+	writable bool
+	events   []Event
+}
+
+func (tx *boltTxn) Get(key string) ([]byte, error) {
+	if key == "" {
+		return nil, ErrEmptyKey
+	}
+
+	value := tx.bucket.Get([]byte(key))
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (tx *boltTxn) Put(key string, value []byte) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	if key == "" {
+		return ErrEmptyKey
+	}
+
+	stored := append([]byte{}, value...)
+	if err := tx.bucket.Put([]byte(key), stored); err != nil {
+		return boltError(err)
+	}
+	tx.events = append(tx.events, Event{Type: EventPut, Key: key, Value: append([]byte{}, stored...)})
+	return nil
+}
+
+func (tx *boltTxn) Delete(key string) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	if _, err := tx.Get(key); err != nil {
+		return err
+	}
+
+	if err := tx.bucket.Delete([]byte(key)); err != nil {
+		return boltError(err)
+	}
+	tx.events = append(tx.events, Event{Type: EventDelete, Key: key})
+	return nil
+}
+
+func (tx *boltTxn) List(prefix string) ([]KV, error) {
+	kvs := []KV{}
+	p := []byte(prefix)
+
+	c := tx.bucket.Cursor()
+	for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+		kvs = append(kvs, KV{Key: string(k), Value: append([]byte{}, v...)})
+	}
+	return kvs, nil
+}
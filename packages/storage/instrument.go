@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/cloudputation/service-seed/packages/stats"
+)
+
+// instrumented records stats metrics and a trace span for every operation
+type instrumented struct {
+	engine string
+	store  Store
+}
+
+// Instrument wraps store with metrics labelled engine and trace spans
+// (when tracing is enabled)
+func Instrument(engine string, store Store) Store {
+	return &instrumented{engine: engine, store: store}
+}
+
+// observe runs op inside a span named storage.<operation> and records its
+// outcome. ErrNotFound is an expected result, not a span error.
+func (s *instrumented) observe(ctx context.Context, operation, key string, op func(ctx context.Context) error) error {
+	var span trace.Span
+	if stats.Tracer != nil {
+		ctx, span = stats.Tracer.Start(ctx, "storage."+operation, trace.WithAttributes(
+			attribute.String("storage.engine", s.engine),
+		))
+		if key != "" {
+			span.SetAttributes(attribute.String("storage.key", key))
+		}
+		defer span.End()
+	}
+
+	timer := stats.NewTimer()
+	err := op(ctx)
+
+	status := "ok"
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = "not_found"
+	case err != nil:
+		status = "error"
+		if span != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	stats.RecordStorageOperation(ctx, s.engine, operation, status, timer.Elapsed())
+
+	return err
+}
+
+func (s *instrumented) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.observe(ctx, "get", key, func(ctx context.Context) error {
+		var err error
+		value, err = s.store.Get(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (s *instrumented) Put(ctx context.Context, key string, value []byte) error {
+	return s.observe(ctx, "put", key, func(ctx context.Context) error {
+		return s.store.Put(ctx, key, value)
+	})
+}
+
+func (s *instrumented) Delete(ctx context.Context, key string) error {
+	return s.observe(ctx, "delete", key, func(ctx context.Context) error {
+		return s.store.Delete(ctx, key)
+	})
+}
+
+func (s *instrumented) List(ctx context.Context, prefix string) ([]KV, error) {
+	var kvs []KV
+	err := s.observe(ctx, "list", prefix, func(ctx context.Context) error {
+		var err error
+		kvs, err = s.store.List(ctx, prefix)
+		return err
+	})
+	return kvs, err
+}
+
+func (s *instrumented) View(ctx context.Context, fn func(tx Txn) error) error {
+	return s.observe(ctx, "view", "", func(ctx context.Context) error {
+		return s.store.View(ctx, fn)
+	})
+}
+
+func (s *instrumented) Update(ctx context.Context, fn func(tx Txn) error) error {
+	return s.observe(ctx, "update", "", func(ctx context.Context) error {
+		return s.store.Update(ctx, fn)
+	})
+}
+
+func (s *instrumented) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	var events <-chan Event
+	err := s.observe(ctx, "watch", prefix, func(_ context.Context) error {
+		var err error
+		// The subscription outlives the span; keep the caller's context
+		events, err = s.store.Watch(ctx, prefix)
+		return err
+	})
+	return events, err
+}
+
+// Snapshot delegates to engines that implement Snapshotter
+func (s *instrumented) Snapshot(ctx context.Context, fn func(size int64, data io.WriterTo) error) error {
+	return s.observe(ctx, "snapshot", "", func(ctx context.Context) error {
+		snapshotter, ok := s.store.(Snapshotter)
+		if !ok {
+			return ErrSnapshotUnsupported
+		}
+		return snapshotter.Snapshot(ctx, fn)
+	})
+}
+
+func (s *instrumented) Close() error {
+	return s.store.Close()
+}
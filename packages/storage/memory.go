@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is a Store held in memory. Contents are lost on Close; use it in
+// tests or with storage.engine = "memory".
+type Memory struct {
+	mu       sync.RWMutex
+	data     map[string][]byte
+	closed   bool
+	watchers watchers
+}
+
+// NewMemory returns an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{data: map[string][]byte{}}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := m.View(ctx, func(tx Txn) error {
+		var err error
+		value, err = tx.Get(key)
+		return err
+	})
+	return value, err
+}
+
+func (m *Memory) Put(ctx context.Context, key string, value []byte) error {
+	return m.Update(ctx, func(tx Txn) error {
+		return tx.Put(key, value)
+	})
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	return m.Update(ctx, func(tx Txn) error {
+		return tx.Delete(key)
+	})
+}
+
+func (m *Memory) List(ctx context.Context, prefix string) ([]KV, error) {
+	var kvs []KV
+	err := m.View(ctx, func(tx Txn) error {
+		var err error
+		kvs, err = tx.List(prefix)
+		return err
+	})
+	return kvs, err
+}
+
+func (m *Memory) View(ctx context.Context, fn func(tx Txn) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	return fn(&memoryTxn{store: m})
+}
+
+// Update buffers writes and applies them under the write lock only when fn
+// succeeds, so a failed transaction leaves no trace
+func (m *Memory) Update(ctx context.Context, fn func(tx Txn) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	tx := &memoryTxn{store: m, writable: true, writes: map[string][]byte{}}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	for key, value := range tx.writes {
+		if value == nil {
+			delete(m.data, key)
+		} else {
+			m.data[key] = value
+		}
+	}
+	m.watchers.publish(tx.events)
+	return nil
+}
+
+func (m *Memory) Watch(ctx context.Context, prefix string) (<-chan Event, error) {
+	return m.watchers.add(ctx, prefix)
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	m.data = nil
+	m.watchers.close()
+	return nil
+}
+
+// memoryTxn reads through its own pending writes to the committed data.
+// A nil entry in writes marks a pending delete.
+type memoryTxn struct {
+	store    *Memory
+	writable bool
+	writes   map[string][]byte
+	events   []Event
+}
+
+func (tx *memoryTxn) Get(key string) ([]byte, error) {
+	if key == "" {
+		return nil, ErrEmptyKey
+	}
+
+	value, ok := tx.writes[key]
+	if !ok {
+		value, ok = tx.store.data[key]
+	}
+	if !ok || value == nil {
+		return nil, ErrNotFound
+	}
+	return copyBytes(value), nil
+}
+
+func (tx *memoryTxn) Put(key string, value []byte) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	if key == "" {
+		return ErrEmptyKey
+	}
+
+	// Empty values are stored as non-nil so they stay distinct from deletes
+	stored := append([]byte{}, value...)
+	tx.writes[key] = stored
+	tx.events = append(tx.events, Event{Type: EventPut, Key: key, Value: copyBytes(stored)})
+	return nil
+}
+
+func (tx *memoryTxn) Delete(key string) error {
+	if !tx.writable {
+		return ErrReadOnly
+	}
+	if _, err := tx.Get(key); err != nil {
+		return err
+	}
+
+	tx.writes[key] = nil
+	tx.events = append(tx.events, Event{Type: EventDelete, Key: key})
+	return nil
+}
+
+func (tx *memoryTxn) List(prefix string) ([]KV, error) {
+	merged := map[string][]byte{}
+	for key, value := range tx.store.data {
+		if strings.HasPrefix(key, prefix) {
+			merged[key] = value
+		}
+	}
+	for key, value := range tx.writes {
+		if strings.HasPrefix(key, prefix) {
+			merged[key] = value
+		}
+	}
+
+	kvs := make([]KV, 0, len(merged))
+	for key, value := range merged {
+		if value != nil {
+			kvs = append(kvs, KV{Key: key, Value: copyBytes(value)})
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs, nil
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/config"
+)
+
+var (
+	// ErrNotFound is returned by Get and Delete when the key does not exist
+	ErrNotFound = errors.New("key not found")
+
+	// ErrEmptyKey is returned when a key is the empty string
+	ErrEmptyKey = errors.New("key must not be empty")
+
+	// ErrReadOnly is returned by writes inside a View transaction
+	ErrReadOnly = errors.New("transaction is read-only")
+
+	// ErrClosed is returned by every operation after Close
+	ErrClosed = errors.New("store is closed")
+
+	// ErrSnapshotUnsupported is returned by Snapshot for engines without a database file
+	ErrSnapshotUnsupported = errors.New("storage engine does not support snapshots")
+)
+
+// Store is a transactional key-value store. Keys are strings; use
+// "/"-separated prefixes to namespace data (e.g. "todos/<id>").
+type Store interface {
+	// Get returns the value stored at key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Put stores value at key, replacing any existing value
+	Put(ctx context.Context, key string, value []byte) error
+
+	// Delete removes key, or returns ErrNotFound
+	Delete(ctx context.Context, key string) error
+
+	// List returns every pair whose key starts with prefix, ordered by key
+	List(ctx context.Context, prefix string) ([]KV, error)
+
+	// View runs fn in a read-only transaction
+	View(ctx context.Context, fn func(tx Txn) error) error
+
+	// Update runs fn in a read-write transaction, committed when fn returns nil
+	// and rolled back otherwise
+	Update(ctx context.Context, fn func(tx Txn) error) error
+
+	// Watch streams committed changes to keys starting with prefix until ctx is
+	// done. The channel is closed when ctx ends, the store closes or the
+	// receiver falls too far behind; re-List and Watch again to resume.
+	Watch(ctx context.Context, prefix string) (<-chan Event, error)
+
+	// Close releases the store; later operations return ErrClosed
+	Close() error
+}
+
+// Snapshotter is implemented by stores kept in a database file. Snapshot calls
+// fn with the size and contents of a consistent copy of the file, taken in a
+// read transaction so writers are not blocked.
+type Snapshotter interface {
+	Snapshot(ctx context.Context, fn func(size int64, data io.WriterTo) error) error
+}
+
+// Txn is a transaction opened by View or Update. It must not be used after
+// the callback returns.
+type Txn interface {
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte) error
+	Delete(key string) error
+	List(prefix string) ([]KV, error)
+}
+
+// KV is a key and its value
+type KV struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// EventType is the kind of change reported by Watch
+type EventType string
+
+const (
+	EventPut    EventType = "put"
+	EventDelete EventType = "delete"
+)
+
+// Event is a committed change. Value is nil for deletes.
+type Event struct {
+	Type  EventType `json:"type"`
+	Key   string    `json:"key"`
+	Value []byte    `json:"value,omitempty"`
+}
+
+// DefaultStore is the store opened by InitStorage from the storage block
+var DefaultStore Store
+
+// InitStorage opens the configured engine and sets DefaultStore. Runs after
+// bootstrap (data_dir exists and is locked) and metrics initialization.
+func InitStorage() error {
+	store, err := Open(config.AppConfig.Storage)
+	if err != nil {
+		return err
+	}
+	DefaultStore = store
+	return nil
+}
+
+// CloseStorage closes DefaultStore. Safe to call when it was never opened.
+func CloseStorage() error {
+	if DefaultStore == nil {
+		return nil
+	}
+	store := DefaultStore
+	DefaultStore = nil
+	return store.Close()
+}
+
+// Open returns an instrumented store for the given settings
+func Open(cfg *config.Storage) (Store, error) {
+	switch cfg.Engine {
+	case "memory":
+		return Instrument("memory", NewMemory()), nil
+	case "bolt":
+		store, err := OpenBolt(config.StoragePath(), time.Duration(cfg.TimeoutSeconds)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return Instrument("bolt", store), nil
+	default:
+		return nil, fmt.Errorf("unknown storage engine %q", cfg.Engine)
+	}
+}
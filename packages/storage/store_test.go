@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// backends opens an empty store of each engine, closed when the test ends
+var backends = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"memory", func(t *testing.T) Store {
+		return NewMemory()
+	}},
+	{"bolt", func(t *testing.T) Store {
+		store, err := OpenBolt(filepath.Join(t.TempDir(), "store.db"), time.Second)
+		if err != nil {
+			t.Fatalf("OpenBolt() failed: %v", err)
+		}
+		return store
+	}},
+}
+
+// forEachBackend runs test against a fresh store of every engine
+func forEachBackend(t *testing.T, test func(t *testing.T, s Store)) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			s := backend.open(t)
+			t.Cleanup(func() { s.Close() })
+			test(t, s)
+		})
+	}
+}
+
+func put(t *testing.T, s Store, kvs ...string) {
+	t.Helper()
+	for i := 0; i < len(kvs); i += 2 {
+		if err := s.Put(context.Background(), kvs[i], []byte(kvs[i+1])); err != nil {
+			t.Fatalf("Put(%s) failed: %v", kvs[i], err)
+		}
+	}
+}
+
+// keys lists the keys under prefix, in the order List returns them
+func keys(t *testing.T, s Store, prefix string) []string {
+	t.Helper()
+	kvs, err := s.List(context.Background(), prefix)
+	if err != nil {
+		t.Fatalf("List(%q) failed: %v", prefix, err)
+	}
+	names := []string{}
+	for _, kv := range kvs {
+		names = append(names, kv.Key)
+	}
+	return names
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestGetPutDelete checks single-key operations and their errors
+func TestGetPutDelete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+
+		if _, err := s.Get(ctx, "todos/1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get() of a missing key = %v, want ErrNotFound", err)
+		}
+		if err := s.Delete(ctx, "todos/1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Delete() of a missing key = %v, want ErrNotFound", err)
+		}
+
+		put(t, s, "todos/1", "first", "todos/1", "replaced", "empty", "")
+		value, err := s.Get(ctx, "todos/1")
+		if err != nil || string(value) != "replaced" {
+			t.Errorf("Get() = %q, %v; want replaced", value, err)
+		}
+		if value, err := s.Get(ctx, "empty"); err != nil || len(value) != 0 {
+			t.Errorf("Get() of an empty value = %q, %v; want an empty value", value, err)
+		}
+
+		// Returned values are copies
+		value[0] = 'X'
+		if again, _ := s.Get(ctx, "todos/1"); string(again) != "replaced" {
+			t.Errorf("changing a returned value changed the store: %q", again)
+		}
+
+		if err := s.Delete(ctx, "todos/1"); err != nil {
+			t.Fatalf("Delete() failed: %v", err)
+		}
+		if _, err := s.Get(ctx, "todos/1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get() after Delete() = %v, want ErrNotFound", err)
+		}
+
+		if err := s.Put(ctx, "", []byte("v")); !errors.Is(err, ErrEmptyKey) {
+			t.Errorf("Put() of an empty key = %v, want ErrEmptyKey", err)
+		}
+		if _, err := s.Get(ctx, ""); !errors.Is(err, ErrEmptyKey) {
+			t.Errorf("Get() of an empty key = %v, want ErrEmptyKey", err)
+		}
+	})
+}
+
+// TestList checks prefix matching and key ordering
+func TestList(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"a", "todos/1", "todos/10", "todos/2", "todosx", "users/1"}},
+		{"todos/", []string{"todos/1", "todos/10", "todos/2"}},
+		{"todos", []string{"todos/1", "todos/10", "todos/2", "todosx"}},
+		{"todos/1", []string{"todos/1", "todos/10"}},
+		{"missing/", []string{}},
+	}
+
+	forEachBackend(t, func(t *testing.T, s Store) {
+		put(t, s, "users/1", "u", "todos/2", "b", "todosx", "x", "todos/10", "c", "a", "a", "todos/1", "a")
+
+		for _, tt := range tests {
+			if got := keys(t, s, tt.prefix); !equal(got, tt.want) {
+				t.Errorf("List(%q) = %v, want %v", tt.prefix, got, tt.want)
+			}
+		}
+
+		kvs, err := s.List(context.Background(), "todos/2")
+		if err != nil || len(kvs) != 1 || string(kvs[0].Value) != "b" {
+			t.Errorf("List(todos/2) = %v, %v; want its value", kvs, err)
+		}
+	})
+}
+
+// TestUpdate checks that a transaction sees its own writes, commits them
+// together, and leaves no trace when fn fails
+func TestUpdate(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		put(t, s, "keep", "old", "gone", "old")
+
+		events, err := s.Watch(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		failure := errors.New("abort")
+		err = s.Update(ctx, func(tx Txn) error {
+			if err := tx.Put("keep", []byte("new")); err != nil {
+				return err
+			}
+			if err := tx.Put("added", []byte("new")); err != nil {
+				return err
+			}
+			if err := tx.Delete("gone"); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Update() = %v, want the error returned by fn", err)
+		}
+		if got := keys(t, s, ""); !equal(got, []string{"gone", "keep"}) {
+			t.Errorf("keys after a failed Update() = %v, want [gone keep]", got)
+		}
+		if value, _ := s.Get(ctx, "keep"); string(value) != "old" {
+			t.Errorf("keep = %q after a failed Update(), want old", value)
+		}
+
+		err = s.Update(ctx, func(tx Txn) error {
+			if err := tx.Put("added", []byte("new")); err != nil {
+				return err
+			}
+			if err := tx.Delete("gone"); err != nil {
+				return err
+			}
+			if value, err := tx.Get("added"); err != nil || string(value) != "new" {
+				t.Errorf("Get() of a pending write = %q, %v", value, err)
+			}
+			if _, err := tx.Get("gone"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() of a pending delete = %v, want ErrNotFound", err)
+			}
+			kvs, err := tx.List("")
+			if err != nil || len(kvs) != 2 || kvs[0].Key != "added" || kvs[1].Key != "keep" {
+				t.Errorf("List() inside Update() = %v, %v; want [added keep]", kvs, err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Update() failed: %v", err)
+		}
+		if got := keys(t, s, ""); !equal(got, []string{"added", "keep"}) {
+			t.Errorf("keys after Update() = %v, want [added keep]", got)
+		}
+
+		// Only the committed transaction is reported
+		want := []Event{{Type: EventPut, Key: "added", Value: []byte("new")}, {Type: EventDelete, Key: "gone"}}
+		for _, w := range want {
+			select {
+			case got := <-events:
+				if got.Type != w.Type || got.Key != w.Key || !bytes.Equal(got.Value, w.Value) {
+					t.Errorf("event = %+v, want %+v", got, w)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("no event for %+v", w)
+			}
+		}
+	})
+}
+
+// TestViewReadOnly checks that writes are refused inside View
+func TestViewReadOnly(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		put(t, s, "key", "value")
+
+		err := s.View(context.Background(), func(tx Txn) error {
+			if err := tx.Put("key", []byte("changed")); !errors.Is(err, ErrReadOnly) {
+				t.Errorf("Put() inside View() = %v, want ErrReadOnly", err)
+			}
+			if err := tx.Delete("key"); !errors.Is(err, ErrReadOnly) {
+				t.Errorf("Delete() inside View() = %v, want ErrReadOnly", err)
+			}
+			value, err := tx.Get("key")
+			if err != nil || string(value) != "value" {
+				t.Errorf("Get() inside View() = %q, %v", value, err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("View() failed: %v", err)
+		}
+		if value, _ := s.Get(context.Background(), "key"); string(value) != "value" {
+			t.Errorf("key = %q after writes inside View(), want value", value)
+		}
+	})
+}
+
+// TestClosed checks that every operation fails after Close
+func TestClosed(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		put(t, s, "key", "value")
+		if err := s.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+
+		if _, err := s.Get(ctx, "key"); !errors.Is(err, ErrClosed) {
+			t.Errorf("Get() = %v, want ErrClosed", err)
+		}
+		if err := s.Put(ctx, "key", nil); !errors.Is(err, ErrClosed) {
+			t.Errorf("Put() = %v, want ErrClosed", err)
+		}
+		if _, err := s.List(ctx, ""); !errors.Is(err, ErrClosed) {
+			t.Errorf("List() = %v, want ErrClosed", err)
+		}
+		if _, err := s.Watch(ctx, ""); !errors.Is(err, ErrClosed) {
+			t.Errorf("Watch() = %v, want ErrClosed", err)
+		}
+	})
+}
+
+// TestSnapshot checks that a bolt snapshot is a database holding the data,
+// and that the memory engine has no snapshots
+func TestSnapshot(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, s Store) {
+		snapshotter, ok := s.(Snapshotter)
+		if _, isMemory := s.(*Memory); isMemory {
+			if ok {
+				t.Errorf("the memory engine implements Snapshotter")
+			}
+			return
+		}
+		if !ok {
+			t.Fatalf("%T does not implement Snapshotter", s)
+		}
+
+		put(t, s, "todos/1", "a", "todos/2", "b")
+
+		copyPath := filepath.Join(t.TempDir(), "copy.db")
+		err := snapshotter.Snapshot(context.Background(), func(size int64, data io.WriterTo) error {
+			var buf bytes.Buffer
+			n, err := data.WriteTo(&buf)
+			if err != nil {
+				return err
+			}
+			if n != size {
+				t.Errorf("snapshot wrote %d bytes, announced %d", n, size)
+			}
+			return os.WriteFile(copyPath, buf.Bytes(), 0600)
+		})
+		if err != nil {
+			t.Fatalf("Snapshot() failed: %v", err)
+		}
+
+		// Writes after the snapshot are not in it
+		put(t, s, "todos/3", "c")
+
+		restored, err := OpenBolt(copyPath, time.Second)
+		if err != nil {
+			t.Fatalf("OpenBolt() of the snapshot failed: %v", err)
+		}
+		defer restored.Close()
+		if got := keys(t, restored, ""); !equal(got, []string{"todos/1", "todos/2"}) {
+			t.Errorf("snapshot holds %v, want [todos/1 todos/2]", got)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// watchBuffer is the number of events a watcher may fall behind by before its
+// channel is closed
+const watchBuffer = 256
+
+// watchers fans committed events out to Watch subscribers. Shared by engines.
+type watchers struct {
+	mu     sync.Mutex
+	next   int
+	subs   map[int]*watcher
+	closed bool
+}
+
+type watcher struct {
+	prefix string
+	ch     chan Event
+
+	// done is closed when the subscriber is removed, so the goroutine waiting
+	// on its context exits when the store closes or drops it
+	done chan struct{}
+}
+
+// add registers a subscriber removed when ctx is done or the store closes
+func (w *watchers) add(ctx context.Context, prefix string) (<-chan Event, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if w.subs == nil {
+		w.subs = map[int]*watcher{}
+	}
+
+	id := w.next
+	w.next++
+	sub := &watcher{prefix: prefix, ch: make(chan Event, watchBuffer), done: make(chan struct{})}
+	w.subs[id] = sub
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.remove(id)
+		case <-sub.done:
+		}
+	}()
+
+	return sub.ch, nil
+}
+
+// remove closes a subscriber's channel once; safe to call repeatedly
+func (w *watchers) remove(id int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.removeLocked(id)
+}
+
+func (w *watchers) removeLocked(id int) {
+	if sub, ok := w.subs[id]; ok {
+		delete(w.subs, id)
+		close(sub.ch)
+		close(sub.done)
+	}
+}
+
+// publish delivers committed events without blocking the writer. Subscribers
+// with a full buffer are dropped so they notice the gap.
+func (w *watchers) publish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for id, sub := range w.subs {
+		for _, event := range events {
+			if !strings.HasPrefix(event.Key, sub.prefix) {
+				continue
+			}
+			select {
+			case sub.ch <- event:
+			default:
+				w.removeLocked(id)
+			}
+			if _, ok := w.subs[id]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// close ends every subscription
+func (w *watchers) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	for id := range w.subs {
+		w.removeLocked(id)
+	}
+}
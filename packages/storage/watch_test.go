@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestWatchEndsOnClose checks that closing the store ends subscriptions made
+// with a context that is never cancelled, without leaking their goroutines
+func TestWatchEndsOnClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	m := NewMemory()
+	var channels []<-chan Event
+	for i := 0; i < 10; i++ {
+		ch, err := m.Watch(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Watch() failed: %v", err)
+		}
+		channels = append(channels, ch)
+	}
+
+	if err := m.Put(context.Background(), "key", []byte("value")); err != nil {
+		t.Fatalf("Put() failed: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	for _, ch := range channels {
+		if event, ok := <-ch; !ok || event.Key != "key" {
+			t.Fatalf("first event = %+v, %v; want the put", event, ok)
+		}
+		if _, ok := <-ch; ok {
+			t.Fatalf("channel still open after Close()")
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines after Close(), %d before Watch()", after, before)
+	}
+
+	if _, err := m.Watch(context.Background(), ""); err != ErrClosed {
+		t.Errorf("Watch() after Close() error = %v, want ErrClosed", err)
+	}
+}
+
+// TestWatchCancel checks that cancelling the context closes the channel
+func TestWatchCancel(t *testing.T) {
+	m := NewMemory()
+	defer m.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := m.Watch(ctx, "")
+	if err != nil {
+		t.Fatalf("Watch() failed: %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("received an event after cancel, want the channel closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("channel not closed after cancel")
+	}
+}
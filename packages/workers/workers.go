@@ -0,0 +1,284 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/cloudputation/service-seed/packages/config"
+	log "github.com/cloudputation/service-seed/packages/logger"
+	"github.com/cloudputation/service-seed/packages/stats"
+)
+
+var (
+	// ErrQueueFull is returned when every worker is busy and the queue is full
+	ErrQueueFull = errors.New("worker queue is full")
+
+	// ErrStopped is returned for submissions after Shutdown has started
+	ErrStopped = errors.New("worker pool is stopped")
+)
+
+// Task is a unit of work run by a pool. ctx is cancelled when the submitter
+// gives up (Do) or when shutdown runs out of drain time.
+type Task func(ctx context.Context) error
+
+// PanicError is returned for a task that panicked; the worker keeps running
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// Pool runs tasks on a fixed number of workers fed by a bounded queue
+type Pool struct {
+	name  string
+	queue chan *job
+
+	// ctx is cancelled when Shutdown runs out of time; running tasks see it
+	// through their own context and queued tasks are skipped
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// mu guards stopped so no submission races with closing the queue
+	mu      sync.RWMutex
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+type job struct {
+	ctx      context.Context
+	name     string
+	task     Task
+	enqueued time.Time
+
+	// done receives the result for Do; nil for Submit
+	done chan error
+}
+
+// New starts a pool of size workers with room for queueSize waiting tasks.
+// name labels the pool's metrics.
+func New(name string, size, queueSize int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		name:   name,
+		queue:  make(chan *job, queueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	p.wg.Add(size)
+	for i := 0; i < size; i++ {
+		go p.worker()
+	}
+	return p
+}
+
+// Submit queues task and returns without waiting for it. The task keeps the
+// values of ctx (trace span, request-scoped data) but not its cancellation,
+// so it outlives the request that submitted it. Failures are logged.
+// Returns ErrQueueFull or ErrStopped when the task is not accepted.
+func (p *Pool) Submit(ctx context.Context, name string, task Task) error {
+	return p.enqueue(&job{
+		ctx:  context.WithoutCancel(ctx),
+		name: name,
+		task: task,
+	})
+}
+
+// Do queues task and waits for its result. The task runs with ctx, so a
+// cancelled caller skips a task that has not started and cancels a running
+// one. Returns ErrQueueFull or ErrStopped when the task is not accepted, and
+// a *PanicError when it panicked.
+func (p *Pool) Do(ctx context.Context, name string, task Task) error {
+	j := &job{
+		ctx:  ctx,
+		name: name,
+		task: task,
+		done: make(chan error, 1),
+	}
+	if err := p.enqueue(j); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue adds a job without blocking
+func (p *Pool) enqueue(j *job) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		stats.RecordWorkerRejection(j.ctx, p.name, j.name, "stopped")
+		return ErrStopped
+	}
+
+	j.enqueued = time.Now()
+	stats.AddWorkerQueueDepth(j.ctx, p.name, 1)
+	select {
+	case p.queue <- j:
+		return nil
+	default:
+		stats.AddWorkerQueueDepth(j.ctx, p.name, -1)
+		stats.RecordWorkerRejection(j.ctx, p.name, j.name, "queue_full")
+		return ErrQueueFull
+	}
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+
+	for j := range p.queue {
+		stats.AddWorkerQueueDepth(j.ctx, p.name, -1)
+		p.run(j)
+	}
+}
+
+// run executes one job and reports its outcome
+func (p *Pool) run(j *job) {
+	ctx, cancel := context.WithCancel(j.ctx)
+	defer cancel()
+	stop := context.AfterFunc(p.ctx, cancel)
+	defer stop()
+
+	wait := time.Since(j.enqueued)
+	var duration time.Duration
+
+	// The pool context is checked directly since AfterFunc cancels asynchronously
+	err := ctx.Err()
+	if err == nil {
+		err = p.ctx.Err()
+	}
+	if err == nil {
+		stats.AddWorkerActive(ctx, p.name, 1)
+		timer := stats.NewTimer()
+		err = call(ctx, j.task)
+		duration = timer.Elapsed()
+		stats.AddWorkerActive(ctx, p.name, -1)
+	}
+
+	status := "ok"
+	var panicErr *PanicError
+	switch {
+	case errors.As(err, &panicErr):
+		status = "panic"
+		log.Error(fmt.Sprintf("Task %s panicked: %v\n%s", j.name, panicErr.Value, string(panicErr.Stack)))
+	case err != nil && (ctx.Err() != nil || p.ctx.Err() != nil):
+		status = "canceled"
+	case err != nil:
+		status = "error"
+		if j.done == nil {
+			log.Error(fmt.Sprintf("Task %s failed: %v", j.name, err))
+		}
+	}
+	stats.RecordWorkerTask(ctx, p.name, j.name, status, wait, duration)
+
+	if j.done != nil {
+		j.done <- err
+	}
+}
+
+// call runs task, turning a panic into a *PanicError
+func call(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return task(ctx)
+}
+
+// Shutdown stops accepting tasks and waits for queued and running ones to
+// finish. When ctx ends first, running tasks are cancelled, queued ones are
+// skipped, and an error reports how the drain ended; tasks that ignore
+// cancellation may still be running when it returns.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.cancel()
+	queued := len(p.queue)
+
+	// Give cancelled tasks a moment to return before reporting
+	select {
+	case <-drained:
+		return fmt.Errorf("worker pool %s did not drain in time; cancelled running tasks and skipped %d queued", p.name, queued)
+	case <-time.After(time.Second):
+		return fmt.Errorf("worker pool %s did not drain in time; cancelled tasks are still running", p.name)
+	}
+}
+
+// DefaultPool is the pool started by InitWorkers from the workers block
+var DefaultPool *Pool
+
+// InitWorkers starts DefaultPool. Runs after metrics initialization so queue
+// depth and active worker counts start from zero.
+func InitWorkers() {
+	cfg := config.AppConfig.Workers
+	DefaultPool = New("default", cfg.MaxWorkers, cfg.QueueSize)
+}
+
+// ShutdownWorkers drains DefaultPool within workers.drain_timeout_seconds.
+// Safe to call when it was never started.
+func ShutdownWorkers() error {
+	if DefaultPool == nil {
+		return nil
+	}
+
+	timeout := time.Duration(config.AppConfig.Workers.DrainTimeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return DefaultPool.Shutdown(ctx)
+}
+
+// Submit queues task on DefaultPool; see Pool.Submit
+func Submit(ctx context.Context, name string, task Task) error {
+	if DefaultPool == nil {
+		return ErrStopped
+	}
+	return DefaultPool.Submit(ctx, name, task)
+}
+
+// Do runs task on DefaultPool and waits for it; see Pool.Do
+func Do(ctx context.Context, name string, task Task) error {
+	if DefaultPool == nil {
+		return ErrStopped
+	}
+	return DefaultPool.Do(ctx, name, task)
+}
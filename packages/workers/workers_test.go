@@ -0,0 +1,258 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// block returns a task that signals started and waits for release or its
+// context, returning the context error in the latter case
+func block(started chan<- struct{}, release <-chan struct{}) Task {
+	return func(ctx context.Context) error {
+		if started != nil {
+			started <- struct{}{}
+		}
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// shutdown drains p when the test ends
+func shutdown(t *testing.T, p *Pool) {
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		p.Shutdown(ctx)
+	})
+}
+
+// TestPanic checks that a panicking task is reported as a *PanicError and
+// that its worker keeps serving
+func TestPanic(t *testing.T) {
+	p := New("test", 1, 1)
+	shutdown(t, p)
+	ctx := context.Background()
+
+	err := p.Do(ctx, "panic", func(context.Context) error { panic("boom") })
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Do() error = %v, want a *PanicError", err)
+	}
+	if panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Errorf("PanicError = %v with %d stack bytes, want boom and a stack", panicErr.Value, len(panicErr.Stack))
+	}
+
+	// Submitted tasks are recovered the same way
+	if err := p.Submit(ctx, "panic", func(context.Context) error { panic("boom") }); err != nil {
+		t.Fatalf("Submit() failed: %v", err)
+	}
+
+	want := errors.New("after panic")
+	if err := p.Do(ctx, "next", func(context.Context) error { return want }); err != want {
+		t.Errorf("Do() after a panic = %v, want %v", err, want)
+	}
+}
+
+// TestQueueFull checks that tasks are refused once every worker is busy and
+// the queue is full
+func TestQueueFull(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		queueSize int
+	}{
+		{"no queue", 1, 0},
+		{"queue", 2, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New("test", tt.size, tt.queueSize)
+			shutdown(t, p)
+			ctx := context.Background()
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+
+			// Without a queue, a task is only accepted once a worker is waiting
+			for i := 0; i < tt.size; i++ {
+				deadline := time.Now().Add(time.Second)
+				err := p.Submit(ctx, "busy", block(started, release))
+				for errors.Is(err, ErrQueueFull) && time.Now().Before(deadline) {
+					time.Sleep(time.Millisecond)
+					err = p.Submit(ctx, "busy", block(started, release))
+				}
+				if err != nil {
+					t.Fatalf("Submit() of worker task %d failed: %v", i, err)
+				}
+				<-started
+			}
+			for i := 0; i < tt.queueSize; i++ {
+				if err := p.Submit(ctx, "queued", block(nil, release)); err != nil {
+					t.Fatalf("Submit() of queued task %d failed: %v", i, err)
+				}
+			}
+
+			if err := p.Submit(ctx, "overflow", block(nil, release)); !errors.Is(err, ErrQueueFull) {
+				t.Errorf("Submit() = %v, want ErrQueueFull", err)
+			}
+			if err := p.Do(ctx, "overflow", block(nil, release)); !errors.Is(err, ErrQueueFull) {
+				t.Errorf("Do() = %v, want ErrQueueFull", err)
+			}
+		})
+	}
+}
+
+// TestStopped checks that nothing is accepted once Shutdown has started,
+// and that a second Shutdown is harmless
+func TestStopped(t *testing.T) {
+	p := New("test", 1, 1)
+	ctx := context.Background()
+
+	if err := p.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() failed: %v", err)
+	}
+	if err := p.Submit(ctx, "late", block(nil, nil)); !errors.Is(err, ErrStopped) {
+		t.Errorf("Submit() after Shutdown() = %v, want ErrStopped", err)
+	}
+	if err := p.Do(ctx, "late", block(nil, nil)); !errors.Is(err, ErrStopped) {
+		t.Errorf("Do() after Shutdown() = %v, want ErrStopped", err)
+	}
+	if err := p.Shutdown(ctx); err != nil {
+		t.Errorf("second Shutdown() = %v", err)
+	}
+}
+
+// TestDoCancel checks that Do returns when its context is cancelled, for a
+// running task and for one still waiting in the queue
+func TestDoCancel(t *testing.T) {
+	p := New("test", 1, 1)
+	shutdown(t, p)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	// The running task sees the cancellation through its own context
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- p.Do(ctx, "running", block(started, release)) }()
+	<-started
+	cancel()
+	if err := waitFor(t, result); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() of a running task = %v, want context.Canceled", err)
+	}
+
+	// A queued task is given up without waiting for the busy worker
+	if err := p.Submit(context.Background(), "busy", block(started, release)); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ran := make(chan struct{}, 1)
+	go func() {
+		result <- p.Do(ctx, "queued", func(context.Context) error {
+			ran <- struct{}{}
+			return nil
+		})
+	}()
+	if err := waitFor(t, result); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() of a queued task = %v, want context.DeadlineExceeded", err)
+	}
+	select {
+	case <-ran:
+		t.Errorf("queued task ran after its caller gave up")
+	default:
+	}
+}
+
+// TestShutdownDrains checks that queued and running tasks finish when the
+// drain has time
+func TestShutdownDrains(t *testing.T) {
+	p := New("test", 1, 5)
+
+	done := make(chan struct{}, 5)
+	for i := 0; i < 5; i++ {
+		err := p.Submit(context.Background(), "drain", func(context.Context) error {
+			time.Sleep(5 * time.Millisecond)
+			done <- struct{}{}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() failed: %v", err)
+	}
+	if len(done) != 5 {
+		t.Errorf("%d of 5 tasks ran before Shutdown() returned", len(done))
+	}
+}
+
+// TestShutdownDeadline checks that a drain past its deadline cancels running
+// tasks and skips queued ones
+func TestShutdownDeadline(t *testing.T) {
+	p := New("test", 1, 2)
+
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+	err := p.Submit(context.Background(), "running", func(ctx context.Context) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ran := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		err := p.Submit(context.Background(), "queued", func(context.Context) error {
+			ran <- struct{}{}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = p.Shutdown(ctx)
+	if err == nil {
+		t.Fatalf("Shutdown() past its deadline succeeded")
+	}
+	if want := "worker pool test did not drain in time; cancelled running tasks and skipped 2 queued"; err.Error() != want {
+		t.Errorf("Shutdown() error = %q, want %q", err, want)
+	}
+	if err := waitFor(t, canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("running task saw %v, want context.Canceled", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("%d queued tasks ran after the deadline", len(ran))
+	}
+}
+
+// waitFor returns the next value of ch, failing the test after a second
+func waitFor(t *testing.T, ch <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(time.Second):
+		t.Fatalf("timed out")
+		return nil
+	}
+}
@@ -0,0 +1,8 @@
+package main
+
+import "embed"
+
+// seedTemplate is the source tree rendered by `service-seed init`
+//
+//go:embed Dockerfile GNUmakefile README.md config.hcl go.mod go.sum main.go seed.go helpers packages
+var seedTemplate embed.FS